@@ -1,10 +1,8 @@
 package alipay
 
 import (
-	"crypto"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -27,20 +25,31 @@ func (a *Action) RespKey() string {
 
 // Encode 签名并生成请求Body
 func (a *Action) Encode(c *Client) (string, error) {
-	if c.prvKey == nil {
-		return "", errors.New("private key is nil (forgotten configure?)")
+	v, err := a.signedValues(c)
+	if err != nil {
+		return "", err
 	}
+	return v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore), value.WithKVEscape()), nil
+}
 
+// signedValues 返回签名后的全部请求参数
+func (a *Action) signedValues(c *Client) (value.V, error) {
 	v := make(value.V)
 
 	v.Set("app_id", c.appid)
 	v.Set("method", a.method)
 	v.Set("format", "JSON")
 	v.Set("charset", "utf-8")
-	v.Set("sign_type", "RSA2")
+	v.Set("sign_type", string(c.signType))
 	v.Set("timestamp", time.Now().In(time.Local).Format("2006-01-02 15:04:05"))
 	v.Set("version", "1.0")
 
+	// 公钥证书模式
+	if c.cert != nil {
+		v.Set("app_cert_sn", c.cert.AppCertSN())
+		v.Set("alipay_root_cert_sn", c.cert.RootCertSN())
+	}
+
 	for key, val := range a.params {
 		v.Set(key, val)
 	}
@@ -48,7 +57,7 @@ func (a *Action) Encode(c *Client) (string, error) {
 	if len(a.bizData) != 0 {
 		bizByte, err := json.Marshal(a.bizData)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		bizContent := string(bizByte)
@@ -56,21 +65,21 @@ func (a *Action) Encode(c *Client) (string, error) {
 		if a.encrypt {
 			bizContent, err = c.Encrypt(bizContent)
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 		}
 
 		v.Set("biz_content", bizContent)
 	}
 
-	sign, err := c.prvKey.Sign(crypto.SHA256, []byte(v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore))))
+	sign, err := c.sign([]byte(v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore))))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	v.Set("sign", base64.StdEncoding.EncodeToString(sign))
 
-	return v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore), value.WithKVEscape()), nil
+	return v, nil
 }
 
 // ActionOption Action选项
@@ -0,0 +1,495 @@
+package alipay
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// BillType 账单类型
+type BillType string
+
+const (
+	BillTrade        BillType = "trade"        // 商户基于支付宝交易收单的业务账单
+	BillSignCustomer BillType = "signcustomer" // 基于商户支付宝余额收入及支出等资金变动的账务账单
+)
+
+// BillRecord 账单行(表头 => 值)，表头中的全角括号已转换为半角
+type BillRecord map[string]string
+
+// Get 获取指定列的值
+func (r BillRecord) Get(column string) string {
+	return r[column]
+}
+
+// TradeBillRow 业务明细账单行(trade)
+type TradeBillRow struct {
+	TradeNo           string    // 支付宝交易号
+	OutTradeNo        string    // 商户订单号
+	BizType           string    // 业务类型
+	Subject           string    // 商品名称
+	CreatedAt         time.Time // 创建时间
+	FinishedAt        time.Time // 完成时间
+	StoreID           string    // 门店编号
+	StoreName         string    // 门店名称
+	Operator          string    // 操作员
+	TerminalID        string    // 终端号
+	Account           string    // 对方账户
+	TotalAmount       Decimal   // 订单金额(元)
+	ReceiptAmount     Decimal   // 商家实收(元)
+	AlipayRedPacket   Decimal   // 支付宝红包(元)
+	JifenbaoAmount    Decimal   // 集分宝(元)
+	AlipayDiscount    Decimal   // 支付宝优惠(元)
+	MerchantDiscount  Decimal   // 商家优惠(元)
+	CouponAmount      Decimal   // 券核销金额(元)
+	CouponName        string    // 券名称
+	MerchantRedPacket Decimal   // 商家红包消费金额(元)
+	CardAmount        Decimal   // 卡消费金额(元)
+	RefundNo          string    // 退款批次号/请求号
+	ServiceFee        Decimal   // 服务费(元)
+	ShareAmount       Decimal   // 分润(元)
+	Remark            string    // 备注
+	Raw               BillRecord
+}
+
+// AccountBillRow 账务明细账单行(signcustomer)
+type AccountBillRow struct {
+	AccountLogID string    // 账务流水号
+	BizNo        string    // 业务流水号
+	OutTradeNo   string    // 商户订单号
+	Subject      string    // 商品名称
+	OccurredAt   time.Time // 发生时间
+	Account      string    // 对方账号
+	Income       Decimal   // 收入金额(+元)
+	Expense      Decimal   // 支出金额(-元)
+	Balance      Decimal   // 账户余额(元)
+	Channel      string    // 交易渠道
+	BizType      string    // 业务类型
+	Remark       string    // 备注
+	Raw          BillRecord
+}
+
+// billParser 将账单行解析为具体类型，解析失败时记录首个错误
+type billParser struct {
+	rec BillRecord
+	err error
+}
+
+func (p *billParser) str(column string) string {
+	return p.rec[column]
+}
+
+func (p *billParser) decimal(column string) Decimal {
+	s := p.rec[column]
+	if len(s) == 0 {
+		return ""
+	}
+
+	d, err := NewDecimal(s)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s: %w", column, err)
+	}
+	return d
+}
+
+func (p *billParser) time(column string) time.Time {
+	t, err := ParseTime(p.rec[column])
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("%s: %w", column, err)
+	}
+	return t
+}
+
+// ParseTradeBillRow 解析业务明细账单行
+func ParseTradeBillRow(rec BillRecord) (*TradeBillRow, error) {
+	p := &billParser{rec: rec}
+
+	row := &TradeBillRow{
+		TradeNo:           p.str("支付宝交易号"),
+		OutTradeNo:        p.str("商户订单号"),
+		BizType:           p.str("业务类型"),
+		Subject:           p.str("商品名称"),
+		CreatedAt:         p.time("创建时间"),
+		FinishedAt:        p.time("完成时间"),
+		StoreID:           p.str("门店编号"),
+		StoreName:         p.str("门店名称"),
+		Operator:          p.str("操作员"),
+		TerminalID:        p.str("终端号"),
+		Account:           p.str("对方账户"),
+		TotalAmount:       p.decimal("订单金额(元)"),
+		ReceiptAmount:     p.decimal("商家实收(元)"),
+		AlipayRedPacket:   p.decimal("支付宝红包(元)"),
+		JifenbaoAmount:    p.decimal("集分宝(元)"),
+		AlipayDiscount:    p.decimal("支付宝优惠(元)"),
+		MerchantDiscount:  p.decimal("商家优惠(元)"),
+		CouponAmount:      p.decimal("券核销金额(元)"),
+		CouponName:        p.str("券名称"),
+		MerchantRedPacket: p.decimal("商家红包消费金额(元)"),
+		CardAmount:        p.decimal("卡消费金额(元)"),
+		RefundNo:          p.str("退款批次号/请求号"),
+		ServiceFee:        p.decimal("服务费(元)"),
+		ShareAmount:       p.decimal("分润(元)"),
+		Remark:            p.str("备注"),
+		Raw:               rec,
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return row, nil
+}
+
+// ParseAccountBillRow 解析账务明细账单行
+func ParseAccountBillRow(rec BillRecord) (*AccountBillRow, error) {
+	p := &billParser{rec: rec}
+
+	row := &AccountBillRow{
+		AccountLogID: p.str("账务流水号"),
+		BizNo:        p.str("业务流水号"),
+		OutTradeNo:   p.str("商户订单号"),
+		Subject:      p.str("商品名称"),
+		OccurredAt:   p.time("发生时间"),
+		Account:      p.str("对方账号"),
+		Income:       p.decimal("收入金额(+元)"),
+		Expense:      p.decimal("支出金额(-元)"),
+		Balance:      p.decimal("账户余额(元)"),
+		Channel:      p.str("交易渠道"),
+		BizType:      p.str("业务类型"),
+		Remark:       p.str("备注"),
+		Raw:          rec,
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return row, nil
+}
+
+// BillIterator 账单行迭代器(忽略「#」开头的说明行，首行为表头)
+type BillIterator[T any] struct {
+	closer io.Closer
+	reader *csv.Reader
+	header []string
+	parse  func(rec BillRecord) (T, error)
+}
+
+// Header 返回表头
+func (it *BillIterator[T]) Header() []string {
+	return it.header
+}
+
+// Next 返回下一行，迭代结束返回 io.EOF
+func (it *BillIterator[T]) Next() (T, error) {
+	var zero T
+
+	for {
+		fields, err := it.reader.Read()
+		if err != nil {
+			return zero, err
+		}
+		if isBlankRecord(fields) {
+			continue
+		}
+
+		rec := make(BillRecord, len(it.header))
+		for i, k := range it.header {
+			if i < len(fields) {
+				rec[k] = cleanBillField(fields[i])
+			}
+		}
+
+		row, err := it.parse(rec)
+		if err != nil {
+			line, _ := it.reader.FieldPos(0)
+			return zero, fmt.Errorf("bill line %d: %w", line, err)
+		}
+		return row, nil
+	}
+}
+
+// All 读取全部账单行
+func (it *BillIterator[T]) All() ([]T, error) {
+	var list []T
+	for {
+		row, err := it.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return list, nil
+			}
+			return nil, err
+		}
+		list = append(list, row)
+	}
+}
+
+// Close 关闭账单文件
+func (it *BillIterator[T]) Close() error {
+	return it.closer.Close()
+}
+
+func newBillIterator[T any](rc io.ReadCloser, parse func(rec BillRecord) (T, error)) (*BillIterator[T], error) {
+	r := csv.NewReader(transform.NewReader(rc, simplifiedchinese.GBK.NewDecoder()))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	it := &BillIterator[T]{
+		closer: rc,
+		reader: r,
+		parse:  parse,
+	}
+
+	for {
+		fields, err := r.Read()
+		if err != nil {
+			rc.Close()
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("bill header not found")
+			}
+			return nil, err
+		}
+		if isBlankRecord(fields) {
+			continue
+		}
+
+		it.header = make([]string, 0, len(fields))
+		for _, v := range fields {
+			it.header = append(it.header, normalizeBillColumn(v))
+		}
+		return it, nil
+	}
+}
+
+// Bill 已下载的账单压缩包(包含明细和汇总两个GBK编码的CSV文件)
+type Bill struct {
+	path string
+	temp bool
+	zr   *zip.ReadCloser
+}
+
+// Files 返回压缩包中的文件名
+func (b *Bill) Files() []string {
+	names := make([]string, 0, len(b.zr.File))
+	for _, f := range b.zr.File {
+		names = append(names, zipFileName(f))
+	}
+	return names
+}
+
+// Details 明细文件的原始账单行
+func (b *Bill) Details() (*BillIterator[BillRecord], error) {
+	return openBillFile(b, false, func(rec BillRecord) (BillRecord, error) { return rec, nil })
+}
+
+// Summary 汇总文件的账单行
+func (b *Bill) Summary() (*BillIterator[BillRecord], error) {
+	return openBillFile(b, true, func(rec BillRecord) (BillRecord, error) { return rec, nil })
+}
+
+// TradeRows 业务明细账单行(trade)
+func (b *Bill) TradeRows() (*BillIterator[*TradeBillRow], error) {
+	return openBillFile(b, false, ParseTradeBillRow)
+}
+
+// AccountRows 账务明细账单行(signcustomer)
+func (b *Bill) AccountRows() (*BillIterator[*AccountBillRow], error) {
+	return openBillFile(b, false, ParseAccountBillRow)
+}
+
+// Close 关闭账单，下载的临时文件会被删除
+func (b *Bill) Close() error {
+	err := b.zr.Close()
+	if b.temp {
+		if _err := os.Remove(b.path); _err != nil && err == nil {
+			err = _err
+		}
+	}
+	return err
+}
+
+func openBillFile[T any](b *Bill, summary bool, parse func(rec BillRecord) (T, error)) (*BillIterator[T], error) {
+	for _, f := range b.zr.File {
+		name := zipFileName(f)
+		if !strings.HasSuffix(strings.ToLower(name), ".csv") || strings.Contains(name, "汇总") != summary {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		return newBillIterator(rc, parse)
+	}
+
+	if summary {
+		return nil, errors.New("bill summary file not found")
+	}
+	return nil, errors.New("bill detail file not found")
+}
+
+// OpenBill 打开本地账单压缩包
+func OpenBill(zipFile string) (*Bill, error) {
+	zr, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Bill{path: zipFile, zr: zr}, nil
+}
+
+// downloadBill 流式下载账单压缩包到临时文件
+func downloadBill(ctx context.Context, client *resty.Client, logger func(ctx context.Context, err error, data map[string]string), billURL string) (*Bill, error) {
+	f, err := os.CreateTemp("", "alipay_bill_*.zip")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = download(ctx, client, logger, billURL, f)
+	if _err := f.Close(); _err != nil && err == nil {
+		err = _err
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	bill, err := OpenBill(f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	bill.temp = true
+
+	return bill, nil
+}
+
+// download 流式下载文件(如：对账单、电子回单)并写入 w
+func download(ctx context.Context, client *resty.Client, logger func(ctx context.Context, err error, data map[string]string), fileURL string, w io.Writer) (int64, error) {
+	log := lib.NewReqLog(http.MethodGet, fileURL)
+	defer log.Do(ctx, logger)
+
+	resp, err := client.R().
+		SetContext(ctx).
+		SetDoNotParseResponse(true).
+		Get(fileURL)
+	if err != nil {
+		log.SetError(err)
+		return 0, err
+	}
+
+	rawBody := resp.RawBody()
+	defer rawBody.Close()
+
+	log.SetRespHeader(resp.Header())
+	log.SetStatusCode(resp.StatusCode())
+	if !resp.IsSuccess() {
+		err = fmt.Errorf("HTTP Request Error, StatusCode = %d", resp.StatusCode())
+		log.SetError(err)
+		return 0, err
+	}
+
+	n, err := io.Copy(w, rawBody)
+	if err != nil {
+		log.SetError(err)
+		return n, err
+	}
+	log.Set("size", fmt.Sprintf("%d", n))
+
+	return n, nil
+}
+
+// BillDownloadURL 查询对账单下载地址(有效期30秒)，billDate 格式：日账单 yyyy-MM-dd，月账单 yyyy-MM
+// [参考](https://opendocs.alipay.com/open/02e7gr)
+func (c *Client) BillDownloadURL(ctx context.Context, billType BillType, billDate string, options ...ActionOption) (string, error) {
+	options = append(options, WithBizContent(lib.X{
+		"bill_type": billType,
+		"bill_date": billDate,
+	}))
+
+	ret, err := c.Do(ctx, "alipay.data.dataservice.bill.downloadurl.query", options...)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("bill_download_url").String(), nil
+}
+
+// DownloadBill 下载对账单，使用完毕后需调用 Bill.Close
+func (c *Client) DownloadBill(ctx context.Context, billType BillType, billDate string, options ...ActionOption) (*Bill, error) {
+	billURL, err := c.BillDownloadURL(ctx, billType, billDate, options...)
+	if err != nil {
+		return nil, err
+	}
+	return downloadBill(ctx, c.client, c.logger, billURL)
+}
+
+// BillDownloadURL 查询对账单下载地址(有效期30秒)，billDate 格式：日账单 yyyy-MM-dd，月账单 yyyy-MM
+// [参考](https://opendocs.alipay.com/open-v3/0c4d4a5b_alipay.data.dataservice.bill.downloadurl.query)
+func (c *ClientV3) BillDownloadURL(ctx context.Context, billType BillType, billDate string, options ...V3HeaderOption) (string, error) {
+	query := url.Values{}
+	query.Set("bill_type", string(billType))
+	query.Set("bill_date", billDate)
+
+	ret, err := c.GetJSON(ctx, "/v3/alipay/data/dataservice/bill/downloadurl/query", query, options...)
+	if err != nil {
+		return "", err
+	}
+	if err = ret.Err(); err != nil {
+		return "", err
+	}
+	return ret.Body.Get("bill_download_url").String(), nil
+}
+
+// DownloadBill 下载对账单，使用完毕后需调用 Bill.Close
+func (c *ClientV3) DownloadBill(ctx context.Context, billType BillType, billDate string, options ...V3HeaderOption) (*Bill, error) {
+	billURL, err := c.BillDownloadURL(ctx, billType, billDate, options...)
+	if err != nil {
+		return nil, err
+	}
+	return downloadBill(ctx, c.client, c.logger, billURL)
+}
+
+// zipFileName 返回文件名，非UTF-8文件名按GBK解码
+func zipFileName(f *zip.File) string {
+	if !f.NonUTF8 && utf8.ValidString(f.Name) {
+		return f.Name
+	}
+
+	name, _, err := transform.String(simplifiedchinese.GBK.NewDecoder(), f.Name)
+	if err != nil {
+		return f.Name
+	}
+	return name
+}
+
+func normalizeBillColumn(s string) string {
+	s = cleanBillField(s)
+	s = strings.ReplaceAll(s, "（", "(")
+	s = strings.ReplaceAll(s, "）", ")")
+	return s
+}
+
+// cleanBillField 去除字段中的空白及制表符(支付宝在单号后追加「\t」以防止Excel科学计数)
+func cleanBillField(s string) string {
+	return strings.Trim(s, " \t\r\n")
+}
+
+func isBlankRecord(fields []string) bool {
+	for _, v := range fields {
+		if len(cleanBillField(v)) != 0 {
+			return false
+		}
+	}
+	return true
+}
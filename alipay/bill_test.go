@@ -0,0 +1,110 @@
+package alipay
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const testTradeBill = `#支付宝业务明细查询
+#账号：[20881234567890120156]
+#起始日期：[2019年01月01日 00:00:00]   终止日期：[2019年01月02日 00:00:00]
+#-----------------------------------------业务明细列表----------------------------------------
+支付宝交易号,商户订单号,业务类型,商品名称,创建时间,完成时间,门店编号,门店名称,操作员,终端号,对方账户,订单金额（元）,商家实收（元）,支付宝红包（元）,集分宝（元）,支付宝优惠（元）,商家优惠（元）,券核销金额（元）,券名称,商家红包消费金额（元）,卡消费金额（元）,退款批次号/请求号,服务费（元）,分润（元）,备注
+2019010122001412340512345678	,201901011234	,交易,测试商品,2019-01-01 10:00:00,2019-01-01 10:00:05,,,,,abc***@163.com,100.01,100.01,0.00,0.00,0.00,0.00,0.00,,0.00,0.00,,-0.60,0.00,
+2019010122001412340512345679	,201901011235	,退款,测试商品,2019-01-01 11:00:00,2019-01-01 11:00:05,,,,,abc***@163.com,-0.01,-0.01,0.00,0.00,0.00,0.00,0.00,,0.00,0.00,201901011235R1	,0.00,0.00,
+#-----------------------------------------业务明细列表结束------------------------------------
+#交易合计：1笔，商家实收共100.01元，商家优惠共0.00元
+#退款合计：1笔，商家实收退款共-0.01元，商家优惠退款共0.00元
+#导出时间：[2019年01月02日 10:00:00]
+`
+
+const testTradeSummary = `#支付宝业务汇总查询
+#账号：[20881234567890120156]
+#-----------------------------------------业务汇总列表----------------------------------------
+门店编号,门店名称,交易订单总笔数,退款订单总笔数,订单金额（元）,商家实收（元）
+,,1,1,100.00,100.00
+#-----------------------------------------业务汇总列表结束------------------------------------
+`
+
+func genTestBillZip(t *testing.T) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"20881234567890120156_20190101_业务明细.csv", testTradeBill},
+		{"20881234567890120156_20190101_业务明细(汇总).csv", testTradeSummary},
+	}
+	for _, f := range files {
+		name, err := simplifiedchinese.GBK.NewEncoder().String(f.name)
+		assert.Nil(t, err)
+		content, err := simplifiedchinese.GBK.NewEncoder().String(f.content)
+		assert.Nil(t, err)
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, NonUTF8: true})
+		assert.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestDownloadBill(t *testing.T) {
+	data := genTestBillZip(t)
+
+	fileSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer fileSrv.Close()
+
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		assert.Equal(t, "alipay.data.dataservice.bill.downloadurl.query", method)
+		assert.Equal(t, "trade", biz.Get("bill_type").String())
+		return `{"code":"10000","msg":"Success","bill_download_url":"` + fileSrv.URL + `/bill.zip"}`
+	})
+	defer closeFn()
+
+	bill, err := cli.DownloadBill(context.Background(), BillTrade, "2019-01-01")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"20881234567890120156_20190101_业务明细.csv", "20881234567890120156_20190101_业务明细(汇总).csv"}, bill.Files())
+
+	it, err := bill.TradeRows()
+	assert.Nil(t, err)
+
+	rows, err := it.All()
+	assert.Nil(t, err)
+	assert.Nil(t, it.Close())
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "2019010122001412340512345678", rows[0].TradeNo)
+	assert.Equal(t, "201901011234", rows[0].OutTradeNo)
+	assert.Equal(t, Decimal("100.01"), rows[0].TotalAmount)
+	assert.Equal(t, Decimal("-0.60"), rows[0].ServiceFee)
+	assert.Equal(t, "2019-01-01 10:00:05", rows[0].FinishedAt.In(cstZone).Format(TimeLayout))
+	assert.Equal(t, "201901011235R1", rows[1].RefundNo)
+
+	summary, err := bill.Summary()
+	assert.Nil(t, err)
+
+	rec, err := summary.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "100.00", rec.Get("商家实收(元)"))
+	assert.Nil(t, summary.Close())
+
+	assert.Nil(t, bill.Close())
+
+	_, err = os.Stat(bill.path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,190 @@
+package alipay
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+	"github.com/shenghui0779/sdk-go/lib/xhash"
+)
+
+// Cert 公钥证书模式的证书信息
+// [参考](https://opendocs.alipay.com/common/02kf5p)
+type Cert struct {
+	mutex      sync.RWMutex
+	appCertSN  string
+	rootCertSN string
+	latestSN   string
+	pubKeys    map[string]*xcrypto.PublicKey
+}
+
+// AppCertSN 返回应用公钥证书SN(app_cert_sn)
+func (c *Cert) AppCertSN() string {
+	return c.appCertSN
+}
+
+// RootCertSN 返回支付宝根证书SN(alipay_root_cert_sn)
+func (c *Cert) RootCertSN() string {
+	return c.rootCertSN
+}
+
+// AddAlipayCert 添加支付宝公钥证书(证书更新时使用)，后添加的证书作为默认验签证书；
+// 证书链(如：支付宝公钥证书 + 中间CA证书)仅使用其中的非CA证书
+func (c *Cert) AddAlipayCert(pemBlock []byte) error {
+	certs, err := parseCerts(pemBlock, false)
+	if err != nil {
+		return err
+	}
+
+	leaf := certs[0]
+	for _, cert := range certs {
+		if !cert.IsCA {
+			leaf = cert
+			break
+		}
+	}
+
+	key, err := xcrypto.NewPublicKeyFromCert(leaf)
+	if err != nil {
+		return err
+	}
+
+	sn := CertSN(leaf)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.pubKeys[sn] = key
+	c.latestSN = sn
+
+	return nil
+}
+
+// publicKey 根据「alipay_cert_sn」获取支付宝公钥，sn为空时返回默认公钥
+func (c *Cert) publicKey(sn string) (*xcrypto.PublicKey, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if len(sn) == 0 {
+		sn = c.latestSN
+	}
+
+	key, ok := c.pubKeys[sn]
+	if !ok {
+		return nil, fmt.Errorf("alipay cert (sn = %s) not found", sn)
+	}
+	return key, nil
+}
+
+// CertSN 计算证书SN：MD5(issuer + serial_number)
+func CertSN(cert *x509.Certificate) string {
+	return xhash.MD5(cert.Issuer.String() + cert.SerialNumber.String())
+}
+
+// RootCertSN 计算支付宝根证书SN，仅计算「SHA256WithRSA」和「SHA1WithRSA」签名的证书，以「_」连接；
+// 标准库无法解析的证书(如：SM2证书)直接跳过
+func RootCertSN(pemBlock []byte) (string, error) {
+	certs, err := parseCerts(pemBlock, true)
+	if err != nil {
+		return "", err
+	}
+
+	sns := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		if cert.SignatureAlgorithm != x509.SHA256WithRSA && cert.SignatureAlgorithm != x509.SHA1WithRSA {
+			continue
+		}
+		sns = append(sns, CertSN(cert))
+	}
+	if len(sns) == 0 {
+		return "", errors.New("no rsa cert is found in root cert")
+	}
+	return strings.Join(sns, "_"), nil
+}
+
+// NewCert 通过PEM字节生成公钥证书信息
+// appCert: 应用公钥证书(appCertPublicKey.crt)
+// alipayCert: 支付宝公钥证书(alipayCertPublicKey_RSA2.crt)
+// rootCert: 支付宝根证书(alipayRootCert.crt)
+func NewCert(appCert, alipayCert, rootCert []byte) (*Cert, error) {
+	certs, err := parseCerts(appCert, false)
+	if err != nil {
+		return nil, fmt.Errorf("app cert: %w", err)
+	}
+
+	rootSN, err := RootCertSN(rootCert)
+	if err != nil {
+		return nil, fmt.Errorf("root cert: %w", err)
+	}
+
+	c := &Cert{
+		appCertSN:  CertSN(certs[0]),
+		rootCertSN: rootSN,
+		pubKeys:    make(map[string]*xcrypto.PublicKey),
+	}
+	if err = c.AddAlipayCert(alipayCert); err != nil {
+		return nil, fmt.Errorf("alipay cert: %w", err)
+	}
+	return c, nil
+}
+
+// NewCertFromFile 通过证书文件生成公钥证书信息
+func NewCertFromFile(appCertFile, alipayCertFile, rootCertFile string) (*Cert, error) {
+	appCert, err := readFile(appCertFile)
+	if err != nil {
+		return nil, err
+	}
+	alipayCert, err := readFile(alipayCertFile)
+	if err != nil {
+		return nil, err
+	}
+	rootCert, err := readFile(rootCertFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewCert(appCert, alipayCert, rootCert)
+}
+
+// parseCerts 解析PEM中的全部证书，skipInvalid 为true时跳过无法解析的证书
+func parseCerts(pemBlock []byte, skipInvalid bool) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+
+	for {
+		var block *pem.Block
+
+		block, pemBlock = pem.Decode(pemBlock)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			if skipInvalid {
+				continue
+			}
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM data is found")
+	}
+	return certs, nil
+}
+
+func readFile(filename string) ([]byte, error) {
+	path, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
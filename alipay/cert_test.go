@@ -0,0 +1,166 @@
+package alipay
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tjfoc/gmsm/sm2"
+	gmx509 "github.com/tjfoc/gmsm/x509"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+	"github.com/shenghui0779/sdk-go/lib/xhash"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *rsa.PrivateKey
+	pem  []byte
+}
+
+func genTestCert(t *testing.T, cn string, serial int64, parent *testCert) *testCert {
+	return genTestCertWithCA(t, cn, serial, parent, parent == nil)
+}
+
+func genTestCertWithCA(t *testing.T, cn string, serial int64, parent *testCert, isCA bool) *testCert {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{Country: []string{"CN"}, Organization: []string{"Ant Financial"}, CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		SignatureAlgorithm:    x509.SHA256WithRSA,
+	}
+
+	issuer, signer := tpl, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, issuer, &key.PublicKey, signer)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return &testCert{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func genSM2Cert(t *testing.T) []byte {
+	key, err := sm2.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	tpl := &gmx509.Certificate{
+		SerialNumber:       big.NewInt(99),
+		Subject:            pkix.Name{CommonName: "Ant Financial Certification Authority E1"},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(time.Hour),
+		SignatureAlgorithm: gmx509.SM2WithSM3,
+	}
+
+	b, err := gmx509.CreateCertificateToPem(tpl, tpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	// 标准库无法解析SM2证书
+	block, _ := pem.Decode(b)
+	_, err = x509.ParseCertificate(block.Bytes)
+	assert.NotNil(t, err)
+
+	return b
+}
+
+func TestCert(t *testing.T) {
+	root := genTestCert(t, "Ant Financial Certification Authority R1", 1, nil)
+	alipayCert := genTestCert(t, "支付宝(中国)网络技术有限公司", 2, root)
+	appCert := genTestCert(t, "2021000000000000", 3, root)
+
+	rootPem := append(append([]byte{}, root.pem...), genSM2Cert(t)...)
+
+	cert, err := NewCert(appCert.pem, alipayCert.pem, rootPem)
+	assert.Nil(t, err)
+
+	sn := xhash.MD5(root.cert.Subject.String() + "3")
+	assert.Equal(t, sn, cert.AppCertSN())
+	// SM2证书不参与根证书SN计算
+	assert.Equal(t, xhash.MD5(root.cert.Subject.String()+"1"), cert.RootCertSN())
+
+	alipaySN := CertSN(alipayCert.cert)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, cert.AppCertSN(), r.PostForm.Get("app_cert_sn"))
+		assert.Equal(t, cert.RootCertSN(), r.PostForm.Get("alipay_root_cert_sn"))
+
+		resp := `{"code":"10000","msg":"Success","trade_no":"2013112011001004330000121536"}`
+		h := sha256.Sum256([]byte(resp))
+		sign, _ := rsa.SignPKCS1v15(rand.Reader, alipayCert.key, crypto.SHA256, h[:])
+
+		_, _ = fmt.Fprintf(w, `{"alipay_trade_query_response":%s,"alipay_cert_sn":"%s","sign":"%s"}`, resp, alipaySN, base64.StdEncoding.EncodeToString(sign))
+	}))
+	defer srv.Close()
+
+	appKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	prvKey, err := xcrypto.NewPrivateKeyFromPemBlock(xcrypto.RSA_PKCS1, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(appKey)}))
+	assert.Nil(t, err)
+
+	cli := NewClient("2021000000000000", "", WithPrivateKey(prvKey), WithCert(cert))
+	cli.gateway = srv.URL
+
+	ret, err := cli.Do(context.Background(), "alipay.trade.query", WithBizContent(lib.X{"out_trade_no": "20150320010101001"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "2013112011001004330000121536", ret.Get("trade_no").String())
+
+	// 未知的支付宝公钥证书SN
+	_, err = cert.publicKey("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestAddAlipayCertChain(t *testing.T) {
+	root := genTestCert(t, "Ant Financial Certification Authority R1", 1, nil)
+	ca := genTestCertWithCA(t, "Ant Financial Certification Authority Class 2 R1", 2, root, true)
+	leaf := genTestCert(t, "支付宝(中国)网络技术有限公司", 3, ca)
+	appCert := genTestCert(t, "2021000000000000", 4, root)
+
+	// 支付宝公钥证书 + 中间CA证书
+	chain := append(append([]byte{}, leaf.pem...), ca.pem...)
+
+	cert, err := NewCert(appCert.pem, chain, root.pem)
+	assert.Nil(t, err)
+	assert.Equal(t, CertSN(leaf.cert), cert.latestSN)
+
+	// 默认验签证书为支付宝公钥证书
+	h := sha256.Sum256([]byte("data"))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, leaf.key, crypto.SHA256, h[:])
+	assert.Nil(t, err)
+
+	key, err := cert.publicKey("")
+	assert.Nil(t, err)
+	assert.Nil(t, key.Verify(crypto.SHA256, []byte("data"), sign))
+
+	// 中间CA证书不作为验签证书
+	_, err = cert.publicKey(CertSN(ca.cert))
+	assert.NotNil(t, err)
+}
@@ -21,13 +21,18 @@ import (
 
 // Client 支付宝客户端
 type Client struct {
-	gateway string
-	appid   string
-	aesKey  string
-	prvKey  *xcrypto.PrivateKey
-	pubKey  *xcrypto.PublicKey
-	client  *resty.Client
-	logger  func(ctx context.Context, err error, data map[string]string)
+	gateway   string
+	oauth     string
+	appid     string
+	aesKey    string
+	signType  SignType
+	prvKey    *xcrypto.PrivateKey
+	pubKey    *xcrypto.PublicKey
+	sm2PrvKey *xcrypto.SM2PrivateKey
+	sm2PubKey *xcrypto.SM2PublicKey
+	cert      *Cert
+	client    *resty.Client
+	logger    func(ctx context.Context, err error, data map[string]string)
 }
 
 // AppID 返回appid
@@ -77,8 +82,9 @@ func (c *Client) Do(ctx context.Context, method string, options ...ActionOption)
 
 	// JSON串，无需解密
 	if strings.HasPrefix(ret.String(), "{") {
-		if code := ret.Get("code").String(); code != CodeOK {
-			return lib.Fail(fmt.Errorf("%s | %s (sub_code = %s, sub_msg = %s)", code, ret.Get("msg").String(), ret.Get("sub_code").String(), ret.Get("sub_msg").String()))
+		// 部分接口(如：alipay.system.oauth.token)成功时不返回code
+		if code := ret.Get("code"); code.Exists() && code.String() != CodeOK {
+			return lib.Fail(newAPIError(ret))
 		}
 		return ret, nil
 	}
@@ -134,8 +140,9 @@ func (c *Client) Upload(ctx context.Context, method string, fieldName, filePath
 
 	// JSON串，无需解密
 	if strings.HasPrefix(ret.String(), "{") {
-		if code := ret.Get("code").String(); code != CodeOK {
-			return lib.Fail(fmt.Errorf("%s | %s (sub_code = %s, sub_msg = %s)", code, ret.Get("msg").String(), ret.Get("sub_code").String(), ret.Get("sub_msg").String()))
+		// 部分接口(如：alipay.system.oauth.token)成功时不返回code
+		if code := ret.Get("code"); code.Exists() && code.String() != CodeOK {
+			return lib.Fail(newAPIError(ret))
 		}
 		return ret, nil
 	}
@@ -191,8 +198,9 @@ func (c *Client) UploadWithReader(ctx context.Context, method string, fieldName,
 
 	// JSON串，无需解密
 	if strings.HasPrefix(ret.String(), "{") {
-		if code := ret.Get("code").String(); code != CodeOK {
-			return lib.Fail(fmt.Errorf("%s | %s (sub_code = %s, sub_msg = %s)", code, ret.Get("msg").String(), ret.Get("sub_code").String(), ret.Get("sub_msg").String()))
+		// 部分接口(如：alipay.system.oauth.token)成功时不返回code
+		if code := ret.Get("code"); code.Exists() && code.String() != CodeOK {
+			return lib.Fail(newAPIError(ret))
 		}
 		return ret, nil
 	}
@@ -208,11 +216,53 @@ func (c *Client) UploadWithReader(ctx context.Context, method string, fieldName,
 	return gjson.ParseBytes(data), nil
 }
 
-func (c *Client) verifyResp(key string, body []byte) (gjson.Result, error) {
+// publicKey 返回验签公钥，公钥证书模式下根据「alipay_cert_sn」选择对应的支付宝公钥证书
+func (c *Client) publicKey(sn string) (*xcrypto.PublicKey, error) {
+	if c.cert != nil {
+		return c.cert.publicKey(sn)
+	}
 	if c.pubKey == nil {
-		return lib.Fail(errors.New("public key is nil (forgotten configure?)"))
+		return nil, errors.New("public key is nil (forgotten configure?)")
+	}
+	return c.pubKey, nil
+}
+
+// sign 使用商户私钥签名(RSA2 或 SM2)
+func (c *Client) sign(data []byte) ([]byte, error) {
+	if c.signType == SignSM2 {
+		if c.sm2PrvKey == nil {
+			return nil, errors.New("sm2 private key is nil (forgotten configure?)")
+		}
+		return c.sm2PrvKey.Sign(data)
+	}
+
+	if c.prvKey == nil {
+		return nil, errors.New("private key is nil (forgotten configure?)")
+	}
+	return c.prvKey.Sign(crypto.SHA256, data)
+}
+
+// verify 使用支付宝公钥验签，signType 为空时使用客户端的签名算法
+func (c *Client) verify(signType SignType, sn string, data, sign []byte) error {
+	if len(signType) == 0 {
+		signType = c.signType
 	}
 
+	if signType == SignSM2 {
+		if c.sm2PubKey == nil {
+			return errors.New("sm2 public key is nil (forgotten configure?)")
+		}
+		return c.sm2PubKey.Verify(data, sign)
+	}
+
+	pubKey, err := c.publicKey(sn)
+	if err != nil {
+		return err
+	}
+	return pubKey.Verify(signType.hash(), data, sign)
+}
+
+func (c *Client) verifyResp(key string, body []byte) (gjson.Result, error) {
 	ret := gjson.ParseBytes(body)
 
 	signByte, err := base64.StdEncoding.DecodeString(ret.Get("sign").String())
@@ -220,26 +270,19 @@ func (c *Client) verifyResp(key string, body []byte) (gjson.Result, error) {
 		return lib.Fail(err)
 	}
 
-	hash := crypto.SHA256
-	if ret.Get("sign_type").String() == "RSA" {
-		hash = crypto.SHA1
-	}
+	signType := SignType(ret.Get("sign_type").String())
+	certSN := ret.Get("alipay_cert_sn").String()
 
 	if errResp := ret.Get("error_response"); errResp.Exists() {
-		if err = c.pubKey.Verify(hash, []byte(errResp.Raw), signByte); err != nil {
+		if err = c.verify(signType, certSN, []byte(errResp.Raw), signByte); err != nil {
 			return lib.Fail(err)
 		}
 
-		return lib.Fail(fmt.Errorf("%s | %s (sub_code = %s, sub_msg = %s)",
-			errResp.Get("code").String(),
-			errResp.Get("msg").String(),
-			errResp.Get("sub_code").String(),
-			errResp.Get("sub_msg").String(),
-		))
+		return lib.Fail(newAPIError(errResp))
 	}
 
 	resp := ret.Get(key)
-	if err = c.pubKey.Verify(hash, []byte(resp.Raw), signByte); err != nil {
+	if err = c.verify(signType, certSN, []byte(resp.Raw), signByte); err != nil {
 		return lib.Fail(err)
 	}
 	return resp, nil
@@ -281,17 +324,24 @@ func (c *Client) Decrypt(encryptData string) ([]byte, error) {
 	return xcrypto.AESDecryptCBC(key, make([]byte, 16), data)
 }
 
-// DecodeEncryptData 解析加密数据，如：授权的用户信息和手机号
+// DecodeEncryptData 解析加密数据，如：授权的用户信息和手机号(SM2签名模式下忽略 hash)
 func (c *Client) DecodeEncryptData(hash crypto.Hash, data, sign string) ([]byte, error) {
-	if c.pubKey == nil {
-		return nil, errors.New("public key is nil (forgotten configure?)")
+	signType := SignRSA2
+	if hash == crypto.SHA1 {
+		signType = SignRSA
 	}
+	if c.signType == SignSM2 {
+		signType = SignSM2
+	}
+	return c.decodeEncryptData(signType, data, sign)
+}
 
+func (c *Client) decodeEncryptData(signType SignType, data, sign string) ([]byte, error) {
 	signByte, err := base64.StdEncoding.DecodeString(sign)
 	if err != nil {
 		return nil, fmt.Errorf("sign base64.decode error: %w", err)
 	}
-	if err = c.pubKey.Verify(hash, []byte(`"`+data+`"`), signByte); err != nil {
+	if err = c.verify(signType, "", []byte(`"`+data+`"`), signByte); err != nil {
 		return nil, fmt.Errorf("sign verified error: %w", err)
 	}
 	return c.Decrypt(data)
@@ -299,32 +349,7 @@ func (c *Client) DecodeEncryptData(hash crypto.Hash, data, sign string) ([]byte,
 
 // VerifyNotify 验证回调通知表单数据
 func (c *Client) VerifyNotify(form url.Values) (value.V, error) {
-	if c.pubKey == nil {
-		return nil, errors.New("public key is nil (forgotten configure?)")
-	}
-
-	sign, err := base64.StdEncoding.DecodeString(form.Get("sign"))
-	if err != nil {
-		return nil, err
-	}
-
-	v := value.V{}
-	for key, vals := range form {
-		if key == "sign_type" || key == "sign" || len(vals) == 0 {
-			continue
-		}
-		v.Set(key, vals[0])
-	}
-	str := v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore))
-
-	hash := crypto.SHA256
-	if form.Get("sign_type") == "RSA" {
-		hash = crypto.SHA1
-	}
-	if err = c.pubKey.Verify(hash, []byte(str), sign); err != nil {
-		return nil, err
-	}
-	return v, nil
+	return verifyNotify(form, c.verify)
 }
 
 // Option 自定义设置项
@@ -351,6 +376,22 @@ func WithPublicKey(key *xcrypto.PublicKey) Option {
 	}
 }
 
+// WithCert 设置公钥证书模式(设置后使用支付宝公钥证书验签，忽略平台RSA公钥)
+func WithCert(cert *Cert) Option {
+	return func(c *Client) {
+		c.cert = cert
+	}
+}
+
+// WithSM2 设置商户SM2私钥和平台SM2公钥，并使用国密签名(sign_type=SM2)
+func WithSM2(prvKey *xcrypto.SM2PrivateKey, pubKey *xcrypto.SM2PublicKey) Option {
+	return func(c *Client) {
+		c.signType = SignSM2
+		c.sm2PrvKey = prvKey
+		c.sm2PubKey = pubKey
+	}
+}
+
 // WithLogger 设置日志记录
 func WithLogger(fn func(ctx context.Context, err error, data map[string]string)) Option {
 	return func(c *Client) {
@@ -361,10 +402,12 @@ func WithLogger(fn func(ctx context.Context, err error, data map[string]string))
 // NewClient 生成支付宝客户端
 func NewClient(appid, aesKey string, options ...Option) *Client {
 	c := &Client{
-		appid:   appid,
-		aesKey:  aesKey,
-		gateway: "https://openapi.alipay.com/gateway.do",
-		client:  lib.NewClient(),
+		appid:    appid,
+		aesKey:   aesKey,
+		gateway:  "https://openapi.alipay.com/gateway.do",
+		oauth:    "https://openauth.alipay.com",
+		signType: SignRSA2,
+		client:   lib.NewClient(),
 	}
 	for _, f := range options {
 		f(c)
@@ -375,10 +418,12 @@ func NewClient(appid, aesKey string, options ...Option) *Client {
 // NewSandbox 生成支付宝沙箱环境
 func NewSandbox(appid, aesKey string, options ...Option) *Client {
 	c := &Client{
-		appid:   appid,
-		aesKey:  aesKey,
-		gateway: "https://openapi-sandbox.dl.alipaydev.com/gateway.do",
-		client:  lib.NewClient(),
+		appid:    appid,
+		aesKey:   aesKey,
+		gateway:  "https://openapi-sandbox.dl.alipaydev.com/gateway.do",
+		oauth:    "https://openauth-sandbox.dl.alipaydev.com",
+		signType: SignRSA2,
+		client:   lib.NewClient(),
 	}
 	for _, f := range options {
 		f(c)
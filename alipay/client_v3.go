@@ -24,13 +24,17 @@ import (
 
 // ClientV3 支付宝V3客户端(仅支持v3版本的接口可用)
 type ClientV3 struct {
-	host   string
-	appid  string
-	aesKey string
-	prvKey *xcrypto.PrivateKey
-	pubKey *xcrypto.PublicKey
-	client *resty.Client
-	logger func(ctx context.Context, err error, data map[string]string)
+	host      string
+	appid     string
+	aesKey    string
+	signType  SignType
+	prvKey    *xcrypto.PrivateKey
+	pubKey    *xcrypto.PublicKey
+	sm2PrvKey *xcrypto.SM2PrivateKey
+	sm2PubKey *xcrypto.SM2PublicKey
+	cert      *Cert
+	client    *resty.Client
+	logger    func(ctx context.Context, err error, data map[string]string)
 }
 
 // AppID 返回appid
@@ -83,6 +87,8 @@ func (c *ClientV3) do(ctx context.Context, method, path string, query url.Values
 		}
 	}
 
+	c.setRootCertSN(header)
+
 	authStr, err := c.Authorization(method, path, query, body, header)
 	if err != nil {
 		log.SetError(err)
@@ -173,6 +179,8 @@ func (c *ClientV3) Upload(ctx context.Context, reqPath, fieldName, filePath, biz
 	for _, f := range options {
 		f(reqHeader)
 	}
+	c.setRootCertSN(reqHeader)
+
 	authStr, err := c.Authorization(http.MethodPost, reqPath, nil, []byte(bizData), reqHeader)
 	if err != nil {
 		log.SetError(err)
@@ -223,6 +231,8 @@ func (c *ClientV3) UploadWithReader(ctx context.Context, reqPath, fieldName, fil
 	for _, f := range options {
 		f(reqHeader)
 	}
+	c.setRootCertSN(reqHeader)
+
 	authStr, err := c.Authorization(http.MethodPost, reqPath, nil, []byte(bizData), reqHeader)
 	if err != nil {
 		log.SetError(err)
@@ -260,11 +270,12 @@ func (c *ClientV3) UploadWithReader(ctx context.Context, reqPath, fieldName, fil
 
 // Authorization 生成签名并返回 HTTP Authorization
 func (c *ClientV3) Authorization(method, path string, query url.Values, body []byte, header http.Header) (string, error) {
-	if c.prvKey == nil {
-		return "", errors.New("private key not found (forgotten configure?)")
+	authStr := "app_id=" + c.appid
+	// 公钥证书模式
+	if c.cert != nil {
+		authStr += ",app_cert_sn=" + c.cert.AppCertSN()
 	}
-
-	authStr := fmt.Sprintf("app_id=%s,nonce=%s,timestamp=%d", c.appid, lib.Nonce(32), time.Now().UnixMilli())
+	authStr += fmt.Sprintf(",nonce=%s,timestamp=%d", lib.Nonce(32), time.Now().UnixMilli())
 
 	var builder strings.Builder
 
@@ -287,20 +298,16 @@ func (c *ClientV3) Authorization(method, path string, query url.Values, body []b
 		builder.WriteString("\n")
 	}
 
-	sign, err := c.prvKey.Sign(crypto.SHA256, []byte(builder.String()))
+	sign, err := c.sign([]byte(builder.String()))
 	if err != nil {
 		return "", err
 	}
-	auth := fmt.Sprintf("ALIPAY-SHA256withRSA %s,sign=%s", authStr, base64.StdEncoding.EncodeToString(sign))
+	auth := fmt.Sprintf("%s %s,sign=%s", c.signType.authScheme(), authStr, base64.StdEncoding.EncodeToString(sign))
 	return auth, nil
 }
 
 // Verify 验证签名
 func (c *ClientV3) Verify(header http.Header, body []byte) error {
-	if c.pubKey == nil {
-		return errors.New("public key not found (forgotten configure?)")
-	}
-
 	signByte, err := base64.StdEncoding.DecodeString(header.Get(HeaderSignature))
 	if err != nil {
 		return err
@@ -320,7 +327,60 @@ func (c *ClientV3) Verify(header http.Header, body []byte) error {
 		builder.WriteString("\n")
 	}
 
-	return c.pubKey.Verify(crypto.SHA256, []byte(builder.String()), signByte)
+	return c.verify(c.signType, header.Get(HeaderSN), []byte(builder.String()), signByte)
+}
+
+// sign 使用商户私钥签名(RSA2 或 SM2)
+func (c *ClientV3) sign(data []byte) ([]byte, error) {
+	if c.signType == SignSM2 {
+		if c.sm2PrvKey == nil {
+			return nil, errors.New("sm2 private key not found (forgotten configure?)")
+		}
+		return c.sm2PrvKey.Sign(data)
+	}
+
+	if c.prvKey == nil {
+		return nil, errors.New("private key not found (forgotten configure?)")
+	}
+	return c.prvKey.Sign(crypto.SHA256, data)
+}
+
+// verify 使用支付宝公钥验签，signType 为空时使用客户端的签名算法
+func (c *ClientV3) verify(signType SignType, sn string, data, sign []byte) error {
+	if len(signType) == 0 {
+		signType = c.signType
+	}
+
+	if signType == SignSM2 {
+		if c.sm2PubKey == nil {
+			return errors.New("sm2 public key not found (forgotten configure?)")
+		}
+		return c.sm2PubKey.Verify(data, sign)
+	}
+
+	pubKey, err := c.publicKey(sn)
+	if err != nil {
+		return err
+	}
+	return pubKey.Verify(signType.hash(), data, sign)
+}
+
+// publicKey 返回验签公钥，公钥证书模式下根据「alipay-sn」选择对应的支付宝公钥证书
+func (c *ClientV3) publicKey(sn string) (*xcrypto.PublicKey, error) {
+	if c.cert != nil {
+		return c.cert.publicKey(sn)
+	}
+	if c.pubKey == nil {
+		return nil, errors.New("public key not found (forgotten configure?)")
+	}
+	return c.pubKey, nil
+}
+
+// setRootCertSN 公钥证书模式下设置「alipay-root-cert-sn」
+func (c *ClientV3) setRootCertSN(header http.Header) {
+	if c.cert != nil && len(header.Get(HeaderRootCertSN)) == 0 {
+		header.Set(HeaderRootCertSN, c.cert.RootCertSN())
+	}
 }
 
 // Encrypt 数据加密
@@ -373,6 +433,22 @@ func WithV3PublicKey(key *xcrypto.PublicKey) V3Option {
 	}
 }
 
+// WithV3Cert 设置公钥证书模式(设置后使用支付宝公钥证书验签，忽略平台RSA公钥)
+func WithV3Cert(cert *Cert) V3Option {
+	return func(c *ClientV3) {
+		c.cert = cert
+	}
+}
+
+// WithV3SM2 设置商户SM2私钥和平台SM2公钥，并使用国密签名(ALIPAY-SM2withSM3)
+func WithV3SM2(prvKey *xcrypto.SM2PrivateKey, pubKey *xcrypto.SM2PublicKey) V3Option {
+	return func(c *ClientV3) {
+		c.signType = SignSM2
+		c.sm2PrvKey = prvKey
+		c.sm2PubKey = pubKey
+	}
+}
+
 // WithV3Logger 设置日志记录
 func WithV3Logger(fn func(ctx context.Context, err error, data map[string]string)) V3Option {
 	return func(c *ClientV3) {
@@ -383,10 +459,11 @@ func WithV3Logger(fn func(ctx context.Context, err error, data map[string]string
 // NewClientV3 生成支付宝客户端V3
 func NewClientV3(appid, aesKey string, options ...V3Option) *ClientV3 {
 	c := &ClientV3{
-		host:   "https://openapi.alipay.com",
-		appid:  appid,
-		aesKey: aesKey,
-		client: lib.NewClient(),
+		host:     "https://openapi.alipay.com",
+		appid:    appid,
+		aesKey:   aesKey,
+		signType: SignRSA2,
+		client:   lib.NewClient(),
 	}
 	for _, f := range options {
 		f(c)
@@ -397,10 +474,11 @@ func NewClientV3(appid, aesKey string, options ...V3Option) *ClientV3 {
 // NewSandboxV3 生成支付宝沙箱V3
 func NewSandboxV3(appid, aesKey string, options ...V3Option) *ClientV3 {
 	c := &ClientV3{
-		host:   "http://openapi.sandbox.dl.alipaydev.com",
-		appid:  appid,
-		aesKey: aesKey,
-		client: lib.NewClient(),
+		host:     "http://openapi.sandbox.dl.alipaydev.com",
+		appid:    appid,
+		aesKey:   aesKey,
+		signType: SignRSA2,
+		client:   lib.NewClient(),
 	}
 	for _, f := range options {
 		f(c)
@@ -0,0 +1,103 @@
+package alipay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var decimalRegex = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// Decimal 金额(单位：元)，以字符串保存，避免 float64 精度丢失
+type Decimal string
+
+// NewDecimal 通过字符串生成金额，如：88.88
+func NewDecimal(s string) (Decimal, error) {
+	if !decimalRegex.MatchString(s) {
+		return "", fmt.Errorf("invalid decimal: %q", s)
+	}
+	return Decimal(s), nil
+}
+
+// NewDecimalFromCents 通过金额(单位：分)生成金额
+func NewDecimalFromCents(cents int64) Decimal {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return Decimal(fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100))
+}
+
+// String 返回金额字符串
+func (d Decimal) String() string {
+	return string(d)
+}
+
+// IsZero 判断金额是否为空或0
+func (d Decimal) IsZero() bool {
+	return len(strings.Trim(strings.TrimLeft(string(d), "-"), "0.")) == 0
+}
+
+// Cents 转换为金额(单位：分)，小数位超过两位时返回错误
+func (d Decimal) Cents() (int64, error) {
+	s := string(d)
+	if len(s) == 0 {
+		return 0, nil
+	}
+	if !decimalRegex.MatchString(s) {
+		return 0, fmt.Errorf("invalid decimal: %q", s)
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+
+	yuan, fen, _ := strings.Cut(s, ".")
+	if len(fen) > 2 {
+		if strings.Trim(fen[2:], "0") != "" {
+			return 0, fmt.Errorf("decimal %q has more than 2 decimal places", string(d))
+		}
+		fen = fen[:2]
+	}
+	fen += strings.Repeat("0", 2-len(fen))
+
+	cents, err := strconv.ParseInt(yuan+fen, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+// UnmarshalJSON 兼容字符串和数字两种格式
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	if len(b) != 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		if len(s) == 0 {
+			*d = ""
+			return nil
+		}
+		b = []byte(s)
+	}
+
+	v, err := NewDecimal(string(b))
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
@@ -0,0 +1,353 @@
+package alipay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// ErrEreceiptTimeout 电子回单生成超时
+var ErrEreceiptTimeout = errors.New("ereceipt apply timeout")
+
+// Participant 收/付款方信息
+type Participant struct {
+	Identity     string `json:"identity"`
+	IdentityType string `json:"identity_type"` // ALIPAY_USER_ID：支付宝会员ID；ALIPAY_LOGON_ID：支付宝登录号；ALIPAY_OPEN_ID：支付宝openid
+	Name         string `json:"name,omitempty"`
+}
+
+// FundTransfer 单笔转账请求参数
+type FundTransfer struct {
+	OutBizNo       string       `json:"out_biz_no"`
+	TransAmount    Decimal      `json:"trans_amount"`
+	ProductCode    string       `json:"product_code"` // 默认：TRANS_ACCOUNT_NO_PWD
+	BizScene       string       `json:"biz_scene"`    // 默认：DIRECT_TRANSFER
+	OrderTitle     string       `json:"order_title,omitempty"`
+	PayeeInfo      *Participant `json:"payee_info"`
+	Remark         string       `json:"remark,omitempty"`
+	BusinessParams string       `json:"business_params,omitempty"`
+}
+
+// FundTransferResult 单笔转账结果
+type FundTransferResult struct {
+	OutBizNo       string `json:"out_biz_no"`
+	OrderID        string `json:"order_id"`
+	PayFundOrderID string `json:"pay_fund_order_id"`
+	Status         string `json:"status"` // SUCCESS：成功；DEALING：处理中；FAIL：失败
+	TransDate      Time   `json:"trans_date"`
+}
+
+// FundTransQuery 转账业务单据查询请求参数
+type FundTransQuery struct {
+	ProductCode    string `json:"product_code,omitempty"`
+	BizScene       string `json:"biz_scene,omitempty"`
+	OutBizNo       string `json:"out_biz_no,omitempty"`
+	OrderID        string `json:"order_id,omitempty"`
+	PayFundOrderID string `json:"pay_fund_order_id,omitempty"`
+}
+
+// FundTransQueryResult 转账业务单据查询结果
+type FundTransQueryResult struct {
+	OrderID        string  `json:"order_id"`
+	PayFundOrderID string  `json:"pay_fund_order_id"`
+	OutBizNo       string  `json:"out_biz_no"`
+	TransAmount    Decimal `json:"trans_amount"`
+	Status         string  `json:"status"` // SUCCESS、DEALING、REFUND、FAIL
+	PayDate        Time    `json:"pay_date"`
+	ArrivalTimeEnd Time    `json:"arrival_time_end"` // 预计到账时间
+	OrderFee       Decimal `json:"order_fee"`
+	ErrorCode      string  `json:"error_code"`
+	FailReason     string  `json:"fail_reason"`
+}
+
+// FundAccountQuery 账户余额查询请求参数
+type FundAccountQuery struct {
+	AlipayUserID string `json:"alipay_user_id"`
+	AccountType  string `json:"account_type,omitempty"` // 默认：ACCTRANS_ACCOUNT
+}
+
+// FundAccountQueryResult 账户余额查询结果
+type FundAccountQueryResult struct {
+	AvailableAmount Decimal `json:"available_amount"`
+	FreezeAmount    Decimal `json:"freeze_amount"`
+}
+
+// EreceiptApply 申请电子回单请求参数
+type EreceiptApply struct {
+	Type string `json:"type"` // FUND_DETAIL：资金业务回单
+	Key  string `json:"key"`  // 根据 type 传入，如：转账的支付宝转账单号(pay_fund_order_id)
+}
+
+// EreceiptStatus 电子回单状态
+type EreceiptStatus string
+
+const (
+	EreceiptInit    EreceiptStatus = "INIT"
+	EreceiptProcess EreceiptStatus = "PROCESS"
+	EreceiptSuccess EreceiptStatus = "SUCCESS"
+	EreceiptFail    EreceiptStatus = "FAIL"
+)
+
+// EreceiptQueryResult 电子回单查询结果
+type EreceiptQueryResult struct {
+	Status       EreceiptStatus `json:"status"`
+	DownloadURL  string         `json:"download_url"`
+	ErrorMessage string         `json:"error_message"`
+}
+
+func (req *FundTransfer) setDefaults() {
+	if len(req.ProductCode) == 0 {
+		req.ProductCode = "TRANS_ACCOUNT_NO_PWD"
+	}
+	if len(req.BizScene) == 0 {
+		req.BizScene = "DIRECT_TRANSFER"
+	}
+}
+
+func (req *FundTransQuery) values() url.Values {
+	query := url.Values{}
+	for k, v := range map[string]string{
+		"product_code":      req.ProductCode,
+		"biz_scene":         req.BizScene,
+		"out_biz_no":        req.OutBizNo,
+		"order_id":          req.OrderID,
+		"pay_fund_order_id": req.PayFundOrderID,
+	} {
+		if len(v) != 0 {
+			query.Set(k, v)
+		}
+	}
+	return query
+}
+
+// FundTransfer 单笔转账到支付宝账户
+// [参考](https://opendocs.alipay.com/open/02byuo)
+func (c *Client) FundTransfer(ctx context.Context, req *FundTransfer, options ...ActionOption) (*FundTransferResult, error) {
+	// 复制请求参数，避免修改调用方的数据
+	r := *req
+	r.setDefaults()
+
+	result := new(FundTransferResult)
+	if err := c.doBiz(ctx, "alipay.fund.trans.uni.transfer", &r, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FundTransQuery 转账业务单据查询
+// [参考](https://opendocs.alipay.com/open/02byup)
+func (c *Client) FundTransQuery(ctx context.Context, req *FundTransQuery, options ...ActionOption) (*FundTransQueryResult, error) {
+	result := new(FundTransQueryResult)
+	if err := c.doBiz(ctx, "alipay.fund.trans.common.query", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FundAccountQuery 支付宝资金账户余额查询
+// [参考](https://opendocs.alipay.com/open/02byuq)
+func (c *Client) FundAccountQuery(ctx context.Context, req *FundAccountQuery, options ...ActionOption) (*FundAccountQueryResult, error) {
+	result := new(FundAccountQueryResult)
+	if err := c.doBiz(ctx, "alipay.fund.account.query", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// EreceiptApply 申请电子回单，返回文件申请号(file_id)
+func (c *Client) EreceiptApply(ctx context.Context, req *EreceiptApply, options ...ActionOption) (string, error) {
+	data, err := lib.ToX(req)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := c.Do(ctx, "alipay.data.bill.ereceipt.apply", append([]ActionOption{WithBizContent(data)}, options...)...)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("file_id").String(), nil
+}
+
+// EreceiptQuery 查询电子回单状态
+func (c *Client) EreceiptQuery(ctx context.Context, fileID string, options ...ActionOption) (*EreceiptQueryResult, error) {
+	result := new(EreceiptQueryResult)
+	if err := c.doBiz(ctx, "alipay.data.bill.ereceipt.query", map[string]string{"file_id": fileID}, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DownloadEreceipt 申请电子回单并按 interval(须大于0) 轮询至生成完成，然后将回单文件(PDF)写入 w；
+// 超时未生成返回 ErrEreceiptTimeout
+func (c *Client) DownloadEreceipt(ctx context.Context, req *EreceiptApply, interval, timeout time.Duration, w io.Writer, options ...ActionOption) (int64, error) {
+	return downloadEreceipt(ctx, interval, timeout,
+		func() (string, error) {
+			return c.EreceiptApply(ctx, req, options...)
+		},
+		func(fileID string) (*EreceiptQueryResult, error) {
+			return c.EreceiptQuery(ctx, fileID, options...)
+		},
+		func(downloadURL string) (int64, error) {
+			return download(ctx, c.client, c.logger, downloadURL, w)
+		},
+	)
+}
+
+// FundTransfer 单笔转账到支付宝账户
+func (c *ClientV3) FundTransfer(ctx context.Context, req *FundTransfer, options ...V3HeaderOption) (*FundTransferResult, error) {
+	// 复制请求参数，避免修改调用方的数据
+	r := *req
+	r.setDefaults()
+
+	params, err := lib.ToX(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := c.PostJSON(ctx, "/v3/alipay/fund/trans/uni/transfer", params, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(FundTransferResult)
+	if err = ret.Unmarshal(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FundTransQuery 转账业务单据查询
+func (c *ClientV3) FundTransQuery(ctx context.Context, req *FundTransQuery, options ...V3HeaderOption) (*FundTransQueryResult, error) {
+	ret, err := c.GetJSON(ctx, "/v3/alipay/fund/trans/common/query", req.values(), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(FundTransQueryResult)
+	if err = ret.Unmarshal(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FundAccountQuery 支付宝资金账户余额查询
+func (c *ClientV3) FundAccountQuery(ctx context.Context, req *FundAccountQuery, options ...V3HeaderOption) (*FundAccountQueryResult, error) {
+	query := url.Values{}
+	query.Set("alipay_user_id", req.AlipayUserID)
+	if len(req.AccountType) != 0 {
+		query.Set("account_type", req.AccountType)
+	}
+
+	ret, err := c.GetJSON(ctx, "/v3/alipay/fund/account/query", query, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(FundAccountQueryResult)
+	if err = ret.Unmarshal(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// EreceiptApply 申请电子回单，返回文件申请号(file_id)
+func (c *ClientV3) EreceiptApply(ctx context.Context, req *EreceiptApply, options ...V3HeaderOption) (string, error) {
+	params, err := lib.ToX(req)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := c.PostJSON(ctx, "/v3/alipay/data/bill/ereceipt/apply", params, options...)
+	if err != nil {
+		return "", err
+	}
+	if err = ret.Err(); err != nil {
+		return "", err
+	}
+	return ret.Body.Get("file_id").String(), nil
+}
+
+// EreceiptQuery 查询电子回单状态
+func (c *ClientV3) EreceiptQuery(ctx context.Context, fileID string, options ...V3HeaderOption) (*EreceiptQueryResult, error) {
+	query := url.Values{}
+	query.Set("file_id", fileID)
+
+	ret, err := c.GetJSON(ctx, "/v3/alipay/data/bill/ereceipt/query", query, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(EreceiptQueryResult)
+	if err = ret.Unmarshal(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DownloadEreceipt 申请电子回单并按 interval(须大于0) 轮询至生成完成，然后将回单文件(PDF)写入 w；
+// 超时未生成返回 ErrEreceiptTimeout
+func (c *ClientV3) DownloadEreceipt(ctx context.Context, req *EreceiptApply, interval, timeout time.Duration, w io.Writer, options ...V3HeaderOption) (int64, error) {
+	return downloadEreceipt(ctx, interval, timeout,
+		func() (string, error) {
+			return c.EreceiptApply(ctx, req, options...)
+		},
+		func(fileID string) (*EreceiptQueryResult, error) {
+			return c.EreceiptQuery(ctx, fileID, options...)
+		},
+		func(downloadURL string) (int64, error) {
+			return download(ctx, c.client, c.logger, downloadURL, w)
+		},
+	)
+}
+
+// downloadEreceipt 电子回单下载流程：申请 -> 轮询 -> 下载
+func downloadEreceipt(ctx context.Context, interval, timeout time.Duration,
+	apply func() (string, error),
+	query func(fileID string) (*EreceiptQueryResult, error),
+	fetch func(downloadURL string) (int64, error),
+) (int64, error) {
+	if interval <= 0 {
+		return 0, errors.New("ereceipt query interval must be greater than 0")
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	fileID, err := apply()
+	if err != nil {
+		return 0, err
+	}
+	if len(fileID) == 0 {
+		return 0, errors.New("ereceipt file_id is empty")
+	}
+
+	for {
+		ret, err := query(fileID)
+		if err != nil {
+			return 0, err
+		}
+
+		switch ret.Status {
+		case EreceiptSuccess:
+			return fetch(ret.DownloadURL)
+		case EreceiptFail:
+			return 0, fmt.Errorf("ereceipt apply failed: %s", ret.ErrorMessage)
+		}
+
+		if !time.Now().Add(interval).Before(deadline) {
+			return 0, ErrEreceiptTimeout
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
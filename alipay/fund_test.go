@@ -0,0 +1,136 @@
+package alipay
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestFundTransfer(t *testing.T) {
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		assert.Equal(t, "alipay.fund.trans.uni.transfer", method)
+		assert.Equal(t, "TRANS_ACCOUNT_NO_PWD", biz.Get("product_code").String())
+		assert.Equal(t, "DIRECT_TRANSFER", biz.Get("biz_scene").String())
+		assert.Equal(t, "1.68", biz.Get("trans_amount").String())
+		assert.Equal(t, "ALIPAY_USER_ID", biz.Get("payee_info.identity_type").String())
+		return `{"code":"10000","msg":"Success","out_biz_no":"201806300001","order_id":"20190801110070000006380000250621","pay_fund_order_id":"20190801110070001506380000251556","status":"SUCCESS","trans_date":"2019-08-21 00:00:00"}`
+	})
+	defer closeFn()
+
+	req := &FundTransfer{
+		OutBizNo:    "201806300001",
+		TransAmount: NewDecimalFromCents(168),
+		OrderTitle:  "转账标题",
+		PayeeInfo: &Participant{
+			Identity:     "2088123412341234",
+			IdentityType: "ALIPAY_USER_ID",
+		},
+	}
+
+	ret, err := cli.FundTransfer(context.Background(), req)
+	assert.Nil(t, err)
+	// 默认值不写回调用方的请求参数
+	assert.Empty(t, req.ProductCode)
+	assert.Empty(t, req.BizScene)
+	assert.Equal(t, "SUCCESS", ret.Status)
+	assert.Equal(t, "20190801110070001506380000251556", ret.PayFundOrderID)
+	assert.Equal(t, "2019-08-21 00:00:00", ret.TransDate.In(cstZone).Format(TimeLayout))
+}
+
+func TestFundAccountQuery(t *testing.T) {
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		assert.Equal(t, "alipay.fund.account.query", method)
+		assert.Equal(t, "2088301409188095", biz.Get("alipay_user_id").String())
+		return `{"code":"10000","msg":"Success","available_amount":"32.00","freeze_amount":"1.00"}`
+	})
+	defer closeFn()
+
+	ret, err := cli.FundAccountQuery(context.Background(), &FundAccountQuery{AlipayUserID: "2088301409188095"})
+	assert.Nil(t, err)
+	assert.Equal(t, Decimal("32.00"), ret.AvailableAmount)
+	assert.Equal(t, Decimal("1.00"), ret.FreezeAmount)
+}
+
+func TestDownloadEreceipt(t *testing.T) {
+	var queries int32
+
+	fileSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("%PDF-1.4"))
+	}))
+	defer fileSrv.Close()
+
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		switch method {
+		case "alipay.data.bill.ereceipt.apply":
+			assert.Equal(t, "FUND_DETAIL", biz.Get("type").String())
+			return `{"code":"10000","msg":"Success","file_id":"2019122311001004330000121536"}`
+		case "alipay.data.bill.ereceipt.query":
+			assert.Equal(t, "2019122311001004330000121536", biz.Get("file_id").String())
+			if atomic.AddInt32(&queries, 1) < 2 {
+				return `{"code":"10000","msg":"Success","status":"PROCESS"}`
+			}
+			return `{"code":"10000","msg":"Success","status":"SUCCESS","download_url":"` + fileSrv.URL + `/ereceipt.pdf"}`
+		}
+		return `{"code":"40004","msg":"Business Failed"}`
+	})
+	defer closeFn()
+
+	var buf bytes.Buffer
+
+	n, err := cli.DownloadEreceipt(context.Background(), &EreceiptApply{
+		Type: "FUND_DETAIL",
+		Key:  "20190801110070001506380000251556",
+	}, 10*time.Millisecond, time.Second, &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), n)
+	assert.Equal(t, "%PDF-1.4", buf.String())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&queries))
+}
+
+func TestDownloadEreceiptTimeout(t *testing.T) {
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		switch method {
+		case "alipay.data.bill.ereceipt.apply":
+			return `{"code":"10000","msg":"Success","file_id":"2019122311001004330000121536"}`
+		case "alipay.data.bill.ereceipt.query":
+			return `{"code":"10000","msg":"Success","status":"INIT"}`
+		}
+		return `{"code":"40004","msg":"Business Failed"}`
+	})
+	defer closeFn()
+
+	var buf bytes.Buffer
+
+	_, err := cli.DownloadEreceipt(context.Background(), &EreceiptApply{
+		Type: "FUND_DETAIL",
+		Key:  "20190801110070001506380000251556",
+	}, 10*time.Millisecond, 50*time.Millisecond, &buf)
+	assert.ErrorIs(t, err, ErrEreceiptTimeout)
+	assert.Zero(t, buf.Len())
+}
+
+func TestDownloadEreceiptInterval(t *testing.T) {
+	var called int32
+
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		atomic.AddInt32(&called, 1)
+		return `{"code":"10000","msg":"Success","file_id":"2019122311001004330000121536"}`
+	})
+	defer closeFn()
+
+	var buf bytes.Buffer
+
+	_, err := cli.DownloadEreceipt(context.Background(), &EreceiptApply{
+		Type: "FUND_DETAIL",
+		Key:  "20190801110070001506380000251556",
+	}, 0, time.Second, &buf)
+	assert.EqualError(t, err, "ereceipt query interval must be greater than 0")
+	assert.Zero(t, atomic.LoadInt32(&called))
+}
@@ -0,0 +1,438 @@
+package alipay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+// ErrAppAuthNotFound 商户授权不存在
+var ErrAppAuthNotFound = errors.New("app auth token not found")
+
+// 应用授权相关通知
+const (
+	NotifyTypeAppAuth   = "open_app_auth_notify"               // 应用授权通知
+	MsgAppAuthCancelled = "alipay.open.auth.appauth.cancelled" // 应用授权取消消息
+)
+
+// AppAuthToken 商户授权令牌
+type AppAuthToken struct {
+	AuthAppID       string    `json:"auth_app_id"`
+	UserID          string    `json:"user_id"`
+	AppAuthToken    string    `json:"app_auth_token"`
+	AppRefreshToken string    `json:"app_refresh_token"`
+	ExpiresIn       int64     `json:"expires_in"`
+	ReExpiresIn     int64     `json:"re_expires_in"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	ReExpiresAt     time.Time `json:"re_expires_at"`
+}
+
+// parseAppAuthToken 解析令牌，以 now 计算过期时间
+func parseAppAuthToken(ret gjson.Result, now time.Time) (*AppAuthToken, error) {
+	token := &AppAuthToken{
+		AuthAppID:       ret.Get("auth_app_id").String(),
+		UserID:          ret.Get("user_id").String(),
+		AppAuthToken:    ret.Get("app_auth_token").String(),
+		AppRefreshToken: ret.Get("app_refresh_token").String(),
+		ExpiresIn:       ret.Get("expires_in").Int(), // 可能以字符串返回
+		ReExpiresIn:     ret.Get("re_expires_in").Int(),
+	}
+	if len(token.AuthAppID) == 0 || len(token.AppAuthToken) == 0 {
+		return nil, errors.New("app_auth_token or auth_app_id is empty")
+	}
+	token.ExpiresAt = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	token.ReExpiresAt = now.Add(time.Duration(token.ReExpiresIn) * time.Second)
+	return token, nil
+}
+
+// AppAuthStore 商户授权令牌存储
+type AppAuthStore interface {
+	// Get 获取令牌，不存在返回 ErrAppAuthNotFound
+	Get(ctx context.Context, authAppID string) (*AppAuthToken, error)
+	// Set 保存令牌
+	Set(ctx context.Context, token *AppAuthToken) error
+	// Del 删除令牌
+	Del(ctx context.Context, authAppID string) error
+}
+
+type memAppAuthStore struct {
+	mutex  sync.RWMutex
+	tokens map[string]*AppAuthToken
+}
+
+func (s *memAppAuthStore) Get(ctx context.Context, authAppID string) (*AppAuthToken, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	token, ok := s.tokens[authAppID]
+	if !ok {
+		return nil, ErrAppAuthNotFound
+	}
+	return token, nil
+}
+
+func (s *memAppAuthStore) Set(ctx context.Context, token *AppAuthToken) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens[token.AuthAppID] = token
+	return nil
+}
+
+func (s *memAppAuthStore) Del(ctx context.Context, authAppID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.tokens, authAppID)
+	return nil
+}
+
+// NewMemAppAuthStore 基于内存的令牌存储(仅适用于单实例)
+func NewMemAppAuthStore() AppAuthStore {
+	return &memAppAuthStore{tokens: make(map[string]*AppAuthToken)}
+}
+
+// AppAuthNotify 应用授权变更通知
+type AppAuthNotify struct {
+	AuthAppID string
+	Cancelled bool          // 是否为取消授权
+	Token     *AppAuthToken // 授权成功时的令牌
+	Raw       value.V
+}
+
+// AppAuthManager 第三方应用(ISV)的商户授权管理
+// [参考](https://opendocs.alipay.com/isv/04h3uf)
+type AppAuthManager struct {
+	cli           *Client
+	store         AppAuthStore
+	refreshBefore time.Duration
+	mutex         sync.Mutex
+}
+
+// ExchangeCode 使用 app_auth_code 换取令牌并保存(批量授权时返回多个)
+func (m *AppAuthManager) ExchangeCode(ctx context.Context, code string) ([]*AppAuthToken, error) {
+	return m.requestToken(ctx, lib.X{
+		"grant_type": OAuthCode,
+		"code":       code,
+	})
+}
+
+// Refresh 刷新令牌并保存
+func (m *AppAuthManager) Refresh(ctx context.Context, authAppID string) (*AppAuthToken, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.refresh(ctx, authAppID)
+}
+
+func (m *AppAuthManager) refresh(ctx context.Context, authAppID string) (*AppAuthToken, error) {
+	old, err := m.store.Get(ctx, authAppID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := m.requestToken(ctx, lib.X{
+		"grant_type":    RefreshToken,
+		"refresh_token": old.AppRefreshToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range tokens {
+		if v.AuthAppID == authAppID {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("refresh token response missing auth_app_id = %s", authAppID)
+}
+
+func (m *AppAuthManager) requestToken(ctx context.Context, biz lib.X) ([]*AppAuthToken, error) {
+	now := time.Now()
+
+	ret, err := m.cli.Do(ctx, "alipay.open.auth.token.app", WithBizContent(biz))
+	if err != nil {
+		return nil, err
+	}
+
+	// 新版本以「tokens」数组返回，旧版本直接返回令牌字段
+	results := []gjson.Result{ret}
+	if arr := ret.Get("tokens"); arr.IsArray() {
+		results = arr.Array()
+	}
+
+	tokens := make([]*AppAuthToken, 0, len(results))
+	for _, v := range results {
+		token, err := parseAppAuthToken(v, now)
+		if err != nil {
+			return nil, err
+		}
+		if err = m.store.Set(ctx, token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// valid 令牌是否在刷新阈值之外；ExpiresAt 为零值表示过期时间未知(如：自行导入的令牌)，
+// 需刷新一次，刷新后会保存计算得到的过期时间
+func (m *AppAuthManager) valid(token *AppAuthToken) bool {
+	if token.ExpiresAt.IsZero() {
+		return false
+	}
+	return time.Until(token.ExpiresAt) > m.refreshBefore
+}
+
+// Token 获取商户的 app_auth_token，临近过期或过期时间未知时自动刷新
+func (m *AppAuthManager) Token(ctx context.Context, authAppID string) (string, error) {
+	token, err := m.store.Get(ctx, authAppID)
+	if err != nil {
+		return "", err
+	}
+	if m.valid(token) {
+		return token.AppAuthToken, nil
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// 再次检查，避免并发重复刷新
+	token, err = m.store.Get(ctx, authAppID)
+	if err != nil {
+		return "", err
+	}
+	if m.valid(token) {
+		return token.AppAuthToken, nil
+	}
+	if !token.ReExpiresAt.IsZero() && time.Now().After(token.ReExpiresAt) {
+		return "", fmt.Errorf("app refresh token expired (auth_app_id = %s), re-authorization required", authAppID)
+	}
+
+	token, err = m.refresh(ctx, authAppID)
+	if err != nil {
+		return "", err
+	}
+	return token.AppAuthToken, nil
+}
+
+// Revoke 删除商户授权(如：商户取消授权)
+func (m *AppAuthManager) Revoke(ctx context.Context, authAppID string) error {
+	return m.store.Del(ctx, authAppID)
+}
+
+// ParseNotify 解析并处理应用授权变更通知：授权成功保存令牌，取消授权删除令牌；
+// 校验同 Client.ParseNotify(验签、app_id、通知时效及 notify_id)，避免重放的旧通知覆盖或删除当前授权
+// [参考](https://opendocs.alipay.com/isv/06evao)
+func (m *AppAuthManager) ParseNotify(r *http.Request, options ...NotifyOption) (*AppAuthNotify, error) {
+	v, err := parseNotify(r, m.cli.appid, m.cli.VerifyNotify, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	biz := gjson.Parse(v.Get("biz_content"))
+	notify := &AppAuthNotify{Raw: v}
+
+	switch {
+	case v.Get("msg_method") == MsgAppAuthCancelled:
+		notify.AuthAppID = biz.Get("auth_app_id").String()
+		notify.Cancelled = true
+		if err = m.store.Del(ctx, notify.AuthAppID); err != nil {
+			return nil, err
+		}
+	case v.Get("notify_type") == NotifyTypeAppAuth:
+		detail := biz.Get("detail")
+		if !detail.Exists() {
+			return nil, errors.New("app auth notify missing detail")
+		}
+		token, err := parseAppAuthToken(detail, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if err = m.store.Set(ctx, token); err != nil {
+			return nil, err
+		}
+		notify.AuthAppID = token.AuthAppID
+		notify.Token = token
+	default:
+		return nil, fmt.Errorf("unsupported app auth notify (notify_type = %s, msg_method = %s)", v.Get("notify_type"), v.Get("msg_method"))
+	}
+	return notify, nil
+}
+
+// NotifyHandler 应用授权变更通知 http.Handler，fn 可为nil，处理成功应答「success」，否则应答「fail」
+func (m *AppAuthManager) NotifyHandler(fn func(ctx context.Context, n *AppAuthNotify) error, options ...NotifyOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(lib.HeaderContentType, lib.ContentText)
+
+		n, err := m.ParseNotify(r, options...)
+		if err == nil && fn != nil {
+			err = fn(r.Context(), n)
+		}
+		if err != nil {
+			log := lib.NewReqLog(r.Method, r.URL.String())
+			log.SetError(err)
+			log.Do(r.Context(), m.cli.logger)
+
+			_, _ = io.WriteString(w, NotifyFail)
+			return
+		}
+		_, _ = io.WriteString(w, NotifySuccess)
+	})
+}
+
+// Merchant 返回代指定商户调用的客户端(自动注入 app_auth_token)
+func (m *AppAuthManager) Merchant(authAppID string) *MerchantClient {
+	return &MerchantClient{manager: m, authAppID: authAppID}
+}
+
+// MerchantV3 返回代指定商户调用的V3客户端(自动注入 alipay-app-auth-token)
+func (m *AppAuthManager) MerchantV3(cli *ClientV3, authAppID string) *MerchantClientV3 {
+	return &MerchantClientV3{manager: m, cli: cli, authAppID: authAppID}
+}
+
+// AppAuthOption 商户授权管理选项
+type AppAuthOption func(m *AppAuthManager)
+
+// WithAppAuthRefreshBefore 设置令牌过期前多久自动刷新，默认：24小时
+func WithAppAuthRefreshBefore(d time.Duration) AppAuthOption {
+	return func(m *AppAuthManager) {
+		m.refreshBefore = d
+	}
+}
+
+// NewAppAuthManager 生成商户授权管理，cli 为第三方应用的客户端
+func NewAppAuthManager(cli *Client, store AppAuthStore, options ...AppAuthOption) *AppAuthManager {
+	m := &AppAuthManager{
+		cli:           cli,
+		store:         store,
+		refreshBefore: 24 * time.Hour,
+	}
+	for _, f := range options {
+		f(m)
+	}
+	return m
+}
+
+// MerchantClient 代商户调用的客户端
+type MerchantClient struct {
+	manager   *AppAuthManager
+	authAppID string
+}
+
+// AuthAppID 返回商户授权的appid
+func (mc *MerchantClient) AuthAppID() string {
+	return mc.authAppID
+}
+
+// AuthOption 返回注入 app_auth_token 的 ActionOption，可用于 TradeQuery 等接口
+func (mc *MerchantClient) AuthOption(ctx context.Context) (ActionOption, error) {
+	token, err := mc.manager.Token(ctx, mc.authAppID)
+	if err != nil {
+		return nil, err
+	}
+	return WithAppAuthToken(token), nil
+}
+
+// Do 代商户向支付宝网关发送请求
+func (mc *MerchantClient) Do(ctx context.Context, method string, options ...ActionOption) (gjson.Result, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return lib.Fail(err)
+	}
+	return mc.manager.cli.Do(ctx, method, append(options, opt)...)
+}
+
+// UploadWithReader 代商户上传文件
+func (mc *MerchantClient) UploadWithReader(ctx context.Context, method string, fieldName, fileName string, reader io.Reader, formData map[string]string, options ...ActionOption) (gjson.Result, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return lib.Fail(err)
+	}
+	return mc.manager.cli.UploadWithReader(ctx, method, fieldName, fileName, reader, formData, append(options, opt)...)
+}
+
+// PageExecute 代商户生成页面跳转URL
+func (mc *MerchantClient) PageExecute(ctx context.Context, method string, options ...ActionOption) (string, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return "", err
+	}
+	return mc.manager.cli.PageExecute(method, append(options, opt)...)
+}
+
+// PageExecuteForm 代商户生成自动提交的HTML表单
+func (mc *MerchantClient) PageExecuteForm(ctx context.Context, method string, options ...ActionOption) (string, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return "", err
+	}
+	return mc.manager.cli.PageExecuteForm(method, append(options, opt)...)
+}
+
+// MerchantClientV3 代商户调用的V3客户端
+type MerchantClientV3 struct {
+	manager   *AppAuthManager
+	cli       *ClientV3
+	authAppID string
+}
+
+// AuthAppID 返回商户授权的appid
+func (mc *MerchantClientV3) AuthAppID() string {
+	return mc.authAppID
+}
+
+// AuthOption 返回注入 alipay-app-auth-token 的 V3HeaderOption
+func (mc *MerchantClientV3) AuthOption(ctx context.Context) (V3HeaderOption, error) {
+	token, err := mc.manager.Token(ctx, mc.authAppID)
+	if err != nil {
+		return nil, err
+	}
+	return WithV3AppAuthToken(token), nil
+}
+
+// GetJSON 代商户GET请求JSON数据
+func (mc *MerchantClientV3) GetJSON(ctx context.Context, path string, query url.Values, options ...V3HeaderOption) (*APIResult, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mc.cli.GetJSON(ctx, path, query, append(options, opt)...)
+}
+
+// PostJSON 代商户POST请求JSON数据
+func (mc *MerchantClientV3) PostJSON(ctx context.Context, path string, params lib.X, options ...V3HeaderOption) (*APIResult, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mc.cli.PostJSON(ctx, path, params, append(options, opt)...)
+}
+
+// PostEncrypt 代商户POST加密请求
+func (mc *MerchantClientV3) PostEncrypt(ctx context.Context, path string, params lib.X, options ...V3HeaderOption) (*APIResult, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mc.cli.PostEncrypt(ctx, path, params, append(options, opt)...)
+}
+
+// UploadWithReader 代商户上传文件
+func (mc *MerchantClientV3) UploadWithReader(ctx context.Context, reqPath, fieldName, fileName string, reader io.Reader, bizData string, options ...V3HeaderOption) (*APIResult, error) {
+	opt, err := mc.AuthOption(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mc.cli.UploadWithReader(ctx, reqPath, fieldName, fileName, reader, bizData, append(options, opt)...)
+}
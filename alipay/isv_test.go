@@ -0,0 +1,172 @@
+package alipay
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestAppAuthManager(t *testing.T) {
+	var refreshed int32
+
+	cli, closeFn := newTestFormClient(t, func(form url.Values) string {
+		biz := gjson.Parse(form.Get("biz_content"))
+
+		switch form.Get("method") {
+		case "alipay.open.auth.token.app":
+			if biz.Get("grant_type").String() == "authorization_code" {
+				assert.Equal(t, "APP_AUTH_CODE", biz.Get("code").String())
+				return `{"code":"10000","msg":"Success","tokens":[{"app_auth_token":"TOKEN_1","app_refresh_token":"REFRESH_1","auth_app_id":"2013121100055554","expires_in":3600,"re_expires_in":"7200","user_id":"2088102150527498"}]}`
+			}
+			atomic.AddInt32(&refreshed, 1)
+			assert.Equal(t, "REFRESH_1", biz.Get("refresh_token").String())
+			return `{"code":"10000","msg":"Success","tokens":[{"app_auth_token":"TOKEN_2","app_refresh_token":"REFRESH_2","auth_app_id":"2013121100055554","expires_in":31536000,"re_expires_in":32140800,"user_id":"2088102150527498"}]}`
+		case "alipay.trade.query":
+			assert.Equal(t, "TOKEN_2", form.Get("app_auth_token"))
+			return `{"code":"10000","msg":"Success","trade_status":"TRADE_SUCCESS"}`
+		}
+		return `{"code":"40004","msg":"Business Failed"}`
+	})
+	defer closeFn()
+
+	ctx := context.Background()
+	m := NewAppAuthManager(cli, NewMemAppAuthStore(), WithAppAuthRefreshBefore(2*time.Hour))
+
+	tokens, err := m.ExchangeCode(ctx, "APP_AUTH_CODE")
+	assert.Nil(t, err)
+	assert.Len(t, tokens, 1)
+	assert.Equal(t, int64(7200), tokens[0].ReExpiresIn)
+
+	// 令牌将在1小时后过期，小于2小时的刷新阈值，自动刷新
+	mc := m.Merchant("2013121100055554")
+
+	ret, err := mc.Do(ctx, "alipay.trade.query")
+	assert.Nil(t, err)
+	assert.Equal(t, "TRADE_SUCCESS", ret.Get("trade_status").String())
+
+	opt, err := mc.AuthOption(ctx)
+	assert.Nil(t, err)
+
+	_, err = cli.TradeQuery(ctx, &TradeQuery{OutTradeNo: "20150320010101001"}, opt)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshed))
+
+	_, err = m.Token(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrAppAuthNotFound)
+}
+
+func TestAppAuthTokenUnknownExpiry(t *testing.T) {
+	var refreshed int32
+
+	cli, closeFn := newTestFormClient(t, func(form url.Values) string {
+		biz := gjson.Parse(form.Get("biz_content"))
+
+		assert.Equal(t, "alipay.open.auth.token.app", form.Get("method"))
+		assert.Equal(t, "refresh_token", biz.Get("grant_type").String())
+		assert.Equal(t, "REFRESH_1", biz.Get("refresh_token").String())
+
+		atomic.AddInt32(&refreshed, 1)
+		return `{"code":"10000","msg":"Success","tokens":[{"app_auth_token":"TOKEN_2","app_refresh_token":"REFRESH_1","auth_app_id":"2013121100055554","expires_in":31536000,"re_expires_in":32140800,"user_id":"2088102150527498"}]}`
+	})
+	defer closeFn()
+
+	ctx := context.Background()
+	store := NewMemAppAuthStore()
+
+	// 自行导入的令牌，未设置过期时间
+	assert.Nil(t, store.Set(ctx, &AppAuthToken{
+		AuthAppID:       "2013121100055554",
+		AppAuthToken:    "TOKEN_1",
+		AppRefreshToken: "REFRESH_1",
+	}))
+
+	m := NewAppAuthManager(cli, store)
+
+	// 过期时间未知，刷新一次并保存过期时间
+	for i := 0; i < 3; i++ {
+		token, err := m.Token(ctx, "2013121100055554")
+		assert.Nil(t, err)
+		assert.Equal(t, "TOKEN_2", token)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshed))
+
+	token, err := store.Get(ctx, "2013121100055554")
+	assert.Nil(t, err)
+	assert.False(t, token.ExpiresAt.IsZero())
+}
+
+func TestAppAuthNotify(t *testing.T) {
+	prvKey, pubKey := genTestKeyPair(t)
+
+	cli := NewClient("2014072300007148", "", WithPublicKey(pubKey))
+	store := NewMemAppAuthStore()
+	m := NewAppAuthManager(cli, store)
+
+	seen := make(map[string]bool)
+	checkID := WithNotifyIDCheck(func(ctx context.Context, notifyID string) error {
+		if seen[notifyID] {
+			return errors.New("duplicate notify")
+		}
+		seen[notifyID] = true
+		return nil
+	})
+
+	authForm := func(notifyID string, notifyTime time.Time) url.Values {
+		form := url.Values{}
+		form.Set("notify_id", notifyID)
+		form.Set("notify_time", notifyTime.In(cstZone).Format(TimeLayout))
+		form.Set("notify_type", NotifyTypeAppAuth)
+		form.Set("app_id", "2014072300007148")
+		form.Set("biz_content", `{"detail":{"app_auth_token":"TOKEN","app_refresh_token":"REFRESH","auth_app_id":"2013121100055554","expires_in":31536000,"re_expires_in":32140800,"user_id":"2088102150527498"},"status":"auth_success"}`)
+		signTestForm(t, prvKey, form)
+		return form
+	}
+
+	w := httptest.NewRecorder()
+	m.NotifyHandler(nil, checkID).ServeHTTP(w, newNotifyRequest(authForm("2021030900222101010078941408", time.Now())))
+	assert.Equal(t, NotifySuccess, w.Body.String())
+
+	token, err := m.Token(context.Background(), "2013121100055554")
+	assert.Nil(t, err)
+	assert.Equal(t, "TOKEN", token)
+
+	// 重放的通知(notify_id 重复或通知已过期)不会覆盖令牌
+	_, err = m.ParseNotify(newNotifyRequest(authForm("2021030900222101010078941408", time.Now())), checkID)
+	assert.NotNil(t, err)
+	_, err = m.ParseNotify(newNotifyRequest(authForm("2021030900222101010078941407", time.Now().Add(-48*time.Hour))), checkID)
+	assert.NotNil(t, err)
+
+	// 取消授权
+	cancelForm := func(notifyID string, ts time.Time) url.Values {
+		form := url.Values{}
+		form.Set("notify_id", notifyID)
+		form.Set("utc_timestamp", strconv.FormatInt(ts.UnixMilli(), 10))
+		form.Set("msg_method", MsgAppAuthCancelled)
+		form.Set("app_id", "2014072300007148")
+		form.Set("biz_content", `{"auth_app_id":"2013121100055554","app_auth_token":"TOKEN","user_id":"2088102150527498","auth_time":1615255810000}`)
+		signTestForm(t, prvKey, form)
+		return form
+	}
+
+	// 过期的取消授权通知不会删除令牌
+	_, err = m.ParseNotify(newNotifyRequest(cancelForm("2021030900222101010078941406", time.Now().Add(-48*time.Hour))), checkID)
+	assert.NotNil(t, err)
+
+	_, err = store.Get(context.Background(), "2013121100055554")
+	assert.Nil(t, err)
+
+	n, err := m.ParseNotify(newNotifyRequest(cancelForm("2021030900222101010078941409", time.Now())), checkID)
+	assert.Nil(t, err)
+	assert.True(t, n.Cancelled)
+
+	_, err = store.Get(context.Background(), "2013121100055554")
+	assert.ErrorIs(t, err, ErrAppAuthNotFound)
+}
@@ -0,0 +1,361 @@
+package alipay
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+// TimeLayout 支付宝时间格式(东八区)
+const TimeLayout = "2006-01-02 15:04:05"
+
+var cstZone = time.FixedZone("CST", 8*3600)
+
+// 异步通知应答
+const (
+	NotifySuccess = "success"
+	NotifyFail    = "fail"
+)
+
+// TradeStatus 交易状态
+type TradeStatus string
+
+const (
+	TradeWaitBuyerPay TradeStatus = "WAIT_BUYER_PAY" // 交易创建，等待买家付款
+	TradeClosed       TradeStatus = "TRADE_CLOSED"   // 未付款交易超时关闭，或支付完成后全额退款
+	TradeSuccess      TradeStatus = "TRADE_SUCCESS"  // 交易支付成功
+	TradeFinished     TradeStatus = "TRADE_FINISHED" // 交易结束，不可退款
+)
+
+// IsPaid 交易是否已支付
+func (s TradeStatus) IsPaid() bool {
+	return s == TradeSuccess || s == TradeFinished
+}
+
+// ParseTime 解析支付宝时间(东八区)，空字符串返回零值
+func ParseTime(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(TimeLayout, s, cstZone)
+}
+
+// Time 支付宝时间(格式：2006-01-02 15:04:05)，用于JSON解析
+type Time struct {
+	time.Time
+}
+
+// MarshalJSON 以支付宝时间格式输出，零值输出空字符串
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte(`""`), nil
+	}
+	return []byte(`"` + t.In(cstZone).Format(TimeLayout) + `"`), nil
+}
+
+// UnmarshalJSON 解析支付宝时间
+func (t *Time) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" {
+		return nil
+	}
+
+	v, err := ParseTime(s)
+	if err != nil {
+		return err
+	}
+	t.Time = v
+	return nil
+}
+
+// TradeNotify 交易异步通知
+// [参考](https://opendocs.alipay.com/open/270/105902)
+type TradeNotify struct {
+	NotifyTime        time.Time
+	NotifyType        string
+	NotifyID          string
+	AppID             string
+	AuthAppID         string
+	Charset           string
+	Version           string
+	TradeNo           string
+	OutTradeNo        string
+	OutBizNo          string
+	BuyerID           string
+	BuyerOpenID       string
+	BuyerLogonID      string
+	SellerID          string
+	SellerEmail       string
+	TradeStatus       TradeStatus
+	TotalAmount       Decimal
+	ReceiptAmount     Decimal
+	InvoiceAmount     Decimal
+	BuyerPayAmount    Decimal
+	PointAmount       Decimal
+	RefundFee         Decimal
+	Subject           string
+	Body              string
+	GmtCreate         time.Time
+	GmtPayment        time.Time
+	GmtRefund         time.Time
+	GmtClose          time.Time
+	FundBillList      string
+	VoucherDetailList string
+	PassbackParams    string
+	Raw               value.V // 原始通知参数(不含 sign 和 sign_type)
+}
+
+// ParseTradeNotify 将已验签的通知参数解析为交易通知
+func ParseTradeNotify(v value.V) (*TradeNotify, error) {
+	n := &TradeNotify{
+		NotifyType:        v.Get("notify_type"),
+		NotifyID:          v.Get("notify_id"),
+		AppID:             v.Get("app_id"),
+		AuthAppID:         v.Get("auth_app_id"),
+		Charset:           v.Get("charset"),
+		Version:           v.Get("version"),
+		TradeNo:           v.Get("trade_no"),
+		OutTradeNo:        v.Get("out_trade_no"),
+		OutBizNo:          v.Get("out_biz_no"),
+		BuyerID:           v.Get("buyer_id"),
+		BuyerOpenID:       v.Get("buyer_open_id"),
+		BuyerLogonID:      v.Get("buyer_logon_id"),
+		SellerID:          v.Get("seller_id"),
+		SellerEmail:       v.Get("seller_email"),
+		TradeStatus:       TradeStatus(v.Get("trade_status")),
+		Subject:           v.Get("subject"),
+		Body:              v.Get("body"),
+		FundBillList:      v.Get("fund_bill_list"),
+		VoucherDetailList: v.Get("voucher_detail_list"),
+		PassbackParams:    v.Get("passback_params"),
+		Raw:               v,
+	}
+
+	amounts := map[string]*Decimal{
+		"total_amount":     &n.TotalAmount,
+		"receipt_amount":   &n.ReceiptAmount,
+		"invoice_amount":   &n.InvoiceAmount,
+		"buyer_pay_amount": &n.BuyerPayAmount,
+		"point_amount":     &n.PointAmount,
+		"refund_fee":       &n.RefundFee,
+	}
+	for key, ptr := range amounts {
+		s := v.Get(key)
+		if len(s) == 0 {
+			continue
+		}
+		d, err := NewDecimal(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		*ptr = d
+	}
+
+	times := map[string]*time.Time{
+		"notify_time": &n.NotifyTime,
+		"gmt_create":  &n.GmtCreate,
+		"gmt_payment": &n.GmtPayment,
+		"gmt_refund":  &n.GmtRefund,
+		"gmt_close":   &n.GmtClose,
+	}
+	for key, ptr := range times {
+		t, err := ParseTime(v.Get(key))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		*ptr = t
+	}
+
+	return n, nil
+}
+
+type notifyConfig struct {
+	maxAge  time.Duration
+	checkID func(ctx context.Context, notifyID string) error
+}
+
+// NotifyOption 异步通知校验选项
+type NotifyOption func(cfg *notifyConfig)
+
+// WithNotifyMaxAge 设置通知时间(notify_time)的最大时效，默认：25小时(支付宝在25小时内重试通知)
+func WithNotifyMaxAge(d time.Duration) NotifyOption {
+	return func(cfg *notifyConfig) {
+		cfg.maxAge = d
+	}
+}
+
+// WithNotifyIDCheck 设置通知ID(notify_id)校验，如：去重，返回错误则校验失败
+func WithNotifyIDCheck(fn func(ctx context.Context, notifyID string) error) NotifyOption {
+	return func(cfg *notifyConfig) {
+		cfg.checkID = fn
+	}
+}
+
+// parseNotify 解析并校验异步通知请求：签名、app_id、notify_time 时效及 notify_id
+func parseNotify(r *http.Request, appid string, verify func(form url.Values) (value.V, error), options ...NotifyOption) (value.V, error) {
+	cfg := &notifyConfig{maxAge: 25 * time.Hour}
+	for _, f := range options {
+		f(cfg)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	v, err := verify(r.PostForm)
+	if err != nil {
+		return nil, fmt.Errorf("notify sign verified error: %w", err)
+	}
+
+	if id := v.Get("app_id"); id != appid {
+		return nil, fmt.Errorf("notify app_id mismatch (expected = %s, actual = %s)", appid, id)
+	}
+
+	notifyTime, err := notifyTimeOf(v)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.maxAge > 0 && (notifyTime.IsZero() || time.Since(notifyTime) > cfg.maxAge) {
+		return nil, fmt.Errorf("notify expired (notify_time = %s)", notifyTime.In(cstZone).Format(TimeLayout))
+	}
+
+	notifyID := v.Get("notify_id")
+	if len(notifyID) == 0 {
+		return nil, errors.New("notify_id is empty")
+	}
+	if cfg.checkID != nil {
+		if err = cfg.checkID(r.Context(), notifyID); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// notifyTimeOf 返回通知时间，消息服务(msg_method)通知使用 utc_timestamp(毫秒)
+func notifyTimeOf(v value.V) (time.Time, error) {
+	if ts := v.Get("utc_timestamp"); len(v.Get("notify_time")) == 0 && len(ts) != 0 {
+		ms, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("utc_timestamp: %w", err)
+		}
+		return time.UnixMilli(ms), nil
+	}
+
+	t, err := ParseTime(v.Get("notify_time"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("notify_time: %w", err)
+	}
+	return t, nil
+}
+
+// verifyNotify 验证异步通知签名(除 sign 和 sign_type 外的参数按字典序拼接)
+func verifyNotify(form url.Values, verify func(signType SignType, sn string, data, sign []byte) error) (value.V, error) {
+	sign, err := base64.StdEncoding.DecodeString(form.Get("sign"))
+	if err != nil {
+		return nil, err
+	}
+
+	v := value.V{}
+	for key, vals := range form {
+		if key == "sign_type" || key == "sign" || len(vals) == 0 {
+			continue
+		}
+		v.Set(key, vals[0])
+	}
+	str := v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore))
+
+	if err = verify(SignType(form.Get("sign_type")), form.Get("alipay_cert_sn"), []byte(str), sign); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// tradeNotifyHandler 交易异步通知处理，处理成功应答「success」，否则应答「fail」(支付宝会重试)
+func tradeNotifyHandler(parse func(r *http.Request) (value.V, error), fn func(ctx context.Context, n *TradeNotify) error, logger func(ctx context.Context, err error, data map[string]string)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		log := lib.NewReqLog(r.Method, r.URL.String())
+		defer log.Do(ctx, logger)
+
+		reply := func(err error) {
+			w.Header().Set(lib.HeaderContentType, lib.ContentText)
+			if err != nil {
+				log.SetError(err)
+				_, _ = w.Write([]byte(NotifyFail))
+				return
+			}
+			_, _ = w.Write([]byte(NotifySuccess))
+		}
+
+		v, err := parse(r)
+		if err != nil {
+			reply(err)
+			return
+		}
+		log.SetReqBody(v.Encode("=", "&"))
+
+		n, err := ParseTradeNotify(v)
+		if err != nil {
+			reply(err)
+			return
+		}
+		reply(fn(ctx, n))
+	})
+}
+
+// ParseNotify 解析并校验异步通知请求(验签、app_id、notify_time 时效及 notify_id)
+func (c *Client) ParseNotify(r *http.Request, options ...NotifyOption) (value.V, error) {
+	return parseNotify(r, c.appid, c.VerifyNotify, options...)
+}
+
+// ParseTradeNotify 解析并校验交易异步通知请求
+func (c *Client) ParseTradeNotify(r *http.Request, options ...NotifyOption) (*TradeNotify, error) {
+	v, err := c.ParseNotify(r, options...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTradeNotify(v)
+}
+
+// TradeNotifyHandler 交易异步通知 http.Handler，fn 返回nil时应答「success」，否则应答「fail」
+func (c *Client) TradeNotifyHandler(fn func(ctx context.Context, n *TradeNotify) error, options ...NotifyOption) http.Handler {
+	return tradeNotifyHandler(func(r *http.Request) (value.V, error) {
+		return c.ParseNotify(r, options...)
+	}, fn, c.logger)
+}
+
+// VerifyNotify 验证回调通知表单数据
+func (c *ClientV3) VerifyNotify(form url.Values) (value.V, error) {
+	return verifyNotify(form, c.verify)
+}
+
+// ParseNotify 解析并校验异步通知请求(验签、app_id、notify_time 时效及 notify_id)
+func (c *ClientV3) ParseNotify(r *http.Request, options ...NotifyOption) (value.V, error) {
+	return parseNotify(r, c.appid, c.VerifyNotify, options...)
+}
+
+// ParseTradeNotify 解析并校验交易异步通知请求
+func (c *ClientV3) ParseTradeNotify(r *http.Request, options ...NotifyOption) (*TradeNotify, error) {
+	v, err := c.ParseNotify(r, options...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTradeNotify(v)
+}
+
+// TradeNotifyHandler 交易异步通知 http.Handler，fn 返回nil时应答「success」，否则应答「fail」
+func (c *ClientV3) TradeNotifyHandler(fn func(ctx context.Context, n *TradeNotify) error, options ...NotifyOption) http.Handler {
+	return tradeNotifyHandler(func(r *http.Request) (value.V, error) {
+		return c.ParseNotify(r, options...)
+	}, fn, c.logger)
+}
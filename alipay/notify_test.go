@@ -0,0 +1,152 @@
+package alipay
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/value"
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+)
+
+func genTestKeyPair(t *testing.T) (*rsa.PrivateKey, *xcrypto.PublicKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+
+	pubKey, err := xcrypto.NewPublicKeyFromPemBlock(xcrypto.RSA_PKCS8, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	assert.Nil(t, err)
+
+	return key, pubKey
+}
+
+func signTestForm(t *testing.T, key *rsa.PrivateKey, form url.Values) {
+	v := value.V{}
+	for k := range form {
+		v.Set(k, form.Get(k))
+	}
+
+	h := sha256.Sum256([]byte(v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore))))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	assert.Nil(t, err)
+
+	form.Set("sign_type", "RSA2")
+	form.Set("sign", base64.StdEncoding.EncodeToString(sign))
+}
+
+func newNotifyRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/alipay/notify", strings.NewReader(form.Encode()))
+	r.Header.Set(lib.HeaderContentType, lib.ContentForm)
+	return r
+}
+
+func TestTradeNotify(t *testing.T) {
+	prvKey, pubKey := genTestKeyPair(t)
+
+	form := url.Values{}
+	form.Set("notify_time", time.Now().In(cstZone).Format(TimeLayout))
+	form.Set("notify_type", "trade_status_sync")
+	form.Set("notify_id", "ac05099524730693a8b330c5ecf72da9786")
+	form.Set("app_id", "2014072300007148")
+	form.Set("charset", "utf-8")
+	form.Set("version", "1.0")
+	form.Set("trade_no", "2013112011001004330000121536")
+	form.Set("out_trade_no", "6823789339978248")
+	form.Set("trade_status", "TRADE_SUCCESS")
+	form.Set("total_amount", "20.10")
+	form.Set("receipt_amount", "15")
+	form.Set("gmt_create", "2015-04-27 15:45:57")
+	form.Set("gmt_payment", "2015-04-27 15:45:57")
+	signTestForm(t, prvKey, form)
+
+	cli := NewClient("2014072300007148", "", WithPublicKey(pubKey))
+
+	var notify *TradeNotify
+
+	handler := cli.TradeNotifyHandler(func(ctx context.Context, n *TradeNotify) error {
+		notify = n
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newNotifyRequest(form))
+	assert.Equal(t, NotifySuccess, w.Body.String())
+	assert.True(t, notify.TradeStatus.IsPaid())
+	assert.Equal(t, Decimal("20.10"), notify.TotalAmount)
+	assert.Equal(t, time.Date(2015, 4, 27, 7, 45, 57, 0, time.UTC), notify.GmtPayment.UTC())
+
+	cents, err := notify.ReceiptAmount.Cents()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1500), cents)
+
+	// app_id 不匹配
+	_, err = NewClientV3("2021000000000000", "", WithV3PublicKey(pubKey)).ParseTradeNotify(newNotifyRequest(form))
+	assert.NotNil(t, err)
+
+	// notify_id 重复
+	_, err = cli.ParseNotify(newNotifyRequest(form), WithNotifyIDCheck(func(ctx context.Context, notifyID string) error {
+		return errors.New("duplicate notify")
+	}))
+	assert.NotNil(t, err)
+
+	// 通知过期
+	form.Set("notify_time", time.Now().Add(-26*time.Hour).In(cstZone).Format(TimeLayout))
+	form.Del("sign")
+	form.Del("sign_type")
+	signTestForm(t, prvKey, form)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, newNotifyRequest(form))
+	assert.Equal(t, NotifyFail, w.Body.String())
+
+	// 签名错误
+	form.Set("total_amount", "0.01")
+
+	_, err = cli.ParseNotify(newNotifyRequest(form), WithNotifyMaxAge(0))
+	assert.NotNil(t, err)
+}
+
+func TestDecimal(t *testing.T) {
+	d, err := NewDecimal("88.8")
+	assert.Nil(t, err)
+
+	cents, err := d.Cents()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8880), cents)
+
+	_, err = Decimal("0.001").Cents()
+	assert.NotNil(t, err)
+
+	_, err = NewDecimal("1e3")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, Decimal("-0.05"), NewDecimalFromCents(-5))
+	assert.True(t, Decimal("0.00").IsZero())
+	assert.False(t, Decimal("0.01").IsZero())
+
+	var v struct {
+		A Decimal `json:"a"`
+		B Decimal `json:"b"`
+	}
+	assert.Nil(t, json.Unmarshal([]byte(`{"a":"1.10","b":2.5}`), &v))
+	assert.Equal(t, Decimal("1.10"), v.A)
+	assert.Equal(t, Decimal("2.5"), v.B)
+}
@@ -0,0 +1,176 @@
+package alipay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// OAuthScope 用户授权范围
+type OAuthScope string
+
+const (
+	ScopeAuthBase OAuthScope = "auth_base" // 静默授权，仅获取用户ID(网页及小程序)
+	ScopeAuthUser OAuthScope = "auth_user" // 主动授权，获取用户基础信息(网页及小程序)
+)
+
+// OAuthURL 生成网页授权链接，redirectURI 需与应用配置的授权回调地址一致
+// [参考](https://opendocs.alipay.com/open/284/web)
+func (c *Client) OAuthURL(redirectURI, state string, scopes ...OAuthScope) string {
+	if len(scopes) == 0 {
+		scopes = []OAuthScope{ScopeAuthBase}
+	}
+
+	scope := make([]string, 0, len(scopes))
+	for _, v := range scopes {
+		scope = append(scope, string(v))
+	}
+
+	query := url.Values{}
+	query.Set("app_id", c.appid)
+	query.Set("scope", strings.Join(scope, ","))
+	query.Set("redirect_uri", redirectURI)
+	if len(state) != 0 {
+		query.Set("state", state)
+	}
+
+	return c.oauth + "/oauth2/publicAppAuthorize.htm?" + query.Encode()
+}
+
+// OAuthAppURL 生成在支付宝客户端内打开的授权链接(alipays://)，用于从外部唤起支付宝授权
+func (c *Client) OAuthAppURL(redirectURI, state string, scopes ...OAuthScope) string {
+	query := url.Values{}
+	query.Set("appId", "20000067")
+	query.Set("url", c.OAuthURL(redirectURI, state, scopes...))
+
+	return "alipays://platformapi/startapp?" + query.Encode()
+}
+
+// OAuthToken 用户授权令牌
+type OAuthToken struct {
+	UserID       string    `json:"user_id"`
+	OpenID       string    `json:"open_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    int64     `json:"expires_in"`    // 令牌有效期(秒)
+	ReExpiresIn  int64     `json:"re_expires_in"` // 刷新令牌有效期(秒)
+	AuthStart    time.Time `json:"auth_start"`    // 授权开始时间
+	ExpiresAt    time.Time `json:"expires_at"`
+	ReExpiresAt  time.Time `json:"re_expires_at"`
+}
+
+// IsExpired 令牌是否已过期
+func (t *OAuthToken) IsExpired() bool {
+	return !time.Now().Before(t.ExpiresAt)
+}
+
+// CanRefresh 刷新令牌是否仍有效
+func (t *OAuthToken) CanRefresh() bool {
+	return len(t.RefreshToken) != 0 && time.Now().Before(t.ReExpiresAt)
+}
+
+func (c *Client) oauthToken(ctx context.Context, option ActionOption) (*OAuthToken, error) {
+	now := time.Now()
+
+	ret, err := c.Do(ctx, "alipay.system.oauth.token", option)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &OAuthToken{
+		UserID:       ret.Get("user_id").String(),
+		OpenID:       ret.Get("open_id").String(),
+		AccessToken:  ret.Get("access_token").String(),
+		RefreshToken: ret.Get("refresh_token").String(),
+		ExpiresIn:    ret.Get("expires_in").Int(),
+		ReExpiresIn:  ret.Get("re_expires_in").Int(),
+	}
+	if len(token.AccessToken) == 0 {
+		return nil, errors.New("access_token is empty")
+	}
+
+	// 以授权开始时间计算过期时间
+	start := now
+	if t, err := ParseTime(ret.Get("auth_start").String()); err == nil && !t.IsZero() {
+		token.AuthStart = t
+		start = t
+	}
+	token.ExpiresAt = start.Add(time.Duration(token.ExpiresIn) * time.Second)
+	token.ReExpiresAt = start.Add(time.Duration(token.ReExpiresIn) * time.Second)
+
+	return token, nil
+}
+
+// ExchangeOAuthCode 用授权码换取令牌
+// [参考](https://opendocs.alipay.com/open/02xtla)
+func (c *Client) ExchangeOAuthCode(ctx context.Context, code string) (*OAuthToken, error) {
+	return c.oauthToken(ctx, WithOAuthCode(code))
+}
+
+// RefreshOAuthToken 用刷新令牌换取新的令牌
+// [参考](https://opendocs.alipay.com/open/02xtla)
+func (c *Client) RefreshOAuthToken(ctx context.Context, refreshToken string) (*OAuthToken, error) {
+	return c.oauthToken(ctx, WithRefreshToken(refreshToken))
+}
+
+// UserInfo 支付宝会员基础信息
+type UserInfo struct {
+	UserID      string `json:"user_id"`
+	OpenID      string `json:"open_id"`
+	Avatar      string `json:"avatar"`
+	NickName    string `json:"nick_name"`
+	Gender      string `json:"gender"` // F：女性；M：男性
+	Province    string `json:"province"`
+	City        string `json:"city"`
+	CountryCode string `json:"country_code"`
+}
+
+// GetUserInfo 获取会员信息(需 auth_user 授权)
+// [参考](https://opendocs.alipay.com/open/02xtlb)
+func (c *Client) GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	ret, err := c.Do(ctx, "alipay.user.info.share", WithAuthToken(accessToken))
+	if err != nil {
+		return nil, err
+	}
+
+	info := new(UserInfo)
+	if err = json.Unmarshal([]byte(ret.Raw), info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// PhoneNumber 小程序用户手机号
+type PhoneNumber struct {
+	Mobile string `json:"mobile"`
+}
+
+// DecodePhoneNumber 解析小程序 my.getPhoneNumber 返回的加密手机号，
+// response 为前端返回的完整JSON字符串，如：{"response":"...","sign":"...","sign_type":"RSA2",...}
+// [参考](https://opendocs.alipay.com/mini/api/getphonenumber)
+func (c *Client) DecodePhoneNumber(response string) (*PhoneNumber, error) {
+	ret := gjson.Parse(response)
+
+	signType := SignType(ret.Get("sign_type").String())
+
+	b, err := c.decodeEncryptData(signType, ret.Get("response").String(), ret.Get("sign").String())
+	if err != nil {
+		return nil, err
+	}
+
+	data := gjson.ParseBytes(b)
+	if code := data.Get("code").String(); code != CodeOK {
+		return nil, newAPIError(data)
+	}
+
+	phone := new(PhoneNumber)
+	if err = json.Unmarshal(b, phone); err != nil {
+		return nil, err
+	}
+	return phone, nil
+}
@@ -0,0 +1,82 @@
+package alipay
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth(t *testing.T) {
+	cli, closeFn := newTestFormClient(t, func(form url.Values) string {
+		switch form.Get("method") {
+		case "alipay.system.oauth.token":
+			if form.Get("grant_type") == "authorization_code" {
+				assert.Equal(t, "4b203fe6c11548bcabd8da5bb087a83b", form.Get("code"))
+			} else {
+				assert.Equal(t, "201208134b203fe6c11548bcabd8da5bb087a83b", form.Get("refresh_token"))
+			}
+			return `{"user_id":"2088102150477652","open_id":"074a1CcTG1LelxKe4xQC0zgNdId0nxi95b5lsNpazWYoCo5","access_token":"20120823ac6ffaa4d2d84e7384bf983531473993","expires_in":"3600","refresh_token":"201208134b203fe6c11548bcabd8da5bb087a83b","re_expires_in":"3600","auth_start":"2010-11-11 11:11:11"}`
+		case "alipay.user.info.share":
+			assert.Equal(t, "20120823ac6ffaa4d2d84e7384bf983531473993", form.Get("auth_token"))
+			return `{"code":"10000","msg":"Success","user_id":"2088102104794936","avatar":"http://tfsimg.alipay.com/images/partner/T1uIxXXbpXXXXXXXX","province":"安徽省","city":"安庆","nick_name":"支付宝小二","gender":"F"}`
+		}
+		return `{"code":"40004","msg":"Business Failed"}`
+	})
+	defer closeFn()
+
+	ctx := context.Background()
+
+	token, err := cli.ExchangeOAuthCode(ctx, "4b203fe6c11548bcabd8da5bb087a83b")
+	assert.Nil(t, err)
+	assert.Equal(t, "2010-11-11 12:11:11", token.ExpiresAt.In(cstZone).Format(TimeLayout))
+	assert.True(t, token.IsExpired())
+	assert.False(t, token.CanRefresh())
+
+	_, err = cli.RefreshOAuthToken(ctx, token.RefreshToken)
+	assert.Nil(t, err)
+
+	info, err := cli.GetUserInfo(ctx, token.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "支付宝小二", info.NickName)
+	assert.Equal(t, "F", info.Gender)
+
+	u, err := url.Parse(cli.OAuthURL("https://example.com/callback?a=1", "init", ScopeAuthUser))
+	assert.Nil(t, err)
+	assert.Equal(t, "/oauth2/publicAppAuthorize.htm", u.Path)
+	assert.Equal(t, "auth_user", u.Query().Get("scope"))
+	assert.Equal(t, "https://example.com/callback?a=1", u.Query().Get("redirect_uri"))
+
+	u, err = url.Parse(cli.OAuthAppURL("https://example.com/callback", ""))
+	assert.Nil(t, err)
+	assert.Equal(t, "alipays", u.Scheme)
+	assert.Contains(t, u.Query().Get("url"), "scope=auth_base")
+}
+
+func TestDecodePhoneNumber(t *testing.T) {
+	prvKey, pubKey := genTestKeyPair(t)
+
+	cli := NewClient("2014072300007148", base64.StdEncoding.EncodeToString([]byte("1234567890123456")), WithPublicKey(pubKey))
+
+	encrypted, err := cli.Encrypt(`{"code":"10000","msg":"Success","mobile":"15900000000"}`)
+	assert.Nil(t, err)
+
+	h := sha256.Sum256([]byte(`"` + encrypted + `"`))
+	sign, err := rsa.SignPKCS1v15(rand.Reader, prvKey, crypto.SHA256, h[:])
+	assert.Nil(t, err)
+
+	phone, err := cli.DecodePhoneNumber(fmt.Sprintf(`{"response":"%s","sign":"%s","sign_type":"RSA2","encrypt_type":"AES","charset":"UTF-8"}`, encrypted, base64.StdEncoding.EncodeToString(sign)))
+	assert.Nil(t, err)
+	assert.Equal(t, "15900000000", phone.Mobile)
+
+	_, err = cli.DecodePhoneNumber(fmt.Sprintf(`{"response":"%s","sign":"%s","sign_type":"RSA2"}`, encrypted, base64.StdEncoding.EncodeToString([]byte("invalid"))))
+	assert.NotNil(t, err)
+
+}
@@ -0,0 +1,99 @@
+package alipay
+
+import (
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// PageMethod 页面跳转接口的请求方式
+type PageMethod string
+
+const (
+	PageGet  PageMethod = http.MethodGet  // 返回跳转URL
+	PagePost PageMethod = http.MethodPost // 返回自动提交的HTML表单
+)
+
+// PageExecuteWith 页面跳转接口(如：alipay.trade.page.pay、alipay.trade.wap.pay)，
+// GET 返回跳转URL，POST 返回自动提交的HTML表单(biz_content 较长时使用)
+func (c *Client) PageExecuteWith(mode PageMethod, method string, options ...ActionOption) (string, error) {
+	if mode == PagePost {
+		return c.PageExecuteForm(method, options...)
+	}
+	return c.PageExecute(method, options...)
+}
+
+// PageExecuteForm 生成自动提交(POST)的HTML表单
+func (c *Client) PageExecuteForm(method string, options ...ActionOption) (string, error) {
+	action := NewAction(method, options...)
+
+	v, err := action.signedValues(c)
+	if err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		if len(v[k]) != 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+
+	builder.WriteString(`<form name="punchout_form" method="post" action="`)
+	builder.WriteString(html.EscapeString(c.gateway + "?charset=utf-8"))
+	builder.WriteString("\">\n")
+	for _, k := range keys {
+		builder.WriteString(`<input type="hidden" name="`)
+		builder.WriteString(html.EscapeString(k))
+		builder.WriteString(`" value="`)
+		builder.WriteString(html.EscapeString(v[k]))
+		builder.WriteString("\">\n")
+	}
+	builder.WriteString(`<input type="submit" value="立即支付" style="display:none">`)
+	builder.WriteString("\n</form>\n")
+	builder.WriteString("<script>document.forms['punchout_form'].submit();</script>")
+
+	return builder.String(), nil
+}
+
+// WritePageForm 将自动提交的HTML表单写入 http.ResponseWriter
+func (c *Client) WritePageForm(w http.ResponseWriter, method string, options ...ActionOption) error {
+	form, err := c.PageExecuteForm(method, options...)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set(lib.HeaderContentType, "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write([]byte(form))
+	return err
+}
+
+// PageFormHandler 页面支付 http.Handler，build 根据请求生成 Action 选项(如：WithBizContent、WithReturnURL)，
+// 成功时输出自动提交的HTML表单，失败时返回 400/500(错误详情仅记录日志，不返回给用户)
+func (c *Client) PageFormHandler(method string, build func(r *http.Request) ([]ActionOption, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fail := func(err error, code int) {
+			log := lib.NewReqLog(r.Method, r.URL.String())
+			log.SetError(err)
+			log.Do(r.Context(), c.logger)
+
+			http.Error(w, http.StatusText(code), code)
+		}
+
+		options, err := build(r)
+		if err != nil {
+			fail(err, http.StatusBadRequest)
+			return
+		}
+		if err = c.WritePageForm(w, method, options...); err != nil {
+			fail(err, http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package alipay
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+func TestPageExecuteForm(t *testing.T) {
+	cli, closeFn := newTestClient(t, nil)
+	defer closeFn()
+
+	options := []ActionOption{
+		WithReturnURL("https://example.com/return?a=1&b=2"),
+		WithBizContent(lib.X{
+			"out_trade_no": "70501111111S001111119",
+			"total_amount": "9.00",
+			"subject":      `<script>alert("x")</script>`,
+			"product_code": "FAST_INSTANT_TRADE_PAY",
+		}),
+	}
+
+	form, err := cli.PageExecuteWith(PagePost, "alipay.trade.page.pay", options...)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(form, `<form name="punchout_form" method="post" action="`+cli.gateway+`?charset=utf-8">`))
+	assert.Contains(t, form, `<input type="hidden" name="return_url" value="https://example.com/return?a=1&amp;b=2">`)
+	assert.Contains(t, form, `&#34;subject&#34;:&#34;\u003cscript\u003ealert(\&#34;x\&#34;)`)
+	assert.NotContains(t, form, `<script>alert`)
+
+	link, err := cli.PageExecuteWith(PageGet, "alipay.trade.page.pay", options...)
+	assert.Nil(t, err)
+
+	u, err := url.Parse(link)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/return?a=1&b=2", u.Query().Get("return_url"))
+	assert.NotEmpty(t, u.Query().Get("sign"))
+
+	handler := cli.PageFormHandler("alipay.trade.wap.pay", func(r *http.Request) ([]ActionOption, error) {
+		return []ActionOption{WithBizContent(lib.X{"out_trade_no": r.URL.Query().Get("order")})}, nil
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pay?order=70501111111S001111119", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get(lib.HeaderContentType))
+	assert.Contains(t, w.Body.String(), "70501111111S001111119")
+	assert.Contains(t, w.Body.String(), "document.forms['punchout_form'].submit();")
+}
+
+func TestPageFormHandlerError(t *testing.T) {
+	var logged []error
+
+	cli := NewClient("2021000000000000", "", WithLogger(func(ctx context.Context, err error, data map[string]string) {
+		logged = append(logged, err)
+	}))
+
+	handler := cli.PageFormHandler("alipay.trade.wap.pay", func(r *http.Request) ([]ActionOption, error) {
+		if len(r.URL.Query().Get("order")) == 0 {
+			return nil, errors.New("missing order (internal detail)")
+		}
+		return []ActionOption{WithBizContent(lib.X{"out_trade_no": r.URL.Query().Get("order")})}, nil
+	})
+
+	// 参数错误
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pay", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusText(http.StatusBadRequest), strings.TrimSpace(w.Body.String()))
+
+	// 签名错误(未设置私钥)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pay?order=70501111111S001111119", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError), strings.TrimSpace(w.Body.String()))
+
+	assert.Equal(t, 2, len(logged))
+	assert.EqualError(t, logged[0], "missing order (internal detail)")
+	assert.NotNil(t, logged[1])
+}
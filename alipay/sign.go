@@ -0,0 +1,28 @@
+package alipay
+
+import "crypto"
+
+// SignType 签名算法
+type SignType string
+
+const (
+	SignRSA  SignType = "RSA"  // SHA1WithRSA
+	SignRSA2 SignType = "RSA2" // SHA256WithRSA (默认)
+	SignSM2  SignType = "SM2"  // SM2WithSM3 (国密)
+)
+
+// hash 返回RSA签名摘要算法
+func (st SignType) hash() crypto.Hash {
+	if st == SignRSA {
+		return crypto.SHA1
+	}
+	return crypto.SHA256
+}
+
+// authScheme 返回v3接口 Authorization 签名方案
+func (st SignType) authScheme() string {
+	if st == SignSM2 {
+		return "ALIPAY-SM2withSM3"
+	}
+	return "ALIPAY-SHA256withRSA"
+}
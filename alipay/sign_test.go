@@ -0,0 +1,123 @@
+package alipay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tjfoc/gmsm/sm2"
+	gmx509 "github.com/tjfoc/gmsm/x509"
+
+	"github.com/shenghui0779/sdk-go/lib/value"
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+)
+
+func genTestSM2KeyPair(t *testing.T) (*xcrypto.SM2PrivateKey, *xcrypto.SM2PublicKey) {
+	key, err := sm2.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	prvPem, err := gmx509.WritePrivateKeyToPem(key, nil)
+	assert.Nil(t, err)
+
+	prvKey, err := xcrypto.NewSM2PrivateKeyFromPemBlock(prvPem)
+	assert.Nil(t, err)
+
+	return prvKey, prvKey.PublicKey()
+}
+
+func TestSM2Client(t *testing.T) {
+	appKey, appPubKey := genTestSM2KeyPair(t)
+	alipayKey, alipayPubKey := genTestSM2KeyPair(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, r.ParseForm())
+		assert.Equal(t, "SM2", r.PostForm.Get("sign_type"))
+
+		// 验证请求签名
+		v := value.V{}
+		for k := range r.PostForm {
+			if k != "sign" {
+				v.Set(k, r.PostForm.Get(k))
+			}
+		}
+		sign, err := base64.StdEncoding.DecodeString(r.PostForm.Get("sign"))
+		assert.Nil(t, err)
+		assert.Nil(t, appPubKey.Verify([]byte(v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore))), sign))
+
+		resp := `{"code":"10000","msg":"Success","out_trade_no":"6823789339978248","trade_status":"TRADE_SUCCESS","total_amount":"88.88"}`
+		sign, err = alipayKey.Sign([]byte(resp))
+		assert.Nil(t, err)
+
+		_, _ = fmt.Fprintf(w, `{"alipay_trade_query_response":%s,"sign":"%s"}`, resp, base64.StdEncoding.EncodeToString(sign))
+	}))
+	defer srv.Close()
+
+	cli := NewClient("2014072300007148", "", WithSM2(appKey, alipayPubKey))
+	cli.gateway = srv.URL
+
+	ret, err := cli.TradeQuery(context.Background(), &TradeQuery{OutTradeNo: "6823789339978248"})
+	assert.Nil(t, err)
+	assert.Equal(t, TradeSuccess, ret.TradeStatus)
+
+	// 异步通知
+	form := url.Values{}
+	form.Set("notify_id", "ac05099524730693a8b330c5ecf72da9786")
+	form.Set("app_id", "2014072300007148")
+	form.Set("out_trade_no", "6823789339978248")
+
+	v := value.V{}
+	for k := range form {
+		v.Set(k, form.Get(k))
+	}
+	sign, err := alipayKey.Sign([]byte(v.Encode("=", "&", value.WithEmptyMode(value.EmptyIgnore))))
+	assert.Nil(t, err)
+
+	form.Set("sign_type", "SM2")
+	form.Set("sign", base64.StdEncoding.EncodeToString(sign))
+
+	_, err = cli.VerifyNotify(form)
+	assert.Nil(t, err)
+
+	form.Set("out_trade_no", "6823789339978249")
+	_, err = cli.VerifyNotify(form)
+	assert.NotNil(t, err)
+}
+
+func TestSM2ClientV3(t *testing.T) {
+	appKey, appPubKey := genTestSM2KeyPair(t)
+	alipayKey, alipayPubKey := genTestSM2KeyPair(t)
+
+	cli := NewClientV3("2014072300007148", "", WithV3SM2(appKey, alipayPubKey))
+
+	auth, err := cli.Authorization(http.MethodGet, "/v3/alipay/trade/query", nil, nil, http.Header{})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(auth, "ALIPAY-SM2withSM3 app_id=2014072300007148,"))
+
+	// 验证 Authorization 签名
+	idx := strings.LastIndex(auth, ",sign=")
+	authStr := strings.TrimPrefix(auth[:idx], "ALIPAY-SM2withSM3 ")
+	sign, err := base64.StdEncoding.DecodeString(auth[idx+6:])
+	assert.Nil(t, err)
+	assert.Nil(t, appPubKey.Verify([]byte(authStr+"\nGET\n/v3/alipay/trade/query\n"), sign))
+
+	// 验证响应签名
+	body := []byte(`{"out_trade_no":"6823789339978248","trade_status":"TRADE_SUCCESS"}`)
+	sign, err = alipayKey.Sign([]byte("1615255810000\nNONCE\n" + string(body) + "\n"))
+	assert.Nil(t, err)
+
+	header := http.Header{}
+	header.Set(HeaderTimestamp, "1615255810000")
+	header.Set(HeaderNonce, "NONCE")
+	header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(sign))
+	assert.Nil(t, cli.Verify(header, body))
+
+	header.Set(HeaderNonce, "NONCE_X")
+	assert.NotNil(t, cli.Verify(header, body))
+}
@@ -0,0 +1,407 @@
+package alipay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+var (
+	// ErrTradeClosed 交易已关闭
+	ErrTradeClosed = errors.New("trade closed")
+	// ErrPayTimeout 条码支付超时(交易已撤销)
+	ErrPayTimeout = errors.New("barcode pay timeout, trade cancelled")
+)
+
+// GoodsDetail 商品明细
+type GoodsDetail struct {
+	GoodsID        string  `json:"goods_id"`
+	GoodsName      string  `json:"goods_name"`
+	Quantity       int     `json:"quantity"`
+	Price          Decimal `json:"price"`
+	GoodsCategory  string  `json:"goods_category,omitempty"`
+	CategoriesTree string  `json:"categories_tree,omitempty"`
+	ShowURL        string  `json:"show_url,omitempty"`
+}
+
+// FundBill 交易支付使用的资金渠道
+type FundBill struct {
+	FundChannel string  `json:"fund_channel"`
+	Amount      Decimal `json:"amount"`
+	RealAmount  Decimal `json:"real_amount,omitempty"`
+}
+
+// TradePrecreate 统一收单线下交易预创建(扫码支付)
+type TradePrecreate struct {
+	OutTradeNo         string         `json:"out_trade_no"`
+	TotalAmount        Decimal        `json:"total_amount"`
+	Subject            string         `json:"subject"`
+	Body               string         `json:"body,omitempty"`
+	ProductCode        string         `json:"product_code,omitempty"`
+	SellerID           string         `json:"seller_id,omitempty"`
+	GoodsDetail        []*GoodsDetail `json:"goods_detail,omitempty"`
+	DiscountableAmount Decimal        `json:"discountable_amount,omitempty"`
+	StoreID            string         `json:"store_id,omitempty"`
+	OperatorID         string         `json:"operator_id,omitempty"`
+	TerminalID         string         `json:"terminal_id,omitempty"`
+	TimeoutExpress     string         `json:"timeout_express,omitempty"`
+	TimeExpire         string         `json:"time_expire,omitempty"`
+}
+
+// TradePrecreateResult 预创建结果
+type TradePrecreateResult struct {
+	OutTradeNo string `json:"out_trade_no"`
+	QRCode     string `json:"qr_code"`
+}
+
+// TradePay 统一收单交易支付(付款码支付)
+type TradePay struct {
+	OutTradeNo         string         `json:"out_trade_no"`
+	TotalAmount        Decimal        `json:"total_amount"`
+	Subject            string         `json:"subject"`
+	AuthCode           string         `json:"auth_code"`
+	Scene              string         `json:"scene"` // 条码支付：bar_code
+	ProductCode        string         `json:"product_code,omitempty"`
+	Body               string         `json:"body,omitempty"`
+	SellerID           string         `json:"seller_id,omitempty"`
+	GoodsDetail        []*GoodsDetail `json:"goods_detail,omitempty"`
+	DiscountableAmount Decimal        `json:"discountable_amount,omitempty"`
+	StoreID            string         `json:"store_id,omitempty"`
+	OperatorID         string         `json:"operator_id,omitempty"`
+	TerminalID         string         `json:"terminal_id,omitempty"`
+	TimeoutExpress     string         `json:"timeout_express,omitempty"`
+}
+
+// TradePayResult 支付结果
+type TradePayResult struct {
+	TradeNo        string      `json:"trade_no"`
+	OutTradeNo     string      `json:"out_trade_no"`
+	BuyerLogonID   string      `json:"buyer_logon_id"`
+	TotalAmount    Decimal     `json:"total_amount"`
+	ReceiptAmount  Decimal     `json:"receipt_amount"`
+	BuyerPayAmount Decimal     `json:"buyer_pay_amount"`
+	PointAmount    Decimal     `json:"point_amount"`
+	InvoiceAmount  Decimal     `json:"invoice_amount"`
+	GmtPayment     Time        `json:"gmt_payment"`
+	FundBillList   []*FundBill `json:"fund_bill_list"`
+	StoreName      string      `json:"store_name"`
+	BuyerUserID    string      `json:"buyer_user_id"`
+	BuyerOpenID    string      `json:"buyer_open_id"`
+}
+
+// TradeQuery 统一收单交易查询(trade_no 和 out_trade_no 二选一)
+type TradeQuery struct {
+	OutTradeNo   string   `json:"out_trade_no,omitempty"`
+	TradeNo      string   `json:"trade_no,omitempty"`
+	QueryOptions []string `json:"query_options,omitempty"`
+}
+
+// TradeQueryResult 交易查询结果
+type TradeQueryResult struct {
+	TradeNo        string      `json:"trade_no"`
+	OutTradeNo     string      `json:"out_trade_no"`
+	BuyerLogonID   string      `json:"buyer_logon_id"`
+	TradeStatus    TradeStatus `json:"trade_status"`
+	TotalAmount    Decimal     `json:"total_amount"`
+	ReceiptAmount  Decimal     `json:"receipt_amount"`
+	BuyerPayAmount Decimal     `json:"buyer_pay_amount"`
+	PointAmount    Decimal     `json:"point_amount"`
+	InvoiceAmount  Decimal     `json:"invoice_amount"`
+	SendPayDate    Time        `json:"send_pay_date"`
+	StoreID        string      `json:"store_id"`
+	TerminalID     string      `json:"terminal_id"`
+	FundBillList   []*FundBill `json:"fund_bill_list"`
+	StoreName      string      `json:"store_name"`
+	BuyerUserID    string      `json:"buyer_user_id"`
+	BuyerOpenID    string      `json:"buyer_open_id"`
+	Subject        string      `json:"subject"`
+	Body           string      `json:"body"`
+}
+
+// TradeRefund 统一收单交易退款(trade_no 和 out_trade_no 二选一)
+type TradeRefund struct {
+	OutTradeNo   string  `json:"out_trade_no,omitempty"`
+	TradeNo      string  `json:"trade_no,omitempty"`
+	RefundAmount Decimal `json:"refund_amount"`
+	RefundReason string  `json:"refund_reason,omitempty"`
+	OutRequestNo string  `json:"out_request_no,omitempty"` // 部分退款必传
+	OperatorID   string  `json:"operator_id,omitempty"`
+	StoreID      string  `json:"store_id,omitempty"`
+	TerminalID   string  `json:"terminal_id,omitempty"`
+}
+
+// TradeRefundResult 退款结果
+type TradeRefundResult struct {
+	TradeNo      string  `json:"trade_no"`
+	OutTradeNo   string  `json:"out_trade_no"`
+	BuyerLogonID string  `json:"buyer_logon_id"`
+	FundChange   string  `json:"fund_change"` // 本次退款是否发生了资金变化(Y/N)
+	RefundFee    Decimal `json:"refund_fee"`
+	GmtRefundPay Time    `json:"gmt_refund_pay"`
+	StoreName    string  `json:"store_name"`
+	BuyerUserID  string  `json:"buyer_user_id"`
+	BuyerOpenID  string  `json:"buyer_open_id"`
+}
+
+// RefundQuery 统一收单交易退款查询(trade_no 和 out_trade_no 二选一)
+type RefundQuery struct {
+	OutTradeNo   string   `json:"out_trade_no,omitempty"`
+	TradeNo      string   `json:"trade_no,omitempty"`
+	OutRequestNo string   `json:"out_request_no"` // 退款请求号，未传时为 out_trade_no
+	QueryOptions []string `json:"query_options,omitempty"`
+}
+
+// RefundQueryResult 退款查询结果
+type RefundQueryResult struct {
+	TradeNo      string  `json:"trade_no"`
+	OutTradeNo   string  `json:"out_trade_no"`
+	OutRequestNo string  `json:"out_request_no"`
+	TotalAmount  Decimal `json:"total_amount"`
+	RefundAmount Decimal `json:"refund_amount"`
+	RefundStatus string  `json:"refund_status"` // REFUND_SUCCESS 退款处理成功，未返回表示退款未成功
+	RefundReason string  `json:"refund_reason"`
+	GmtRefundPay Time    `json:"gmt_refund_pay"`
+}
+
+// IsSuccess 退款是否成功
+func (r *RefundQueryResult) IsSuccess() bool {
+	return r.RefundStatus == "REFUND_SUCCESS"
+}
+
+// TradeClose 统一收单交易关闭(trade_no 和 out_trade_no 二选一)
+type TradeClose struct {
+	OutTradeNo string `json:"out_trade_no,omitempty"`
+	TradeNo    string `json:"trade_no,omitempty"`
+	OperatorID string `json:"operator_id,omitempty"`
+}
+
+// TradeCloseResult 交易关闭结果
+type TradeCloseResult struct {
+	TradeNo    string `json:"trade_no"`
+	OutTradeNo string `json:"out_trade_no"`
+}
+
+// TradeCancel 统一收单交易撤销(trade_no 和 out_trade_no 二选一)
+type TradeCancel struct {
+	OutTradeNo string `json:"out_trade_no,omitempty"`
+	TradeNo    string `json:"trade_no,omitempty"`
+}
+
+// TradeCancelResult 交易撤销结果
+type TradeCancelResult struct {
+	TradeNo    string `json:"trade_no"`
+	OutTradeNo string `json:"out_trade_no"`
+	RetryFlag  string `json:"retry_flag"` // 是否需要重试(Y/N)
+	Action     string `json:"action"`     // 本次撤销触发的交易动作：close/refund
+}
+
+// doBiz 以结构体作为「biz_content」发送请求，并将结果解析到 result
+func (c *Client) doBiz(ctx context.Context, method string, biz, result any, options ...ActionOption) error {
+	data, err := lib.ToX(biz)
+	if err != nil {
+		return err
+	}
+
+	ret, err := c.Do(ctx, method, append([]ActionOption{WithBizContent(data)}, options...)...)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(ret.Raw), result)
+}
+
+// TradePrecreate 统一收单线下交易预创建(生成二维码)
+// [参考](https://opendocs.alipay.com/open/02ekfg)
+func (c *Client) TradePrecreate(ctx context.Context, req *TradePrecreate, options ...ActionOption) (*TradePrecreateResult, error) {
+	result := new(TradePrecreateResult)
+	if err := c.doBiz(ctx, "alipay.trade.precreate", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TradePay 统一收单交易支付(付款码)，用户支付中时返回错误码「10003」，使用 BarcodePay 自动轮询
+// [参考](https://opendocs.alipay.com/open/02ekfp)
+func (c *Client) TradePay(ctx context.Context, req *TradePay, options ...ActionOption) (*TradePayResult, error) {
+	result := new(TradePayResult)
+	if err := c.doBiz(ctx, "alipay.trade.pay", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TradeQuery 统一收单交易查询
+// [参考](https://opendocs.alipay.com/open/02ekfh)
+func (c *Client) TradeQuery(ctx context.Context, req *TradeQuery, options ...ActionOption) (*TradeQueryResult, error) {
+	result := new(TradeQueryResult)
+	if err := c.doBiz(ctx, "alipay.trade.query", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TradeRefund 统一收单交易退款
+// [参考](https://opendocs.alipay.com/open/02ekfk)
+func (c *Client) TradeRefund(ctx context.Context, req *TradeRefund, options ...ActionOption) (*TradeRefundResult, error) {
+	result := new(TradeRefundResult)
+	if err := c.doBiz(ctx, "alipay.trade.refund", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RefundQuery 统一收单交易退款查询
+// [参考](https://opendocs.alipay.com/open/02ekfl)
+func (c *Client) RefundQuery(ctx context.Context, req *RefundQuery, options ...ActionOption) (*RefundQueryResult, error) {
+	result := new(RefundQueryResult)
+	if err := c.doBiz(ctx, "alipay.trade.fastpay.refund.query", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TradeClose 统一收单交易关闭(仅用于等待买家付款的交易)
+// [参考](https://opendocs.alipay.com/open/02ekfj)
+func (c *Client) TradeClose(ctx context.Context, req *TradeClose, options ...ActionOption) (*TradeCloseResult, error) {
+	result := new(TradeCloseResult)
+	if err := c.doBiz(ctx, "alipay.trade.close", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TradeCancel 统一收单交易撤销(支付失败或支付结果未知时调用)
+// [参考](https://opendocs.alipay.com/open/02ekfi)
+func (c *Client) TradeCancel(ctx context.Context, req *TradeCancel, options ...ActionOption) (*TradeCancelResult, error) {
+	result := new(TradeCancelResult)
+	if err := c.doBiz(ctx, "alipay.trade.cancel", req, result, options...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BarcodePay 条码支付：用户支付中(10003)或结果未知(20000)时，按 interval 轮询交易查询直到 timeout；
+// 超时未支付成功则撤销交易并返回 ErrPayTimeout，交易关闭返回 ErrTradeClosed；
+// ctx 取消或查询返回不可重试的错误时，同样撤销交易后返回对应错误
+func (c *Client) BarcodePay(ctx context.Context, req *TradePay, interval, timeout time.Duration, options ...ActionOption) (*TradePayResult, error) {
+	if interval <= 0 {
+		return nil, errors.New("barcode pay interval must be greater than 0")
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	result, err := c.TradePay(ctx, req, options...)
+	if err == nil {
+		return result, nil
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || (apiErr.Code != CodeWaitUserPay && apiErr.Code != CodeUnknown) {
+		return nil, err
+	}
+
+	// 撤销交易，避免调用方放弃后用户仍可完成支付
+	abort := func(cause error) error {
+		cancelCtx := ctx
+		if ctx.Err() != nil {
+			// 调用方的 ctx 已取消，使用独立的 ctx 撤销
+			var stop context.CancelFunc
+			cancelCtx, stop = context.WithTimeout(context.Background(), barcodeCancelTimeout)
+			defer stop()
+		}
+		if err := c.cancelTrade(cancelCtx, &TradeCancel{OutTradeNo: req.OutTradeNo}, interval, options...); err != nil {
+			return errors.Join(cause, fmt.Errorf("trade cancel: %w", err))
+		}
+		return cause
+	}
+
+	query := &TradeQuery{OutTradeNo: req.OutTradeNo}
+
+	for time.Now().Add(interval).Before(deadline) {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, abort(ctx.Err())
+		case <-timer.C:
+		}
+
+		ret, err := c.TradeQuery(ctx, query, options...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, abort(ctx.Err())
+			}
+			if !queryRetryable(err) {
+				return nil, abort(err)
+			}
+			continue
+		}
+
+		switch ret.TradeStatus {
+		case TradeSuccess, TradeFinished:
+			return &TradePayResult{
+				TradeNo:        ret.TradeNo,
+				OutTradeNo:     ret.OutTradeNo,
+				BuyerLogonID:   ret.BuyerLogonID,
+				TotalAmount:    ret.TotalAmount,
+				ReceiptAmount:  ret.ReceiptAmount,
+				BuyerPayAmount: ret.BuyerPayAmount,
+				PointAmount:    ret.PointAmount,
+				InvoiceAmount:  ret.InvoiceAmount,
+				GmtPayment:     ret.SendPayDate,
+				FundBillList:   ret.FundBillList,
+				StoreName:      ret.StoreName,
+				BuyerUserID:    ret.BuyerUserID,
+				BuyerOpenID:    ret.BuyerOpenID,
+			}, nil
+		case TradeClosed:
+			return nil, ErrTradeClosed
+		}
+	}
+	return nil, abort(ErrPayTimeout)
+}
+
+// barcodeCancelTimeout 调用方 ctx 取消后，撤销交易的超时时间
+const barcodeCancelTimeout = 30 * time.Second
+
+// queryRetryable 交易查询错误是否可继续轮询：网络错误、系统错误(20000)及交易暂不存在
+func queryRetryable(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.Code == CodeUnknown || apiErr.SubCode == "ACQ.TRADE_NOT_EXIST" || apiErr.SubCode == "ACQ.SYSTEM_ERROR"
+}
+
+// cancelTrade 撤销交易，retry_flag=Y 时间隔 interval 重试(最多3次)
+func (c *Client) cancelTrade(ctx context.Context, req *TradeCancel, interval time.Duration, options ...ActionOption) error {
+	var err error
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		var ret *TradeCancelResult
+
+		ret, err = c.TradeCancel(ctx, req, options...)
+		if err == nil && ret.RetryFlag != "Y" {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return errors.New("trade cancel failed after retries")
+}
@@ -0,0 +1,207 @@
+package alipay
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+)
+
+// newTestClient 返回一个请求 httptest 网关的客户端，handler 返回 method 对应的响应JSON
+func newTestClient(t *testing.T, handler func(method string, biz gjson.Result) string) (*Client, func()) {
+	return newTestFormClient(t, func(form url.Values) string {
+		return handler(form.Get("method"), gjson.Parse(form.Get("biz_content")))
+	})
+}
+
+// newTestFormClient 同 newTestClient，handler 接收完整的请求参数
+func newTestFormClient(t *testing.T, handler func(form url.Values) string) (*Client, func()) {
+	alipayKey, pubKey := genTestKeyPair(t)
+
+	appKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	prvKey, err := xcrypto.NewPrivateKeyFromPemBlock(xcrypto.RSA_PKCS1, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(appKey)}))
+	assert.Nil(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, r.ParseForm())
+
+		method := r.PostForm.Get("method")
+		resp := handler(r.PostForm)
+
+		h := sha256.Sum256([]byte(resp))
+		sign, _ := rsa.SignPKCS1v15(rand.Reader, alipayKey, crypto.SHA256, h[:])
+
+		_, _ = fmt.Fprintf(w, `{"%s_response":%s,"sign":"%s"}`, strings.ReplaceAll(method, ".", "_"), resp, base64.StdEncoding.EncodeToString(sign))
+	}))
+
+	cli := NewClient("2014072300007148", "", WithPrivateKey(prvKey), WithPublicKey(pubKey))
+	cli.gateway = srv.URL
+
+	return cli, srv.Close
+}
+
+func TestTradeRefund(t *testing.T) {
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		assert.Equal(t, "alipay.trade.refund", method)
+		assert.Equal(t, "200.12", biz.Get("refund_amount").String())
+		return `{"code":"10000","msg":"Success","trade_no":"2013112011001004330000121536","out_trade_no":"6823789339978248","buyer_logon_id":"159****5620","fund_change":"Y","refund_fee":88.88,"gmt_refund_pay":"2014-11-27 15:45:57"}`
+	})
+	defer closeFn()
+
+	ret, err := cli.TradeRefund(context.Background(), &TradeRefund{
+		OutTradeNo:   "6823789339978248",
+		RefundAmount: NewDecimalFromCents(20012),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, Decimal("88.88"), ret.RefundFee)
+	assert.Equal(t, "2014-11-27 15:45:57", ret.GmtRefundPay.In(cstZone).Format(TimeLayout))
+}
+
+func TestBarcodePay(t *testing.T) {
+	var queries int32
+
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		switch method {
+		case "alipay.trade.pay":
+			assert.Equal(t, "bar_code", biz.Get("scene").String())
+			return `{"code":"10003","msg":"Business Failed","sub_code":"ACQ.WAIT_USER_PAY","sub_msg":"等待用户付款"}`
+		case "alipay.trade.query":
+			if atomic.AddInt32(&queries, 1) < 2 {
+				return `{"code":"10000","msg":"Success","out_trade_no":"6823789339978248","trade_status":"WAIT_BUYER_PAY","total_amount":"88.88"}`
+			}
+			return `{"code":"10000","msg":"Success","trade_no":"2013112011001004330000121536","out_trade_no":"6823789339978248","trade_status":"TRADE_SUCCESS","total_amount":"88.88","send_pay_date":"2014-11-27 15:45:57"}`
+		}
+		return `{"code":"40004","msg":"Business Failed"}`
+	})
+	defer closeFn()
+
+	ret, err := cli.BarcodePay(context.Background(), &TradePay{
+		OutTradeNo:  "6823789339978248",
+		TotalAmount: "88.88",
+		Subject:     "Iphone6 16G",
+		AuthCode:    "28763443825664394",
+		Scene:       "bar_code",
+	}, 10*time.Millisecond, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "2013112011001004330000121536", ret.TradeNo)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&queries))
+}
+
+func TestBarcodePayTimeout(t *testing.T) {
+	var cancelled int32
+
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		switch method {
+		case "alipay.trade.pay":
+			return `{"code":"10003","msg":"Business Failed","sub_code":"ACQ.WAIT_USER_PAY","sub_msg":"等待用户付款"}`
+		case "alipay.trade.query":
+			return `{"code":"10000","msg":"Success","out_trade_no":"6823789339978248","trade_status":"WAIT_BUYER_PAY","total_amount":"88.88"}`
+		case "alipay.trade.cancel":
+			atomic.AddInt32(&cancelled, 1)
+			assert.Equal(t, "6823789339978248", biz.Get("out_trade_no").String())
+			return `{"code":"10000","msg":"Success","out_trade_no":"6823789339978248","retry_flag":"N","action":"close"}`
+		}
+		return `{"code":"40004","msg":"Business Failed"}`
+	})
+	defer closeFn()
+
+	_, err := cli.BarcodePay(context.Background(), &TradePay{
+		OutTradeNo:  "6823789339978248",
+		TotalAmount: "88.88",
+		Subject:     "Iphone6 16G",
+		AuthCode:    "28763443825664394",
+		Scene:       "bar_code",
+	}, 10*time.Millisecond, 50*time.Millisecond)
+	assert.ErrorIs(t, err, ErrPayTimeout)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+}
+
+func TestBarcodePayAbort(t *testing.T) {
+	var (
+		cancelled int32
+		queryResp atomic.Value
+	)
+
+	queryResp.Store(`{"code":"10000","msg":"Success","out_trade_no":"6823789339978248","trade_status":"WAIT_BUYER_PAY","total_amount":"88.88"}`)
+
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		switch method {
+		case "alipay.trade.pay":
+			return `{"code":"10003","msg":"Business Failed","sub_code":"ACQ.WAIT_USER_PAY","sub_msg":"等待用户付款"}`
+		case "alipay.trade.query":
+			return queryResp.Load().(string)
+		case "alipay.trade.cancel":
+			// 首次撤销需重试
+			if atomic.AddInt32(&cancelled, 1) < 2 {
+				return `{"code":"10000","msg":"Success","out_trade_no":"6823789339978248","retry_flag":"Y"}`
+			}
+			return `{"code":"10000","msg":"Success","out_trade_no":"6823789339978248","retry_flag":"N","action":"close"}`
+		}
+		return `{"code":"40004","msg":"Business Failed"}`
+	})
+	defer closeFn()
+
+	req := &TradePay{
+		OutTradeNo:  "6823789339978248",
+		TotalAmount: "88.88",
+		Subject:     "Iphone6 16G",
+		AuthCode:    "28763443825664394",
+		Scene:       "bar_code",
+	}
+
+	_, err := cli.BarcodePay(context.Background(), req, 0, time.Second)
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&cancelled))
+
+	// 调用方取消后仍会撤销交易
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	_, err = cli.BarcodePay(ctx, req, 10*time.Millisecond, time.Second)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&cancelled))
+
+	// 不可重试的查询错误直接返回，并撤销交易
+	atomic.StoreInt32(&cancelled, 1)
+	queryResp.Store(`{"code":"40004","msg":"Business Failed","sub_code":"ACQ.INVALID_PARAMETER","sub_msg":"参数无效"}`)
+
+	_, err = cli.BarcodePay(context.Background(), req, 10*time.Millisecond, time.Second)
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "ACQ.INVALID_PARAMETER", apiErr.SubCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&cancelled))
+}
+
+func TestDoBizNumberPrecision(t *testing.T) {
+	cli, closeFn := newTestClient(t, func(method string, biz gjson.Result) string {
+		// 超过 float64 精度的 int64 原样透传
+		assert.Equal(t, "9007199254740993", biz.Get("id").Raw)
+		return `{"code":"10000","msg":"Success"}`
+	})
+	defer closeFn()
+
+	biz := struct {
+		ID int64 `json:"id"`
+	}{ID: 9007199254740993}
+
+	assert.Nil(t, cli.doBiz(context.Background(), "alipay.test.query", &biz, &struct{}{}))
+}
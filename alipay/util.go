@@ -1,13 +1,41 @@
 package alipay
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/shenghui0779/sdk-go/lib/xcrypto"
 	"github.com/tidwall/gjson"
 )
 
-const CodeOK = "10000" // API请求成功
+// API公共错误码
+const (
+	CodeOK          = "10000" // API请求成功
+	CodeWaitUserPay = "10003" // 业务处理中(如：等待用户付款)
+	CodeUnknown     = "20000" // 服务不可用(业务处理结果未知)
+)
+
+// APIError API错误
+type APIError struct {
+	Code    string
+	Msg     string
+	SubCode string
+	SubMsg  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s | %s (sub_code = %s, sub_msg = %s)", e.Code, e.Msg, e.SubCode, e.SubMsg)
+}
+
+func newAPIError(ret gjson.Result) *APIError {
+	return &APIError{
+		Code:    ret.Get("code").String(),
+		Msg:     ret.Get("msg").String(),
+		SubCode: ret.Get("sub_code").String(),
+		SubMsg:  ret.Get("sub_msg").String(),
+	}
+}
 
 const (
 	HeaderMethodOverride = "x-http-method-override"
@@ -19,6 +47,7 @@ const (
 	HeaderEncryptType    = "alipay-encrypt-type"
 	HeaderAppAuthToken   = "alipay-app-auth-token"
 	HeaderSignature      = "alipay-signature"
+	HeaderSN             = "alipay-sn"
 )
 
 type GrantType string
@@ -34,6 +63,25 @@ type APIResult struct {
 	Body gjson.Result
 }
 
+// Err 非2xx状态码时返回错误，如：{"code":"...","message":"..."}
+func (r *APIResult) Err() error {
+	if r.Code >= 200 && r.Code < 300 {
+		return nil
+	}
+	return &APIError{
+		Code: r.Body.Get("code").String(),
+		Msg:  r.Body.Get("message").String(),
+	}
+}
+
+// Unmarshal 检查状态码并将结果解析到 v
+func (r *APIResult) Unmarshal(v any) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(r.Body.Raw), v)
+}
+
 // FormatPKCS1PrivateKey 格式化支付宝应用私钥(PKCS#1)
 func FormatPKCS1PrivateKey(pemStr string) (xcrypto.RSAPadding, []byte) {
 	rawLen := 64
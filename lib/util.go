@@ -50,3 +50,20 @@ func MarshalNoEscapeHTML(v interface{}) ([]byte, error) {
 	}
 	return b, nil
 }
+
+// ToX 将结构体等转换为 X (数字以 json.Number 保留，避免 int64 精度丢失)
+func ToX(v any) (X, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	x := X{}
+	if err = d.Decode(&x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
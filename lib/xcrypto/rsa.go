@@ -223,6 +223,16 @@ func NewPublicKeyFromDerBlock(pemBlock []byte) (*PublicKey, error) {
 	return &PublicKey{key: cert.PublicKey.(*rsa.PublicKey)}, nil
 }
 
+// NewPublicKeyFromCert 通过X.509证书生成RSA公钥
+func NewPublicKeyFromCert(cert *x509.Certificate) (*PublicKey, error) {
+	pk, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("cert public key is not rsa")
+	}
+
+	return &PublicKey{key: pk}, nil
+}
+
 // NewPublicKeyFromDerFile 通过DER证书生成RSA公钥
 // 注意PEM格式: -----BEGIN CERTIFICATE----- | -----END CERTIFICATE-----
 // DER转换命令: openssl x509 -inform der -in cert.cer -out cert.pem
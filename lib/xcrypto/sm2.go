@@ -0,0 +1,130 @@
+package xcrypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/tjfoc/gmsm/sm2"
+	"github.com/tjfoc/gmsm/sm3"
+	gmx509 "github.com/tjfoc/gmsm/x509"
+)
+
+// SM3 国密SM3摘要
+func SM3(data []byte) []byte {
+	return sm3.Sm3Sum(data)
+}
+
+// ------------------------------------ private-key ------------------------------------
+
+// SM2PrivateKey 国密SM2私钥
+type SM2PrivateKey struct {
+	key *sm2.PrivateKey
+}
+
+// Sign SM2私钥签名(SM3摘要，默认UID「1234567812345678」，ASN.1编码)
+func (pk *SM2PrivateKey) Sign(data []byte) ([]byte, error) {
+	return pk.key.Sign(rand.Reader, data, nil)
+}
+
+// Decrypt SM2私钥解密(C1C3C2，ASN.1编码)
+func (pk *SM2PrivateKey) Decrypt(data []byte) ([]byte, error) {
+	return pk.key.DecryptAsn1(data)
+}
+
+// PublicKey 返回对应的SM2公钥
+func (pk *SM2PrivateKey) PublicKey() *SM2PublicKey {
+	return &SM2PublicKey{key: &pk.key.PublicKey}
+}
+
+// NewSM2PrivateKeyFromPemBlock 通过PEM字节生成SM2私钥(PKCS#8，格式：`PRIVATE KEY`)
+func NewSM2PrivateKeyFromPemBlock(pemBlock []byte) (*SM2PrivateKey, error) {
+	pk, err := gmx509.ReadPrivateKeyFromPem(pemBlock, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SM2PrivateKey{key: pk}, nil
+}
+
+// NewSM2PrivateKeyFromPemFile 通过PEM文件生成SM2私钥
+func NewSM2PrivateKeyFromPemFile(pemFile string) (*SM2PrivateKey, error) {
+	keyPath, err := filepath.Abs(pemFile)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSM2PrivateKeyFromPemBlock(b)
+}
+
+// NewSM2PrivateKeyFromHex 通过十六进制私钥值(D)生成SM2私钥
+func NewSM2PrivateKeyFromHex(hexKey string) (*SM2PrivateKey, error) {
+	pk, err := gmx509.ReadPrivateKeyFromHex(hexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SM2PrivateKey{key: pk}, nil
+}
+
+// ------------------------------------ public-key ------------------------------------
+
+// SM2PublicKey 国密SM2公钥
+type SM2PublicKey struct {
+	key *sm2.PublicKey
+}
+
+// Encrypt SM2公钥加密(C1C3C2，ASN.1编码)
+func (pk *SM2PublicKey) Encrypt(data []byte) ([]byte, error) {
+	return pk.key.EncryptAsn1(data, rand.Reader)
+}
+
+// Verify SM2公钥验签
+func (pk *SM2PublicKey) Verify(data, signature []byte) error {
+	if !pk.key.Verify(data, signature) {
+		return errors.New("sm2: verification error")
+	}
+
+	return nil
+}
+
+// NewSM2PublicKeyFromPemBlock 通过PEM字节生成SM2公钥(格式：`PUBLIC KEY`)
+func NewSM2PublicKeyFromPemBlock(pemBlock []byte) (*SM2PublicKey, error) {
+	pk, err := gmx509.ReadPublicKeyFromPem(pemBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SM2PublicKey{key: pk}, nil
+}
+
+// NewSM2PublicKeyFromPemFile 通过PEM文件生成SM2公钥
+func NewSM2PublicKeyFromPemFile(pemFile string) (*SM2PublicKey, error) {
+	keyPath, err := filepath.Abs(pemFile)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSM2PublicKeyFromPemBlock(b)
+}
+
+// NewSM2PublicKeyFromHex 通过十六进制公钥值(04||X||Y)生成SM2公钥
+func NewSM2PublicKeyFromHex(hexKey string) (*SM2PublicKey, error) {
+	pk, err := gmx509.ReadPublicKeyFromHex(hexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SM2PublicKey{key: pk}, nil
+}
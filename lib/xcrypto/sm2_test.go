@@ -0,0 +1,57 @@
+package xcrypto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tjfoc/gmsm/sm2"
+	gmx509 "github.com/tjfoc/gmsm/x509"
+)
+
+func TestSM3(t *testing.T) {
+	assert.Equal(t, "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0", hex.EncodeToString(SM3([]byte("abc"))))
+}
+
+func TestSM2Crypto(t *testing.T) {
+	key, err := sm2.GenerateKey(rand.Reader)
+	assert.Nil(t, err)
+
+	prvPem, err := gmx509.WritePrivateKeyToPem(key, nil)
+	assert.Nil(t, err)
+	pubPem, err := gmx509.WritePublicKeyToPem(&key.PublicKey)
+	assert.Nil(t, err)
+
+	prvKey, err := NewSM2PrivateKeyFromPemBlock(prvPem)
+	assert.Nil(t, err)
+	pubKey, err := NewSM2PublicKeyFromPemBlock(pubPem)
+	assert.Nil(t, err)
+
+	plainText := "ILoveYiigo"
+
+	// 加解密
+	cipherText, err := pubKey.Encrypt([]byte(plainText))
+	assert.Nil(t, err)
+
+	data, err := prvKey.Decrypt(cipherText)
+	assert.Nil(t, err)
+	assert.Equal(t, plainText, string(data))
+
+	// 签名验签
+	signature, err := prvKey.Sign([]byte(plainText))
+	assert.Nil(t, err)
+	assert.Nil(t, pubKey.Verify([]byte(plainText), signature))
+	assert.NotNil(t, pubKey.Verify([]byte("ILoveGo"), signature))
+
+	// 十六进制密钥
+	hexPrv, err := NewSM2PrivateKeyFromHex(gmx509.WritePrivateKeyToHex(key))
+	assert.Nil(t, err)
+	hexPub, err := NewSM2PublicKeyFromHex(gmx509.WritePublicKeyToHex(&key.PublicKey))
+	assert.Nil(t, err)
+
+	signature, err = hexPrv.Sign([]byte(plainText))
+	assert.Nil(t, err)
+	assert.Nil(t, hexPub.Verify([]byte(plainText), signature))
+	assert.Nil(t, prvKey.PublicKey().Verify([]byte(plainText), signature))
+}
@@ -0,0 +1,428 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+const ComponentAccessToken = "component_access_token"
+
+// 第三方平台授权事件(InfoType)
+const (
+	InfoVerifyTicket     = "component_verify_ticket" // 验证票据
+	InfoAuthorized       = "authorized"              // 授权成功
+	InfoUnauthorized     = "unauthorized"            // 取消授权
+	InfoUpdateAuthorized = "updateauthorized"        // 授权更新
+)
+
+// ComponentAuthType 授权账号类型
+type ComponentAuthType int
+
+const (
+	ComponentAuthOA  ComponentAuthType = 1 // 仅展示公众号
+	ComponentAuthMP  ComponentAuthType = 2 // 仅展示小程序
+	ComponentAuthAll ComponentAuthType = 3 // 公众号和小程序都展示
+)
+
+// AuthorizerStore 授权方令牌存储
+type AuthorizerStore interface {
+	// GetRefreshToken 获取授权方的 authorizer_refresh_token
+	GetRefreshToken(ctx context.Context, appid string) (string, error)
+
+	// SetRefreshToken 保存授权方最新的 authorizer_refresh_token
+	SetRefreshToken(ctx context.Context, appid, refreshToken string) error
+}
+
+// Component 微信开放平台第三方平台
+type Component struct {
+	openClient
+
+	appid  string
+	secret string
+	srvCfg *ServerConfig
+	ticket atomic.Value
+	token  atomic.Value
+}
+
+// AppID 返回第三方平台appid
+func (c *Component) AppID() string {
+	return c.appid
+}
+
+// Secret 返回第三方平台appsecret
+func (c *Component) Secret() string {
+	return c.secret
+}
+
+// SetVerifyTicket 设置验证票据(component_verify_ticket)，用于多实例部署时从共享存储中恢复票据
+func (c *Component) SetVerifyTicket(ticket string) {
+	c.ticket.Store(ticket)
+}
+
+// VerifyTicket 返回最近一次推送的验证票据(component_verify_ticket)
+func (c *Component) VerifyTicket() (string, error) {
+	v := c.ticket.Load()
+	if v == nil {
+		return "", errors.New("component_verify_ticket is empty (not received yet?)")
+	}
+	ticket, ok := v.(string)
+	if !ok {
+		return "", errors.New("component_verify_ticket is not a string")
+	}
+	return ticket, nil
+}
+
+// ComponentAccessToken 获取第三方平台接口调用凭据
+// [参考](https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getComponentAccessToken.html)
+func (c *Component) ComponentAccessToken(ctx context.Context) (gjson.Result, error) {
+	ticket, err := c.VerifyTicket()
+	if err != nil {
+		return lib.Fail(err)
+	}
+
+	params := lib.X{
+		"component_appid":         c.appid,
+		"component_appsecret":     c.secret,
+		"component_verify_ticket": ticket,
+	}
+
+	return c.postJSON(ctx, "/cgi-bin/component/api_component_token", nil, params)
+}
+
+// AutoLoadAccessToken 自动加载ComponentAccessToken(需先收到验证票据)
+func (c *Component) AutoLoadAccessToken(interval time.Duration) error {
+	ctx := context.Background()
+
+	// 初始化AccessToken
+	ret, err := c.ComponentAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	c.token.Store(ret.Get(ComponentAccessToken).String())
+
+	// 异步定时加载
+	go func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			_ret, _ := c.ComponentAccessToken(ctx)
+			if token := _ret.Get(ComponentAccessToken).String(); len(token) != 0 {
+				c.token.Store(token)
+			}
+		}
+	}(ctx)
+
+	return nil
+}
+
+// CustomAccessTokenLoad 自定义加载ComponentAccessToken
+func (c *Component) CustomAccessTokenLoad(fn func(ctx context.Context, c *Component) (string, error), interval time.Duration) error {
+	ctx := context.Background()
+
+	// 初始化AccessToken
+	token, err := fn(ctx, c)
+	if err != nil {
+		return err
+	}
+	c.token.Store(token)
+
+	// 异步定时加载
+	go func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _token, _ := fn(ctx, c); len(_token) != 0 {
+				c.token.Store(_token)
+			}
+		}
+	}(ctx)
+
+	return nil
+}
+
+func (c *Component) getToken() (string, error) {
+	v := c.token.Load()
+	if v == nil {
+		return "", errors.New("component_access_token is empty (forgotten auto load?)")
+	}
+	token, ok := v.(string)
+	if !ok {
+		return "", errors.New("component_access_token is not a string")
+	}
+	return token, nil
+}
+
+// GetJSON GET请求JSON数据(使用component_access_token)
+func (c *Component) GetJSON(ctx context.Context, path string, query url.Values) (gjson.Result, error) {
+	token, err := c.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set(ComponentAccessToken, token)
+
+	return c.getJSON(ctx, path, query)
+}
+
+// PostJSON POST请求JSON数据(使用component_access_token)
+func (c *Component) PostJSON(ctx context.Context, path string, params lib.X) (gjson.Result, error) {
+	token, err := c.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+	query := url.Values{}
+	query.Set(ComponentAccessToken, token)
+
+	return c.postJSON(ctx, path, query, params)
+}
+
+// PreAuthCode 获取预授权码
+// [参考](https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getPreAuthCode.html)
+func (c *Component) PreAuthCode(ctx context.Context) (string, error) {
+	ret, err := c.PostJSON(ctx, "/cgi-bin/component/api_create_preauthcode", lib.X{"component_appid": c.appid})
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("pre_auth_code").String(), nil
+}
+
+// AuthURL 生成PC端授权链接(需在第三方平台配置的授权发起页域名下打开)
+// [参考](https://developers.weixin.qq.com/doc/oplatform/Third-party_Platforms/2.0/operation/thirdparty/Before_Develop/Authorization_Process_Technical_Description.html)
+func (c *Component) AuthURL(preAuthCode, redirectURI string, authType ComponentAuthType, bizAppID string) string {
+	query := url.Values{}
+
+	query.Set("component_appid", c.appid)
+	query.Set("pre_auth_code", preAuthCode)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("auth_type", strconv.Itoa(int(authType)))
+	if len(bizAppID) != 0 {
+		query.Set("biz_appid", bizAppID)
+	}
+
+	return fmt.Sprintf("https://mp.weixin.qq.com/cgi-bin/componentloginpage?%s", query.Encode())
+}
+
+// MobileAuthURL 生成移动端授权链接(需在微信客户端中打开)
+// [参考](https://developers.weixin.qq.com/doc/oplatform/Third-party_Platforms/2.0/operation/thirdparty/Before_Develop/Authorization_Process_Technical_Description.html)
+func (c *Component) MobileAuthURL(preAuthCode, redirectURI string, authType ComponentAuthType, bizAppID string) string {
+	query := url.Values{}
+
+	query.Set("action", "bindcomponent")
+	query.Set("no_scan", "1")
+	query.Set("component_appid", c.appid)
+	query.Set("pre_auth_code", preAuthCode)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("auth_type", strconv.Itoa(int(authType)))
+	if len(bizAppID) != 0 {
+		query.Set("biz_appid", bizAppID)
+	}
+
+	return fmt.Sprintf("https://open.weixin.qq.com/wxaopen/safe/bindcomponent?%s#wechat_redirect", query.Encode())
+}
+
+// QueryAuth 使用授权码获取授权信息(返回 authorization_info)，并将 authorizer_refresh_token 保存至 store，
+// 之后可通过 OfficialAccount(...) 或 MiniProgram(...) 加载授权方实例
+// [参考](https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/authorization-management/getAuthorizerAccessToken.html)
+func (c *Component) QueryAuth(ctx context.Context, authCode string, store AuthorizerStore) (gjson.Result, error) {
+	params := lib.X{
+		"component_appid":    c.appid,
+		"authorization_code": authCode,
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/component/api_query_auth", params)
+	if err != nil {
+		return lib.Fail(err)
+	}
+
+	info := ret.Get("authorization_info")
+
+	appid := info.Get("authorizer_appid").String()
+	refreshToken := info.Get("authorizer_refresh_token").String()
+	if len(appid) == 0 || len(refreshToken) == 0 {
+		return lib.Fail(errors.New("authorizer_appid or authorizer_refresh_token is empty"))
+	}
+	if err = store.SetRefreshToken(ctx, appid, refreshToken); err != nil {
+		return lib.Fail(err)
+	}
+	return info, nil
+}
+
+// AuthorizerToken 获取/刷新授权方接口调用凭据
+// [参考](https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getAuthorizerAccessToken.html)
+func (c *Component) AuthorizerToken(ctx context.Context, authorizerAppID, refreshToken string) (gjson.Result, error) {
+	params := lib.X{
+		"component_appid":          c.appid,
+		"authorizer_appid":         authorizerAppID,
+		"authorizer_refresh_token": refreshToken,
+	}
+	return c.PostJSON(ctx, "/cgi-bin/component/api_authorizer_token", params)
+}
+
+// AuthorizerInfo 获取授权方的帐号基本信息
+// [参考](https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/authorization-management/getAuthorizerInfo.html)
+func (c *Component) AuthorizerInfo(ctx context.Context, authorizerAppID string) (gjson.Result, error) {
+	params := lib.X{
+		"component_appid":  c.appid,
+		"authorizer_appid": authorizerAppID,
+	}
+	return c.PostJSON(ctx, "/cgi-bin/component/api_get_authorizer_info", params)
+}
+
+func (c *Component) loadAuthorizerToken(ctx context.Context, appid string, store AuthorizerStore) (string, error) {
+	refreshToken, err := store.GetRefreshToken(ctx, appid)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := c.AuthorizerToken(ctx, appid, refreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	// 刷新令牌可能会变化，需及时保存
+	if v := ret.Get("authorizer_refresh_token").String(); len(v) != 0 && v != refreshToken {
+		if err = store.SetRefreshToken(ctx, appid, v); err != nil {
+			return "", err
+		}
+	}
+	return ret.Get("authorizer_access_token").String(), nil
+}
+
+// OfficialAccount 生成代授权公众号实例，接口调用使用 authorizer_access_token，并按 interval 定时刷新
+func (c *Component) OfficialAccount(appid string, store AuthorizerStore, interval time.Duration, options ...OAOption) (*OfficialAccount, error) {
+	oa := &OfficialAccount{
+		host:   "https://api.weixin.qq.com",
+		appid:  appid,
+		srvCfg: &ServerConfig{token: c.srvCfg.token, aeskey: c.srvCfg.aeskey, receiveID: c.appid},
+		client: c.client,
+		logger: c.logger,
+	}
+	for _, f := range options {
+		f(oa)
+	}
+
+	fn := func(ctx context.Context, oa *OfficialAccount) (string, error) {
+		return c.loadAuthorizerToken(ctx, oa.appid, store)
+	}
+	if err := oa.CustomAccessTokenLoad(fn, interval); err != nil {
+		return nil, err
+	}
+	return oa, nil
+}
+
+// MiniProgram 生成代授权小程序实例，接口调用使用 authorizer_access_token，并按 interval 定时刷新
+func (c *Component) MiniProgram(appid string, store AuthorizerStore, interval time.Duration, options ...MPOption) (*MiniProgram, error) {
+	mp := &MiniProgram{
+		host:   "https://api.weixin.qq.com",
+		appid:  appid,
+		srvCfg: &ServerConfig{token: c.srvCfg.token, aeskey: c.srvCfg.aeskey, receiveID: c.appid},
+		sfMode: newSafeMode(),
+		client: c.client,
+		logger: c.logger,
+	}
+	for _, f := range options {
+		f(mp)
+	}
+
+	fn := func(ctx context.Context, mp *MiniProgram) (string, error) {
+		return c.loadAuthorizerToken(ctx, mp.appid, store)
+	}
+	if err := mp.CustomAccessTokenLoad(fn, interval); err != nil {
+		return nil, err
+	}
+	return mp, nil
+}
+
+// VerifyURL 服务器URL验证，使用：signature、timestamp、nonce（若验证成功，请原样返回echostr参数内容）
+func (c *Component) VerifyURL(signature, timestamp, nonce string) error {
+	if SignWithSHA1(c.srvCfg.token, timestamp, nonce) != signature {
+		return errors.New("signature verified fail")
+	}
+	return nil
+}
+
+// DecodeEventMsg 解析授权事件消息，使用：msg_signature、timestamp、nonce、msg_encrypt
+// 若为验证票据推送(InfoType=component_verify_ticket)，会自动更新票据
+// [参考](https://developers.weixin.qq.com/doc/oplatform/openApi/OpenApiDoc/ticket-token/getComponentVerifyTicket.html)
+func (c *Component) DecodeEventMsg(signature, timestamp, nonce, encryptMsg string) (value.V, error) {
+	if SignWithSHA1(c.srvCfg.token, timestamp, nonce, encryptMsg) != signature {
+		return nil, errors.New("signature verified fail")
+	}
+
+	b, err := EventDecrypt(c.appid, c.srvCfg.aeskey, encryptMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := XMLToValue(b)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Get("InfoType") == InfoVerifyTicket {
+		c.SetVerifyTicket(msg.Get("ComponentVerifyTicket"))
+	}
+	return msg, nil
+}
+
+// ReplyEventMsg 代授权方回复事件消息(消息加解密使用第三方平台的配置)
+func (c *Component) ReplyEventMsg(msg value.V) (value.V, error) {
+	return EventReply(c.appid, c.srvCfg.token, c.srvCfg.aeskey, msg)
+}
+
+// ComponentOption 第三方平台设置项
+type ComponentOption func(c *Component)
+
+// WithComponentSrvCfg 设置第三方平台消息校验Token和消息加解密Key
+func WithComponentSrvCfg(token, aeskey string) ComponentOption {
+	return func(c *Component) {
+		c.srvCfg.token = token
+		c.srvCfg.aeskey = aeskey
+	}
+}
+
+// WithComponentClient 设置第三方平台请求的 HTTP Client
+func WithComponentClient(cli *http.Client) ComponentOption {
+	return func(c *Component) {
+		c.client = resty.NewWithClient(cli)
+	}
+}
+
+// WithComponentLogger 设置第三方平台日志记录
+func WithComponentLogger(fn func(ctx context.Context, err error, data map[string]string)) ComponentOption {
+	return func(c *Component) {
+		c.logger = fn
+	}
+}
+
+// NewComponent 生成一个第三方平台实例
+func NewComponent(appid, secret string, options ...ComponentOption) *Component {
+	c := &Component{
+		openClient: openClient{
+			host:   "https://api.weixin.qq.com",
+			client: lib.NewClient(),
+		},
+		appid:  appid,
+		secret: secret,
+		srvCfg: new(ServerConfig),
+	}
+	for _, f := range options {
+		f(c)
+	}
+	return c
+}
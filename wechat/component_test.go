@@ -0,0 +1,143 @@
+package wechat
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+type testAuthorizerStore struct {
+	mutex  sync.Mutex
+	tokens map[string]string
+}
+
+func (s *testAuthorizerStore) GetRefreshToken(ctx context.Context, appid string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	token, ok := s.tokens[appid]
+	if !ok {
+		return "", errors.New("authorizer not found")
+	}
+	return token, nil
+}
+
+func (s *testAuthorizerStore) SetRefreshToken(ctx context.Context, appid, refreshToken string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.tokens[appid] = refreshToken
+	return nil
+}
+
+func genTestEventMsg(t *testing.T, receiveID, token, aeskey string, msg value.V) (signature, timestamp, nonce, encryptMsg string) {
+	reply, err := EventReply(receiveID, token, aeskey, msg)
+	assert.Nil(t, err)
+	return reply.Get("MsgSignature"), reply.Get("TimeStamp"), reply.Get("Nonce"), reply.Get("Encrypt")
+}
+
+func TestComponent(t *testing.T) {
+	compAppID := "wx2d4b0b7f0d0b0c0d"
+	authAppID := "wx4f4bc4dec97d474b"
+	token := "component_token"
+	aeskey := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))[:43]
+
+	var refreshed int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		params := gjson.ParseBytes(body)
+
+		switch r.URL.Path {
+		case "/cgi-bin/component/api_component_token":
+			assert.Equal(t, "TICKET", params.Get("component_verify_ticket").String())
+			_, _ = w.Write([]byte(`{"component_access_token":"COMPONENT_TOKEN","expires_in":7200}`))
+		case "/cgi-bin/component/api_query_auth":
+			assert.Equal(t, "COMPONENT_TOKEN", r.URL.Query().Get(ComponentAccessToken))
+			assert.Equal(t, "AUTH_CODE", params.Get("authorization_code").String())
+			_, _ = w.Write([]byte(`{"authorization_info":{"authorizer_appid":"` + authAppID + `","authorizer_access_token":"ACCESS_TOKEN_0","expires_in":7200,"authorizer_refresh_token":"REFRESH_TOKEN_1"}}`))
+		case "/cgi-bin/component/api_authorizer_token":
+			atomic.AddInt32(&refreshed, 1)
+			assert.Equal(t, "REFRESH_TOKEN_1", params.Get("authorizer_refresh_token").String())
+			_, _ = w.Write([]byte(`{"authorizer_access_token":"ACCESS_TOKEN_1","expires_in":7200,"authorizer_refresh_token":"REFRESH_TOKEN_2"}`))
+		default:
+			_, _ = w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	c := NewComponent(compAppID, "secret", WithComponentSrvCfg(token, aeskey))
+	c.host = srv.URL
+
+	// 未收到验证票据
+	assert.NotNil(t, c.AutoLoadAccessToken(time.Hour))
+
+	// 验证票据推送
+	msg, err := c.DecodeEventMsg(genTestEventMsg(t, compAppID, token, aeskey, value.V{
+		"AppId":                 compAppID,
+		"InfoType":              InfoVerifyTicket,
+		"ComponentVerifyTicket": "TICKET",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, InfoVerifyTicket, msg.Get("InfoType"))
+
+	ticket, err := c.VerifyTicket()
+	assert.Nil(t, err)
+	assert.Equal(t, "TICKET", ticket)
+
+	assert.Nil(t, c.AutoLoadAccessToken(time.Hour))
+
+	// 授权码换取授权信息，并保存刷新令牌
+	store := &testAuthorizerStore{tokens: make(map[string]string)}
+
+	info, err := c.QueryAuth(ctx, "AUTH_CODE", store)
+	assert.Nil(t, err)
+	assert.Equal(t, authAppID, info.Get("authorizer_appid").String())
+
+	refreshToken, err := store.GetRefreshToken(ctx, authAppID)
+	assert.Nil(t, err)
+	assert.Equal(t, "REFRESH_TOKEN_1", refreshToken)
+
+	// 代授权公众号，刷新令牌轮换后保存最新值
+	oa, err := c.OfficialAccount(authAppID, store, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshed))
+
+	accessToken, err := oa.getToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "ACCESS_TOKEN_1", accessToken)
+
+	refreshToken, err = store.GetRefreshToken(ctx, authAppID)
+	assert.Nil(t, err)
+	assert.Equal(t, "REFRESH_TOKEN_2", refreshToken)
+
+	// 授权方事件消息使用第三方平台 appid 作为 receive_id
+	msg, err = oa.DecodeEventMsg(genTestEventMsg(t, compAppID, token, aeskey, value.V{
+		"ToUserName": "gh_0123456789ab",
+		"MsgType":    "event",
+		"Event":      "subscribe",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, "subscribe", msg.Get("Event"))
+
+	reply, err := oa.ReplyEventMsg(value.V{"MsgType": "text", "Content": "hello"})
+	assert.Nil(t, err)
+
+	b, err := EventDecrypt(compAppID, aeskey, reply.Get("Encrypt"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), "hello")
+}
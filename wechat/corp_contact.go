@@ -0,0 +1,220 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// Department 企业微信部门
+type Department struct {
+	ID               int64    `json:"id"`
+	Name             string   `json:"name"`
+	NameEn           string   `json:"name_en"`
+	DepartmentLeader []string `json:"department_leader"`
+	ParentID         int64    `json:"parentid"`
+	Order            int64    `json:"order"`
+}
+
+// DepartmentID 子部门ID(department/simplelist 返回)
+type DepartmentID struct {
+	ID       int64 `json:"id"`
+	ParentID int64 `json:"parentid"`
+	Order    int64 `json:"order"`
+}
+
+// DeptUser 成员ID及所在部门(user/list_id 返回)
+type DeptUser struct {
+	UserID     string `json:"userid"`
+	Department int64  `json:"department"`
+}
+
+// CorpUser 企业微信成员
+type CorpUser struct {
+	UserID         string   `json:"userid"`
+	Name           string   `json:"name"`
+	Department     []int64  `json:"department"`
+	Order          []int64  `json:"order"`
+	Position       string   `json:"position"`
+	Mobile         string   `json:"mobile"`
+	Gender         string   `json:"gender"`
+	Email          string   `json:"email"`
+	BizMail        string   `json:"biz_mail"`
+	IsLeaderInDept []int    `json:"is_leader_in_dept"`
+	DirectLeader   []string `json:"direct_leader"`
+	Avatar         string   `json:"avatar"`
+	ThumbAvatar    string   `json:"thumb_avatar"`
+	Telephone      string   `json:"telephone"`
+	Alias          string   `json:"alias"`
+	Status         int      `json:"status"` // 1-已激活 2-已禁用 4-未激活 5-退出企业
+	MainDepartment int64    `json:"main_department"`
+	OpenUserID     string   `json:"open_userid"`
+}
+
+// CorpTag 企业微信标签
+type CorpTag struct {
+	TagID   int64  `json:"tagid"`
+	TagName string `json:"tagname"`
+}
+
+// CorpTagMembers 标签成员
+type CorpTagMembers struct {
+	TagName   string     `json:"tagname"`
+	UserList  []*TagUser `json:"userlist"`
+	PartyList []int64    `json:"partylist"`
+}
+
+// TagUser 标签中的成员
+type TagUser struct {
+	UserID string `json:"userid"`
+	Name   string `json:"name"`
+}
+
+// ListDepartments 获取指定部门及其下的子部门(id为0时获取全量)
+// [参考](https://developer.work.weixin.qq.com/document/path/90208)
+func (c *Corp) ListDepartments(ctx context.Context, id int64) ([]*Department, error) {
+	var query url.Values
+	if id != 0 {
+		query = url.Values{}
+		query.Set("id", strconv.FormatInt(id, 10))
+	}
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/department/list", query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*Department, 0)
+	if err = unmarshalResult(ret.Get("department"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListDepartmentIDs 获取子部门ID列表(id为0时获取全量)
+// [参考](https://developer.work.weixin.qq.com/document/path/95350)
+func (c *Corp) ListDepartmentIDs(ctx context.Context, id int64) ([]*DepartmentID, error) {
+	var query url.Values
+	if id != 0 {
+		query = url.Values{}
+		query.Set("id", strconv.FormatInt(id, 10))
+	}
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/department/simplelist", query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*DepartmentID, 0)
+	if err = unmarshalResult(ret.Get("department_id"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetDepartment 获取单个部门详情
+// [参考](https://developer.work.weixin.qq.com/document/path/95351)
+func (c *Corp) GetDepartment(ctx context.Context, id int64) (*Department, error) {
+	query := url.Values{}
+	query.Set("id", strconv.FormatInt(id, 10))
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/department/get", query)
+	if err != nil {
+		return nil, err
+	}
+
+	dept := new(Department)
+	if err = unmarshalResult(ret.Get("department"), dept); err != nil {
+		return nil, err
+	}
+	return dept, nil
+}
+
+// GetUser 读取成员
+// [参考](https://developer.work.weixin.qq.com/document/path/90196)
+func (c *Corp) GetUser(ctx context.Context, userid string) (*CorpUser, error) {
+	query := url.Values{}
+	query.Set("userid", userid)
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/user/get", query)
+	if err != nil {
+		return nil, err
+	}
+
+	user := new(CorpUser)
+	if err = unmarshalResult(ret, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUserIDs 分页获取成员ID列表，limit 最大10000
+// [参考](https://developer.work.weixin.qq.com/document/path/96067)
+func (c *Corp) ListUserIDs(ctx context.Context, cursor string, limit int) ([]*DeptUser, string, error) {
+	params := lib.X{}
+	if len(cursor) != 0 {
+		params["cursor"] = cursor
+	}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/user/list_id", params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list := make([]*DeptUser, 0)
+	if err = unmarshalResult(ret.Get("dept_user"), &list); err != nil {
+		return nil, "", err
+	}
+	return list, ret.Get("next_cursor").String(), nil
+}
+
+// UserIDIterator 成员ID列表迭代器(同一成员在多个部门时会返回多条)
+// [参考](https://developer.work.weixin.qq.com/document/path/96067)
+func (c *Corp) UserIDIterator(limit int) *Iterator[*DeptUser] {
+	return NewIterator(func(ctx context.Context, cursor string) ([]*DeptUser, string, error) {
+		return c.ListUserIDs(ctx, cursor, limit)
+	}, "")
+}
+
+// ListTags 获取标签列表
+// [参考](https://developer.work.weixin.qq.com/document/path/90216)
+func (c *Corp) ListTags(ctx context.Context) ([]*CorpTag, error) {
+	ret, err := c.GetJSON(ctx, "/cgi-bin/tag/list", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*CorpTag, 0)
+	if err = unmarshalResult(ret.Get("taglist"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetTagMembers 获取标签成员
+// [参考](https://developer.work.weixin.qq.com/document/path/90213)
+func (c *Corp) GetTagMembers(ctx context.Context, tagID int64) (*CorpTagMembers, error) {
+	if tagID == 0 {
+		return nil, errors.New("tagid is required")
+	}
+
+	query := url.Values{}
+	query.Set("tagid", strconv.FormatInt(tagID, 10))
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/tag/get", query)
+	if err != nil {
+		return nil, err
+	}
+
+	members := new(CorpTagMembers)
+	if err = unmarshalResult(ret, members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
@@ -0,0 +1,539 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+// EventChangeContact 通讯录变更事件
+const EventChangeContact = "change_contact"
+
+// 通讯录变更类型(ChangeType)
+const (
+	ContactCreateUser  = "create_user"
+	ContactUpdateUser  = "update_user"
+	ContactDeleteUser  = "delete_user"
+	ContactCreateParty = "create_party"
+	ContactUpdateParty = "update_party"
+	ContactDeleteParty = "delete_party"
+	ContactUpdateTag   = "update_tag"
+)
+
+// ContactUser 快照中的成员
+type ContactUser struct {
+	UserID      string  `json:"userid"`
+	Departments []int64 `json:"departments"`
+}
+
+// ContactTag 快照中的标签
+type ContactTag struct {
+	TagID    int64    `json:"tagid"`
+	TagName  string   `json:"tagname"`
+	UserIDs  []string `json:"userids"`
+	PartyIDs []int64  `json:"partyids"`
+}
+
+// ContactSnapshot 通讯录快照；Apply 非并发安全，需由调用方保证串行
+type ContactSnapshot struct {
+	Departments map[int64]*Department   `json:"departments"`
+	Users       map[string]*ContactUser `json:"users"`
+	Tags        map[int64]*ContactTag   `json:"tags"`
+	SyncedAt    time.Time               `json:"synced_at"`
+}
+
+// Apply 应用 change_contact 事件(已通过DecodeEventMsg解析)，未知的变更类型会被忽略
+// [参考](https://developer.work.weixin.qq.com/document/path/90970)
+func (s *ContactSnapshot) Apply(msg value.V) error {
+	if event := msg.Get("Event"); event != EventChangeContact {
+		return fmt.Errorf("unexpected event: %s", event)
+	}
+
+	switch msg.Get("ChangeType") {
+	case ContactCreateUser, ContactUpdateUser:
+		userID := msg.Get("UserID")
+		if len(userID) == 0 {
+			return errors.New("UserID is empty")
+		}
+
+		user, ok := s.Users[userID]
+		if !ok {
+			user = &ContactUser{UserID: userID}
+		}
+		if newID := msg.Get("NewUserID"); len(newID) != 0 && newID != userID {
+			delete(s.Users, userID)
+			user.UserID = newID
+			for _, tag := range s.Tags {
+				for i, v := range tag.UserIDs {
+					if v == userID {
+						tag.UserIDs[i] = newID
+					}
+				}
+				sort.Strings(tag.UserIDs)
+			}
+		}
+		if msg.Has("Department") {
+			depts, err := splitInt64(msg.Get("Department"))
+			if err != nil {
+				return err
+			}
+			user.Departments = depts
+		}
+		s.Users[user.UserID] = user
+	case ContactDeleteUser:
+		userID := msg.Get("UserID")
+		delete(s.Users, userID)
+		for _, tag := range s.Tags {
+			tag.UserIDs = removeString(tag.UserIDs, userID)
+		}
+	case ContactCreateParty, ContactUpdateParty:
+		id, err := strconv.ParseInt(msg.Get("Id"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid party id: %w", err)
+		}
+
+		dept, ok := s.Departments[id]
+		if !ok {
+			dept = &Department{ID: id}
+		}
+		if msg.Has("Name") {
+			dept.Name = msg.Get("Name")
+		}
+		if msg.Has("ParentId") {
+			if dept.ParentID, err = strconv.ParseInt(msg.Get("ParentId"), 10, 64); err != nil {
+				return fmt.Errorf("invalid parent id: %w", err)
+			}
+		}
+		if msg.Has("Order") {
+			if dept.Order, err = strconv.ParseInt(msg.Get("Order"), 10, 64); err != nil {
+				return fmt.Errorf("invalid order: %w", err)
+			}
+		}
+		s.Departments[id] = dept
+	case ContactDeleteParty:
+		id, err := strconv.ParseInt(msg.Get("Id"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid party id: %w", err)
+		}
+		delete(s.Departments, id)
+	case ContactUpdateTag:
+		id, err := strconv.ParseInt(msg.Get("TagId"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid tag id: %w", err)
+		}
+
+		tag, ok := s.Tags[id]
+		if !ok {
+			tag = &ContactTag{TagID: id}
+			s.Tags[id] = tag
+		}
+		for _, v := range splitString(msg.Get("AddUserItems")) {
+			if !containsString(tag.UserIDs, v) {
+				tag.UserIDs = append(tag.UserIDs, v)
+			}
+		}
+		for _, v := range splitString(msg.Get("DelUserItems")) {
+			tag.UserIDs = removeString(tag.UserIDs, v)
+		}
+		sort.Strings(tag.UserIDs)
+
+		addParty, err := splitInt64(msg.Get("AddPartyItems"))
+		if err != nil {
+			return err
+		}
+		delParty, err := splitInt64(msg.Get("DelPartyItems"))
+		if err != nil {
+			return err
+		}
+		parties := make(map[int64]struct{}, len(tag.PartyIDs))
+		for _, v := range tag.PartyIDs {
+			parties[v] = struct{}{}
+		}
+		for _, v := range addParty {
+			parties[v] = struct{}{}
+		}
+		for _, v := range delParty {
+			delete(parties, v)
+		}
+		tag.PartyIDs = sortedInt64Keys(parties)
+	}
+	return nil
+}
+
+// ContactDiff 两个通讯录快照之间的差异
+type ContactDiff struct {
+	AddedDepts   []int64  `json:"added_depts"`
+	UpdatedDepts []int64  `json:"updated_depts"`
+	RemovedDepts []int64  `json:"removed_depts"`
+	AddedUsers   []string `json:"added_users"`
+	UpdatedUsers []string `json:"updated_users"`
+	RemovedUsers []string `json:"removed_users"`
+	AddedTags    []int64  `json:"added_tags"`
+	UpdatedTags  []int64  `json:"updated_tags"`
+	RemovedTags  []int64  `json:"removed_tags"`
+}
+
+// IsEmpty 是否无差异
+func (d *ContactDiff) IsEmpty() bool {
+	return len(d.AddedDepts)+len(d.UpdatedDepts)+len(d.RemovedDepts)+
+		len(d.AddedUsers)+len(d.UpdatedUsers)+len(d.RemovedUsers)+
+		len(d.AddedTags)+len(d.UpdatedTags)+len(d.RemovedTags) == 0
+}
+
+// DiffContact 比较新旧快照，返回新快照相对旧快照的变化(结果已排序)
+func DiffContact(old, cur *ContactSnapshot) *ContactDiff {
+	diff := new(ContactDiff)
+
+	for id, v := range cur.Departments {
+		if o, ok := old.Departments[id]; !ok {
+			diff.AddedDepts = append(diff.AddedDepts, id)
+		} else if !equalDepartment(o, v) {
+			diff.UpdatedDepts = append(diff.UpdatedDepts, id)
+		}
+	}
+	for id := range old.Departments {
+		if _, ok := cur.Departments[id]; !ok {
+			diff.RemovedDepts = append(diff.RemovedDepts, id)
+		}
+	}
+
+	for id, v := range cur.Users {
+		if o, ok := old.Users[id]; !ok {
+			diff.AddedUsers = append(diff.AddedUsers, id)
+		} else if !equalInt64s(o.Departments, v.Departments) {
+			diff.UpdatedUsers = append(diff.UpdatedUsers, id)
+		}
+	}
+	for id := range old.Users {
+		if _, ok := cur.Users[id]; !ok {
+			diff.RemovedUsers = append(diff.RemovedUsers, id)
+		}
+	}
+
+	for id, v := range cur.Tags {
+		if o, ok := old.Tags[id]; !ok {
+			diff.AddedTags = append(diff.AddedTags, id)
+		} else if o.TagName != v.TagName || !equalStrings(o.UserIDs, v.UserIDs) || !equalInt64s(o.PartyIDs, v.PartyIDs) {
+			diff.UpdatedTags = append(diff.UpdatedTags, id)
+		}
+	}
+	for id := range old.Tags {
+		if _, ok := cur.Tags[id]; !ok {
+			diff.RemovedTags = append(diff.RemovedTags, id)
+		}
+	}
+
+	for _, v := range [][]int64{diff.AddedDepts, diff.UpdatedDepts, diff.RemovedDepts, diff.AddedTags, diff.UpdatedTags, diff.RemovedTags} {
+		sort.Slice(v, func(i, j int) bool { return v[i] < v[j] })
+	}
+	for _, v := range [][]string{diff.AddedUsers, diff.UpdatedUsers, diff.RemovedUsers} {
+		sort.Strings(v)
+	}
+	return diff
+}
+
+// ContactSyncer 企业微信通讯录同步
+type ContactSyncer struct {
+	corp        *Corp
+	pageSize    int
+	concurrency int
+	interval    time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+// Snapshot 拉取全量通讯录快照(部门、成员ID及所在部门、标签及标签成员)
+func (cs *ContactSyncer) Snapshot(ctx context.Context) (*ContactSnapshot, error) {
+	snapshot := &ContactSnapshot{
+		Departments: make(map[int64]*Department),
+		Users:       make(map[string]*ContactUser),
+		Tags:        make(map[int64]*ContactTag),
+		SyncedAt:    time.Now(),
+	}
+
+	// 部门
+	if err := cs.throttle(ctx); err != nil {
+		return nil, err
+	}
+	depts, err := cs.corp.ListDepartments(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range depts {
+		snapshot.Departments[v.ID] = v
+	}
+
+	// 成员
+	it := NewIterator(func(ctx context.Context, cursor string) ([]*DeptUser, string, error) {
+		if err := cs.throttle(ctx); err != nil {
+			return nil, "", err
+		}
+		return cs.corp.ListUserIDs(ctx, cursor, cs.pageSize)
+	}, "")
+	for {
+		du, err := it.Next(ctx)
+		if err != nil {
+			if errors.Is(err, ErrIterDone) {
+				break
+			}
+			return nil, err
+		}
+
+		user, ok := snapshot.Users[du.UserID]
+		if !ok {
+			user = &ContactUser{UserID: du.UserID}
+			snapshot.Users[du.UserID] = user
+		}
+		user.Departments = append(user.Departments, du.Department)
+	}
+	for _, v := range snapshot.Users {
+		sort.Slice(v.Departments, func(i, j int) bool { return v.Departments[i] < v.Departments[j] })
+	}
+
+	// 标签
+	if err = cs.throttle(ctx); err != nil {
+		return nil, err
+	}
+	tags, err := cs.corp.ListTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = cs.loadTagMembers(ctx, snapshot, tags); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (cs *ContactSyncer) loadTagMembers(ctx context.Context, snapshot *ContactSnapshot, tags []*CorpTag) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		err   error
+	)
+
+	sem := make(chan struct{}, cs.concurrency)
+
+	for _, tag := range tags {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(tag *CorpTag) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			members, _err := cs.tagMembers(ctx, tag.TagID)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if _err != nil {
+				if err == nil {
+					err = fmt.Errorf("tag(%d) members: %w", tag.TagID, _err)
+					cancel()
+				}
+				return
+			}
+
+			ct := &ContactTag{
+				TagID:   tag.TagID,
+				TagName: tag.TagName,
+				UserIDs: make([]string, 0, len(members.UserList)),
+			}
+			for _, v := range members.UserList {
+				ct.UserIDs = append(ct.UserIDs, v.UserID)
+			}
+			sort.Strings(ct.UserIDs)
+			ct.PartyIDs = append(ct.PartyIDs, members.PartyList...)
+			sort.Slice(ct.PartyIDs, func(i, j int) bool { return ct.PartyIDs[i] < ct.PartyIDs[j] })
+
+			snapshot.Tags[tag.TagID] = ct
+		}(tag)
+	}
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (cs *ContactSyncer) tagMembers(ctx context.Context, tagID int64) (*CorpTagMembers, error) {
+	if err := cs.throttle(ctx); err != nil {
+		return nil, err
+	}
+	return cs.corp.GetTagMembers(ctx, tagID)
+}
+
+// throttle 控制请求间隔，避免触发企业微信的频率限制
+func (cs *ContactSyncer) throttle(ctx context.Context) error {
+	if cs.interval <= 0 {
+		return ctx.Err()
+	}
+
+	cs.mutex.Lock()
+	now := time.Now()
+	if cs.next.Before(now) {
+		cs.next = now
+	}
+	wait := cs.next.Sub(now)
+	cs.next = cs.next.Add(cs.interval)
+	cs.mutex.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ContactSyncOption 通讯录同步设置项
+type ContactSyncOption func(cs *ContactSyncer)
+
+// WithContactPageSize 设置成员列表的分页大小(默认：1000，最大：10000)
+func WithContactPageSize(size int) ContactSyncOption {
+	return func(cs *ContactSyncer) {
+		cs.pageSize = size
+	}
+}
+
+// WithContactConcurrency 设置拉取标签成员的并发数(默认：4)
+func WithContactConcurrency(n int) ContactSyncOption {
+	return func(cs *ContactSyncer) {
+		if n > 0 {
+			cs.concurrency = n
+		}
+	}
+}
+
+// WithContactInterval 设置请求的最小间隔(默认：50ms，即每秒不超过20次)
+func WithContactInterval(d time.Duration) ContactSyncOption {
+	return func(cs *ContactSyncer) {
+		cs.interval = d
+	}
+}
+
+// NewContactSyncer 生成企业微信通讯录同步实例
+func NewContactSyncer(corp *Corp, options ...ContactSyncOption) *ContactSyncer {
+	cs := &ContactSyncer{
+		corp:        corp,
+		pageSize:    1000,
+		concurrency: 4,
+		interval:    50 * time.Millisecond,
+	}
+	for _, f := range options {
+		f(cs)
+	}
+	return cs
+}
+
+func equalDepartment(a, b *Department) bool {
+	return a.Name == b.Name && a.NameEn == b.NameEn && a.ParentID == b.ParentID && a.Order == b.Order && equalStrings(a.DepartmentLeader, b.DepartmentLeader)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalInt64s(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	ret := list[:0]
+	for _, v := range list {
+		if v != s {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func splitString(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	var ret []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) != 0 {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func splitInt64(s string) ([]int64, error) {
+	strs := splitString(s)
+
+	ret := make([]int64, 0, len(strs))
+	for _, v := range strs {
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id list(%s): %w", s, err)
+		}
+		ret = append(ret, i)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret, nil
+}
+
+func sortedInt64Keys(m map[int64]struct{}) []int64 {
+	ret := make([]int64, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
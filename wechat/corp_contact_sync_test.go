@@ -0,0 +1,82 @@
+package wechat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+func TestContactSyncer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/department/list":
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","department":[{"id":1,"name":"总部","parentid":0,"order":100},{"id":2,"name":"研发","parentid":1,"order":50}]}`))
+		case "/cgi-bin/user/list_id":
+			body, _ := io.ReadAll(r.Body)
+			if gjson.GetBytes(body, "cursor").String() == "" {
+				_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","next_cursor":"c1","dept_user":[{"userid":"zhangsan","department":1},{"userid":"lisi","department":2}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","next_cursor":"","dept_user":[{"userid":"zhangsan","department":2}]}`))
+		case "/cgi-bin/tag/list":
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","taglist":[{"tagid":1,"tagname":"管理"},{"tagid":2,"tagname":"技术"}]}`))
+		case "/cgi-bin/tag/get":
+			if r.URL.Query().Get("tagid") == "1" {
+				_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","tagname":"管理","userlist":[{"userid":"zhangsan","name":"张三"}],"partylist":[1]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","tagname":"技术","userlist":[{"userid":"lisi","name":"李四"}],"partylist":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	corp := NewCorp("ww4f4bc4dec97d474b", "secret")
+	corp.host = srv.URL
+	corp.token.Store("ACCESS_TOKEN")
+
+	cs := NewContactSyncer(corp, WithContactInterval(0), WithContactConcurrency(2))
+
+	old, err := cs.Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, old.Departments, 2)
+	assert.Equal(t, []int64{1, 2}, old.Users["zhangsan"].Departments)
+	assert.Equal(t, []string{"zhangsan"}, old.Tags[1].UserIDs)
+	assert.Equal(t, []int64{1}, old.Tags[1].PartyIDs)
+
+	cur, err := cs.Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, DiffContact(old, cur).IsEmpty())
+
+	events := []value.V{
+		{"Event": "change_contact", "ChangeType": "create_user", "UserID": "wangwu", "Department": "2"},
+		{"Event": "change_contact", "ChangeType": "update_user", "UserID": "zhangsan", "NewUserID": "zhangsan01", "Department": "1"},
+		{"Event": "change_contact", "ChangeType": "delete_user", "UserID": "lisi"},
+		{"Event": "change_contact", "ChangeType": "update_party", "Id": "2", "Name": "研发中心"},
+		{"Event": "change_contact", "ChangeType": "create_party", "Id": "3", "Name": "测试", "ParentId": "2", "Order": "1"},
+		{"Event": "change_contact", "ChangeType": "update_tag", "TagId": "2", "AddUserItems": "wangwu", "AddPartyItems": "3"},
+	}
+	for _, v := range events {
+		assert.Nil(t, cur.Apply(v))
+	}
+	assert.NotNil(t, cur.Apply(value.V{"Event": "subscribe"}))
+
+	assert.Equal(t, []string{"zhangsan01"}, cur.Tags[1].UserIDs)
+	assert.Equal(t, []string{"wangwu"}, cur.Tags[2].UserIDs)
+
+	diff := DiffContact(old, cur)
+	assert.Equal(t, []int64{3}, diff.AddedDepts)
+	assert.Equal(t, []int64{2}, diff.UpdatedDepts)
+	assert.Equal(t, []string{"wangwu", "zhangsan01"}, diff.AddedUsers)
+	assert.Equal(t, []string{"lisi", "zhangsan"}, diff.RemovedUsers)
+	assert.Equal(t, []int64{1, 2}, diff.UpdatedTags)
+	assert.Empty(t, diff.RemovedTags)
+}
@@ -0,0 +1,30 @@
+package wechat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDepartmentIDs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cgi-bin/department/simplelist", r.URL.Path)
+		assert.Equal(t, "1", r.URL.Query().Get("id"))
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","department_id":[{"id":2,"parentid":1,"order":10},{"id":3,"parentid":2,"order":40}]}`))
+	}))
+	defer srv.Close()
+
+	corp := NewCorp("ww4f4bc4dec97d474b", "secret")
+	corp.host = srv.URL
+	corp.token.Store("ACCESS_TOKEN")
+
+	list, err := corp.ListDepartmentIDs(context.Background(), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, []*DepartmentID{
+		{ID: 2, ParentID: 1, Order: 10},
+		{ID: 3, ParentID: 2, Order: 40},
+	}, list)
+}
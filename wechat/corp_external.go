@@ -0,0 +1,552 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+// 客户变更事件
+const (
+	EventChangeExternalContact = "change_external_contact"
+	EventChangeExternalChat    = "change_external_chat"
+)
+
+// 客户变更类型(ChangeType)
+const (
+	ExternalAddContact     = "add_external_contact"      // 添加企业客户
+	ExternalEditContact    = "edit_external_contact"     // 编辑企业客户
+	ExternalAddHalfContact = "add_half_external_contact" // 外部联系人免验证添加成员
+	ExternalDelContact     = "del_external_contact"      // 删除企业客户
+	ExternalDelFollowUser  = "del_follow_user"           // 删除跟进成员
+	ExternalTransferFail   = "transfer_fail"             // 客户接替失败
+)
+
+// 客户群变更类型(ChangeType)
+const (
+	ExternalChatCreate  = "create"
+	ExternalChatUpdate  = "update"
+	ExternalChatDismiss = "dismiss"
+)
+
+// ExternalContact 外部联系人
+type ExternalContact struct {
+	ExternalUserID  string `json:"external_userid"`
+	Name            string `json:"name"`
+	Position        string `json:"position"`
+	Avatar          string `json:"avatar"`
+	CorpName        string `json:"corp_name"`
+	CorpFullName    string `json:"corp_full_name"`
+	Type            int    `json:"type"`   // 1-微信用户 2-企业微信用户
+	Gender          int    `json:"gender"` // 0-未知 1-男性 2-女性
+	UnionID         string `json:"unionid"`
+	ExternalProfile any    `json:"external_profile,omitempty"`
+}
+
+// FollowTag 跟进成员为客户打的标签
+type FollowTag struct {
+	GroupName string `json:"group_name"`
+	TagName   string `json:"tag_name"`
+	TagID     string `json:"tag_id"`
+	Type      int    `json:"type"` // 1-企业设置 2-用户自定义 3-规则组标签
+}
+
+// FollowUser 客户的跟进成员
+type FollowUser struct {
+	UserID         string       `json:"userid"`
+	Remark         string       `json:"remark"`
+	Description    string       `json:"description"`
+	CreateTime     int64        `json:"createtime"`
+	Tags           []*FollowTag `json:"tags"`
+	TagID          []string     `json:"tag_id"` // 批量获取时返回
+	RemarkCorpName string       `json:"remark_corp_name"`
+	RemarkMobiles  []string     `json:"remark_mobiles"`
+	OperUserID     string       `json:"oper_userid"`
+	AddWay         int          `json:"add_way"`
+	State          string       `json:"state"`
+}
+
+// ExternalContactInfo 客户详情
+type ExternalContactInfo struct {
+	ExternalContact *ExternalContact `json:"external_contact"`
+	FollowUser      []*FollowUser    `json:"follow_user"`
+}
+
+// ExternalContactDetail 批量获取的客户详情(每条对应一个跟进成员)
+type ExternalContactDetail struct {
+	ExternalContact *ExternalContact `json:"external_contact"`
+	FollowInfo      *FollowUser      `json:"follow_info"`
+}
+
+// ExternalRemark 客户备注信息
+type ExternalRemark struct {
+	UserID           string   `json:"userid"`
+	ExternalUserID   string   `json:"external_userid"`
+	Remark           string   `json:"remark,omitempty"`
+	Description      string   `json:"description,omitempty"`
+	RemarkCompany    string   `json:"remark_company,omitempty"`
+	RemarkMobiles    []string `json:"remark_mobiles,omitempty"`
+	RemarkPicMediaID string   `json:"remark_pic_mediaid,omitempty"`
+}
+
+// ExternalTag 企业客户标签
+type ExternalTag struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	CreateTime int64  `json:"create_time"`
+	Order      int64  `json:"order"`
+	Deleted    bool   `json:"deleted"`
+}
+
+// ExternalTagGroup 企业客户标签组
+type ExternalTagGroup struct {
+	GroupID    string         `json:"group_id"`
+	GroupName  string         `json:"group_name"`
+	CreateTime int64          `json:"create_time"`
+	Order      int64          `json:"order"`
+	Deleted    bool           `json:"deleted"`
+	Tag        []*ExternalTag `json:"tag"`
+}
+
+// ContactWay 联系我(渠道活码)配置
+type ContactWay struct {
+	ConfigID      string   `json:"config_id,omitempty"`
+	Type          int      `json:"type"`  // 1-单人 2-多人
+	Scene         int      `json:"scene"` // 1-在小程序中联系 2-通过二维码联系
+	Style         int      `json:"style,omitempty"`
+	Remark        string   `json:"remark,omitempty"`
+	SkipVerify    bool     `json:"skip_verify"`
+	State         string   `json:"state,omitempty"` // 最长30个字符，会在添加客户事件中回传
+	User          []string `json:"user,omitempty"`
+	Party         []int64  `json:"party,omitempty"`
+	IsTemp        bool     `json:"is_temp,omitempty"`
+	ExpiresIn     int64    `json:"expires_in,omitempty"`
+	ChatExpiresIn int64    `json:"chat_expires_in,omitempty"`
+	UnionID       string   `json:"unionid,omitempty"`
+	QRCode        string   `json:"qr_code,omitempty"`
+}
+
+// WelcomeImage 欢迎语图片附件
+type WelcomeImage struct {
+	MediaID string `json:"media_id,omitempty"`
+	PicURL  string `json:"pic_url,omitempty"`
+}
+
+// WelcomeLink 欢迎语图文附件
+type WelcomeLink struct {
+	Title  string `json:"title"`
+	PicURL string `json:"picurl,omitempty"`
+	Desc   string `json:"desc,omitempty"`
+	URL    string `json:"url"`
+}
+
+// WelcomeMiniProgram 欢迎语小程序附件
+type WelcomeMiniProgram struct {
+	Title      string `json:"title"`
+	PicMediaID string `json:"pic_media_id"`
+	AppID      string `json:"appid"`
+	Page       string `json:"page"`
+}
+
+// WelcomeAttachment 欢迎语附件
+type WelcomeAttachment struct {
+	MsgType     string              `json:"msgtype"` // image、link、miniprogram、video、file
+	Image       *WelcomeImage       `json:"image,omitempty"`
+	Link        *WelcomeLink        `json:"link,omitempty"`
+	MiniProgram *WelcomeMiniProgram `json:"miniprogram,omitempty"`
+	Video       *CorpMedia          `json:"video,omitempty"`
+	File        *CorpMedia          `json:"file,omitempty"`
+}
+
+// WelcomeMsg 新客户欢迎语
+type WelcomeMsg struct {
+	Text        *CorpText            `json:"text,omitempty"`
+	Attachments []*WelcomeAttachment `json:"attachments,omitempty"` // 最多9个
+}
+
+// GroupChatFilter 客户群列表过滤条件
+type GroupChatFilter struct {
+	StatusFilter int      // 0-所有 1-离职待继承 2-离职继承中 3-离职继承完成
+	OwnerUserIDs []string // 群主过滤
+}
+
+// GroupChatStatus 客户群ID及状态
+type GroupChatStatus struct {
+	ChatID string `json:"chat_id"`
+	Status int    `json:"status"`
+}
+
+// ChatUser 群成员ID(邀请者、群管理员)
+type ChatUser struct {
+	UserID string `json:"userid"`
+}
+
+// GroupChatMember 客户群成员
+type GroupChatMember struct {
+	UserID        string    `json:"userid"`
+	Type          int       `json:"type"` // 1-企业成员 2-外部联系人
+	JoinTime      int64     `json:"join_time"`
+	JoinScene     int       `json:"join_scene"`
+	Invitor       *ChatUser `json:"invitor,omitempty"`
+	GroupNickname string    `json:"group_nickname"`
+	Name          string    `json:"name"`
+	UnionID       string    `json:"unionid"`
+}
+
+// GroupChat 客户群详情
+type GroupChat struct {
+	ChatID        string             `json:"chat_id"`
+	Name          string             `json:"name"`
+	Owner         string             `json:"owner"`
+	CreateTime    int64              `json:"create_time"`
+	Notice        string             `json:"notice"`
+	MemberList    []*GroupChatMember `json:"member_list"`
+	AdminList     []*ChatUser        `json:"admin_list"`
+	MemberVersion string             `json:"member_version"`
+}
+
+// GetFollowUsers 获取配置了客户联系功能的成员列表
+// [参考](https://developer.work.weixin.qq.com/document/path/92571)
+func (c *Corp) GetFollowUsers(ctx context.Context) ([]string, error) {
+	ret, err := c.GetJSON(ctx, "/cgi-bin/externalcontact/get_follow_user_list", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0)
+	if err = unmarshalResult(ret.Get("follow_user"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListExternalContacts 获取成员添加的客户列表
+// [参考](https://developer.work.weixin.qq.com/document/path/92113)
+func (c *Corp) ListExternalContacts(ctx context.Context, userid string) ([]string, error) {
+	query := url.Values{}
+	query.Set("userid", userid)
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/externalcontact/list", query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, 0)
+	if err = unmarshalResult(ret.Get("external_userid"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetExternalContact 获取客户详情(跟进成员超过500人时仅返回前500个)
+// [参考](https://developer.work.weixin.qq.com/document/path/92114)
+func (c *Corp) GetExternalContact(ctx context.Context, externalUserID string) (*ExternalContactInfo, error) {
+	query := url.Values{}
+	query.Set("external_userid", externalUserID)
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/externalcontact/get", query)
+	if err != nil {
+		return nil, err
+	}
+
+	info := new(ExternalContactInfo)
+	if err = unmarshalResult(ret, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// BatchGetExternalContacts 批量获取客户详情，limit 最大100
+// [参考](https://developer.work.weixin.qq.com/document/path/92994)
+func (c *Corp) BatchGetExternalContacts(ctx context.Context, userids []string, cursor string, limit int) ([]*ExternalContactDetail, string, error) {
+	if len(userids) == 0 || len(userids) > 100 {
+		return nil, "", errors.New("userid_list must be between 1 and 100")
+	}
+
+	params := lib.X{"userid_list": userids}
+	if len(cursor) != 0 {
+		params["cursor"] = cursor
+	}
+	if limit > 0 {
+		params["limit"] = limit
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/batch/get_by_user", params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list := make([]*ExternalContactDetail, 0)
+	if err = unmarshalResult(ret.Get("external_contact_list"), &list); err != nil {
+		return nil, "", err
+	}
+	return list, ret.Get("next_cursor").String(), nil
+}
+
+// ExternalContactIterator 批量获取客户详情的迭代器
+// [参考](https://developer.work.weixin.qq.com/document/path/92994)
+func (c *Corp) ExternalContactIterator(userids []string, limit int) *Iterator[*ExternalContactDetail] {
+	return NewIterator(func(ctx context.Context, cursor string) ([]*ExternalContactDetail, string, error) {
+		return c.BatchGetExternalContacts(ctx, userids, cursor, limit)
+	}, "")
+}
+
+// RemarkExternalContact 修改客户备注信息
+// [参考](https://developer.work.weixin.qq.com/document/path/92115)
+func (c *Corp) RemarkExternalContact(ctx context.Context, remark *ExternalRemark) error {
+	params, err := lib.ToX(remark)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.PostJSON(ctx, "/cgi-bin/externalcontact/remark", params)
+	return err
+}
+
+// GetExternalTags 获取企业标签库，tagIDs 和 groupIDs 均为空时返回所有标签
+// [参考](https://developer.work.weixin.qq.com/document/path/92117)
+func (c *Corp) GetExternalTags(ctx context.Context, tagIDs, groupIDs []string) ([]*ExternalTagGroup, error) {
+	params := lib.X{}
+	if len(tagIDs) != 0 {
+		params["tag_id"] = tagIDs
+	}
+	if len(groupIDs) != 0 {
+		params["group_id"] = groupIDs
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/get_corp_tag_list", params)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*ExternalTagGroup, 0)
+	if err = unmarshalResult(ret.Get("tag_group"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// MarkExternalTags 编辑客户企业标签
+// [参考](https://developer.work.weixin.qq.com/document/path/92118)
+func (c *Corp) MarkExternalTags(ctx context.Context, userid, externalUserID string, addTags, removeTags []string) error {
+	if len(addTags) == 0 && len(removeTags) == 0 {
+		return errors.New("add_tag and remove_tag cannot be empty at the same time")
+	}
+
+	params := lib.X{
+		"userid":          userid,
+		"external_userid": externalUserID,
+	}
+	if len(addTags) != 0 {
+		params["add_tag"] = addTags
+	}
+	if len(removeTags) != 0 {
+		params["remove_tag"] = removeTags
+	}
+
+	_, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/mark_tag", params)
+	return err
+}
+
+// AddContactWay 配置客户联系「联系我」方式，返回 config_id 和 qr_code
+// [参考](https://developer.work.weixin.qq.com/document/path/92228)
+func (c *Corp) AddContactWay(ctx context.Context, way *ContactWay) (string, string, error) {
+	if len([]rune(way.State)) > 30 {
+		return "", "", errors.New("state exceeds 30 characters")
+	}
+
+	params, err := lib.ToX(way)
+	if err != nil {
+		return "", "", err
+	}
+	delete(params, "config_id")
+	delete(params, "qr_code")
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/add_contact_way", params)
+	if err != nil {
+		return "", "", err
+	}
+	return ret.Get("config_id").String(), ret.Get("qr_code").String(), nil
+}
+
+// GetContactWay 获取企业已配置的「联系我」方式
+// [参考](https://developer.work.weixin.qq.com/document/path/92228)
+func (c *Corp) GetContactWay(ctx context.Context, configID string) (*ContactWay, error) {
+	ret, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/get_contact_way", lib.X{"config_id": configID})
+	if err != nil {
+		return nil, err
+	}
+
+	way := new(ContactWay)
+	if err = unmarshalResult(ret.Get("contact_way"), way); err != nil {
+		return nil, err
+	}
+	return way, nil
+}
+
+// DelContactWay 删除企业已配置的「联系我」方式
+// [参考](https://developer.work.weixin.qq.com/document/path/92228)
+func (c *Corp) DelContactWay(ctx context.Context, configID string) error {
+	_, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/del_contact_way", lib.X{"config_id": configID})
+	return err
+}
+
+// SendWelcomeMsg 发送新客户欢迎语，welcomeCode 来自添加客户事件(20秒内有效，只能使用一次)
+// [参考](https://developer.work.weixin.qq.com/document/path/92137)
+func (c *Corp) SendWelcomeMsg(ctx context.Context, welcomeCode string, msg *WelcomeMsg) error {
+	if msg.Text == nil && len(msg.Attachments) == 0 {
+		return errors.New("text and attachments cannot be empty at the same time")
+	}
+	if len(msg.Attachments) > 9 {
+		return errors.New("attachments exceeds 9")
+	}
+
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return err
+	}
+	params["welcome_code"] = welcomeCode
+
+	_, err = c.PostJSON(ctx, "/cgi-bin/externalcontact/send_welcome_msg", params)
+	return err
+}
+
+// ListGroupChats 获取客户群列表，limit 最大1000
+// [参考](https://developer.work.weixin.qq.com/document/path/92120)
+func (c *Corp) ListGroupChats(ctx context.Context, filter *GroupChatFilter, cursor string, limit int) ([]*GroupChatStatus, string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	params := lib.X{"limit": limit}
+	if len(cursor) != 0 {
+		params["cursor"] = cursor
+	}
+	if filter != nil {
+		params["status_filter"] = filter.StatusFilter
+		if len(filter.OwnerUserIDs) != 0 {
+			params["owner_filter"] = lib.X{"userid_list": filter.OwnerUserIDs}
+		}
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/groupchat/list", params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	list := make([]*GroupChatStatus, 0)
+	if err = unmarshalResult(ret.Get("group_chat_list"), &list); err != nil {
+		return nil, "", err
+	}
+	return list, ret.Get("next_cursor").String(), nil
+}
+
+// GroupChatIterator 客户群列表迭代器
+// [参考](https://developer.work.weixin.qq.com/document/path/92120)
+func (c *Corp) GroupChatIterator(filter *GroupChatFilter, limit int) *Iterator[*GroupChatStatus] {
+	return NewIterator(func(ctx context.Context, cursor string) ([]*GroupChatStatus, string, error) {
+		return c.ListGroupChats(ctx, filter, cursor, limit)
+	}, "")
+}
+
+// GetGroupChat 获取客户群详情，needName 表示是否需要返回群成员的名字
+// [参考](https://developer.work.weixin.qq.com/document/path/92122)
+func (c *Corp) GetGroupChat(ctx context.Context, chatID string, needName bool) (*GroupChat, error) {
+	params := lib.X{"chat_id": chatID}
+	if needName {
+		params["need_name"] = 1
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/externalcontact/groupchat/get", params)
+	if err != nil {
+		return nil, err
+	}
+
+	chat := new(GroupChat)
+	if err = unmarshalResult(ret.Get("group_chat"), chat); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+// ExternalContactEvent 客户变更事件
+type ExternalContactEvent struct {
+	ToUserName     string
+	FromUserName   string
+	CreateTime     int64
+	ChangeType     string
+	UserID         string
+	ExternalUserID string
+	State          string // 添加此用户的「联系我」方式配置的state参数
+	WelcomeCode    string // 欢迎语code，可用于发送欢迎语
+	Source         string // 删除客户的操作来源，DELETE_BY_TRANSFER 表示由于成员在职转接而删除
+	FailReason     string // 接替失败的原因
+}
+
+// ParseExternalContactEvent 解析客户变更事件(已通过DecodeEventMsg解析)
+// [参考](https://developer.work.weixin.qq.com/document/path/92130)
+func ParseExternalContactEvent(msg value.V) (*ExternalContactEvent, error) {
+	if event := msg.Get("Event"); event != EventChangeExternalContact {
+		return nil, fmt.Errorf("unexpected event: %s", event)
+	}
+
+	createTime, _ := strconv.ParseInt(msg.Get("CreateTime"), 10, 64)
+
+	e := &ExternalContactEvent{
+		ToUserName:     msg.Get("ToUserName"),
+		FromUserName:   msg.Get("FromUserName"),
+		CreateTime:     createTime,
+		ChangeType:     msg.Get("ChangeType"),
+		UserID:         msg.Get("UserID"),
+		ExternalUserID: msg.Get("ExternalUserID"),
+		State:          msg.Get("State"),
+		WelcomeCode:    msg.Get("WelcomeCode"),
+		Source:         msg.Get("Source"),
+		FailReason:     msg.Get("FailReason"),
+	}
+	return e, nil
+}
+
+// ExternalChatEvent 客户群变更事件
+type ExternalChatEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	ChangeType   string
+	ChatID       string
+	UpdateDetail string // add_member、del_member、change_owner、change_name、change_notice
+	JoinScene    int
+	QuitScene    int
+	MemChangeCnt int
+}
+
+// ParseExternalChatEvent 解析客户群变更事件(已通过DecodeEventMsg解析)
+// [参考](https://developer.work.weixin.qq.com/document/path/92130)
+func ParseExternalChatEvent(msg value.V) (*ExternalChatEvent, error) {
+	if event := msg.Get("Event"); event != EventChangeExternalChat {
+		return nil, fmt.Errorf("unexpected event: %s", event)
+	}
+
+	createTime, _ := strconv.ParseInt(msg.Get("CreateTime"), 10, 64)
+	joinScene, _ := strconv.Atoi(msg.Get("JoinScene"))
+	quitScene, _ := strconv.Atoi(msg.Get("QuitScene"))
+	memChangeCnt, _ := strconv.Atoi(msg.Get("MemChangeCnt"))
+
+	e := &ExternalChatEvent{
+		ToUserName:   msg.Get("ToUserName"),
+		FromUserName: msg.Get("FromUserName"),
+		CreateTime:   createTime,
+		ChangeType:   msg.Get("ChangeType"),
+		ChatID:       msg.Get("ChatId"),
+		UpdateDetail: msg.Get("UpdateDetail"),
+		JoinScene:    joinScene,
+		QuitScene:    quitScene,
+		MemChangeCnt: memChangeCnt,
+	}
+	return e, nil
+}
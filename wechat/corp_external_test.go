@@ -0,0 +1,66 @@
+package wechat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+func TestExternalContactIterator(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "zhangsan", gjson.GetBytes(body, "userid_list.0").String())
+
+		if gjson.GetBytes(body, "cursor").String() == "" {
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","external_contact_list":[{"external_contact":{"external_userid":"woAJ2GCAAAXtWyujaWJHDDGi0mACAAA","name":"李四","type":1},"follow_info":{"userid":"zhangsan","remark":"李部长","tag_id":["etAJ2GCAAAXtWyujaWJHDDGi0mACHAAA"],"state":"外联二维码1"}}],"next_cursor":"r9FqSqsI8fgNbHLHE5QoCP50UIg2cFQbfma3l2QsmwI"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","external_contact_list":[{"external_contact":{"external_userid":"woAJ2GCAAAXtWyujaWJHDDGi0mACBBB","name":"王五","type":2,"corp_name":"腾讯"},"follow_info":{"userid":"zhangsan"}}],"next_cursor":""}`))
+	}))
+	defer srv.Close()
+
+	corp := NewCorp("ww4f4bc4dec97d474b", "secret")
+	corp.host = srv.URL
+	corp.token.Store("ACCESS_TOKEN")
+
+	list, err := corp.ExternalContactIterator([]string{"zhangsan"}, 1).All(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "李部长", list[0].FollowInfo.Remark)
+	assert.Equal(t, []string{"etAJ2GCAAAXtWyujaWJHDDGi0mACHAAA"}, list[0].FollowInfo.TagID)
+	assert.Equal(t, "腾讯", list[1].ExternalContact.CorpName)
+}
+
+func TestParseExternalContactEvent(t *testing.T) {
+	e, err := ParseExternalContactEvent(value.V{
+		"ToUserName":     "toUser",
+		"FromUserName":   "sys",
+		"CreateTime":     "1403610513",
+		"MsgType":        "event",
+		"Event":          "change_external_contact",
+		"ChangeType":     "add_external_contact",
+		"UserID":         "zhangsan",
+		"ExternalUserID": "woAJ2GCAAAXtWyujaWJHDDGi0mACAAA",
+		"State":          "teststate",
+		"WelcomeCode":    "WELCOMECODE",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ExternalAddContact, e.ChangeType)
+	assert.Equal(t, int64(1403610513), e.CreateTime)
+	assert.Equal(t, "WELCOMECODE", e.WelcomeCode)
+
+	_, err = ParseExternalContactEvent(value.V{"Event": "change_contact"})
+	assert.NotNil(t, err)
+
+	ce, err := ParseExternalChatEvent(value.V{"Event": "change_external_chat", "ChatId": "CHAT_ID", "ChangeType": "update", "UpdateDetail": "add_member", "JoinScene": "1", "MemChangeCnt": "10"})
+	assert.Nil(t, err)
+	assert.Equal(t, "CHAT_ID", ce.ChatID)
+	assert.Equal(t, 10, ce.MemChangeCnt)
+}
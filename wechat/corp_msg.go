@@ -0,0 +1,424 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// 企业微信应用消息类型
+const (
+	CorpMsgText              = "text"
+	CorpMsgImage             = "image"
+	CorpMsgFile              = "file"
+	CorpMsgTextCard          = "textcard"
+	CorpMsgNews              = "news"
+	CorpMsgMPNews            = "mpnews"
+	CorpMsgMarkdown          = "markdown"
+	CorpMsgMiniProgramNotice = "miniprogram_notice"
+	CorpMsgTemplateCard      = "template_card"
+)
+
+// CorpText 文本消息
+type CorpText struct {
+	Content string `json:"content"`
+}
+
+// CorpMedia 媒体消息(图片、文件)
+type CorpMedia struct {
+	MediaID string `json:"media_id"`
+}
+
+// CorpTextCard 文本卡片消息
+type CorpTextCard struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	BtnTxt      string `json:"btntxt,omitempty"`
+}
+
+// CorpArticle 图文消息文章
+type CorpArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	PicURL      string `json:"picurl,omitempty"`
+	AppID       string `json:"appid,omitempty"`
+	PagePath    string `json:"pagepath,omitempty"`
+}
+
+// CorpNews 图文消息
+type CorpNews struct {
+	Articles []*CorpArticle `json:"articles"`
+}
+
+// CorpMPArticle 图文消息(mpnews)文章
+type CorpMPArticle struct {
+	Title            string `json:"title"`
+	ThumbMediaID     string `json:"thumb_media_id"`
+	Author           string `json:"author,omitempty"`
+	ContentSourceURL string `json:"content_source_url,omitempty"`
+	Content          string `json:"content"`
+	Digest           string `json:"digest,omitempty"`
+}
+
+// CorpMPNews 图文消息(mpnews)
+type CorpMPNews struct {
+	Articles []*CorpMPArticle `json:"articles"`
+}
+
+// CorpKV 键值对
+type CorpKV struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// CorpMiniProgramNotice 小程序通知消息
+type CorpMiniProgramNotice struct {
+	AppID             string    `json:"appid"`
+	Page              string    `json:"page,omitempty"`
+	Title             string    `json:"title"`
+	Description       string    `json:"description,omitempty"`
+	EmphasisFirstItem bool      `json:"emphasis_first_item,omitempty"`
+	ContentItem       []*CorpKV `json:"content_item,omitempty"`
+}
+
+// CardSource 模板卡片来源
+type CardSource struct {
+	IconURL   string `json:"icon_url,omitempty"`
+	Desc      string `json:"desc,omitempty"`
+	DescColor int    `json:"desc_color,omitempty"`
+}
+
+// CardTitle 模板卡片标题
+type CardTitle struct {
+	Title string `json:"title,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// CardQuoteArea 模板卡片引用区域
+type CardQuoteArea struct {
+	Type      int    `json:"type,omitempty"`
+	URL       string `json:"url,omitempty"`
+	AppID     string `json:"appid,omitempty"`
+	PagePath  string `json:"pagepath,omitempty"`
+	Title     string `json:"title,omitempty"`
+	QuoteText string `json:"quote_text,omitempty"`
+}
+
+// CardHorizontalContent 模板卡片二级标题+文本列表
+type CardHorizontalContent struct {
+	Type    int    `json:"type,omitempty"` // 1-跳转url 2-下载附件 3-点击跳转成员详情
+	KeyName string `json:"keyname"`
+	Value   string `json:"value,omitempty"`
+	URL     string `json:"url,omitempty"`
+	MediaID string `json:"media_id,omitempty"`
+	UserID  string `json:"userid,omitempty"`
+}
+
+// CardJump 模板卡片跳转指引
+type CardJump struct {
+	Type     int    `json:"type,omitempty"` // 1-跳转url 2-跳转小程序
+	Title    string `json:"title"`
+	URL      string `json:"url,omitempty"`
+	AppID    string `json:"appid,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// CardAction 模板卡片整体点击跳转
+type CardAction struct {
+	Type     int    `json:"type"` // 1-跳转url 2-跳转小程序
+	URL      string `json:"url,omitempty"`
+	AppID    string `json:"appid,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// CardImage 模板卡片图片
+type CardImage struct {
+	URL         string  `json:"url"`
+	AspectRatio float64 `json:"aspect_ratio,omitempty"`
+}
+
+// CardButton 模板卡片按钮
+type CardButton struct {
+	Type  int    `json:"type,omitempty"` // 0-回调事件 1-跳转url
+	Text  string `json:"text"`
+	Style int    `json:"style,omitempty"`
+	Key   string `json:"key,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// TemplateCard 模板卡片消息
+type TemplateCard struct {
+	CardType              string                   `json:"card_type"` // text_notice、news_notice、button_interaction、vote_interaction、multiple_interaction
+	Source                *CardSource              `json:"source,omitempty"`
+	MainTitle             *CardTitle               `json:"main_title,omitempty"`
+	EmphasisContent       *CardTitle               `json:"emphasis_content,omitempty"`
+	QuoteArea             *CardQuoteArea           `json:"quote_area,omitempty"`
+	SubTitleText          string                   `json:"sub_title_text,omitempty"`
+	HorizontalContentList []*CardHorizontalContent `json:"horizontal_content_list,omitempty"`
+	JumpList              []*CardJump              `json:"jump_list,omitempty"`
+	CardAction            *CardAction              `json:"card_action,omitempty"`
+	CardImage             *CardImage               `json:"card_image,omitempty"`
+	TaskID                string                   `json:"task_id,omitempty"` // 交互类卡片必填
+	ButtonList            []*CardButton            `json:"button_list,omitempty"`
+}
+
+// CorpMsg 企业微信应用消息
+type CorpMsg struct {
+	ToUser                 string                 `json:"touser,omitempty"`
+	ToParty                string                 `json:"toparty,omitempty"`
+	ToTag                  string                 `json:"totag,omitempty"`
+	MsgType                string                 `json:"msgtype"`
+	AgentID                int64                  `json:"agentid,omitempty"`
+	Text                   *CorpText              `json:"text,omitempty"`
+	Image                  *CorpMedia             `json:"image,omitempty"`
+	File                   *CorpMedia             `json:"file,omitempty"`
+	TextCard               *CorpTextCard          `json:"textcard,omitempty"`
+	News                   *CorpNews              `json:"news,omitempty"`
+	MPNews                 *CorpMPNews            `json:"mpnews,omitempty"`
+	Markdown               *CorpText              `json:"markdown,omitempty"`
+	MiniProgramNotice      *CorpMiniProgramNotice `json:"miniprogram_notice,omitempty"`
+	TemplateCard           *TemplateCard          `json:"template_card,omitempty"`
+	Safe                   int                    `json:"safe,omitempty"`
+	EnableIDTrans          int                    `json:"enable_id_trans,omitempty"`
+	EnableDuplicateCheck   int                    `json:"enable_duplicate_check,omitempty"`
+	DuplicateCheckInterval int                    `json:"duplicate_check_interval,omitempty"`
+}
+
+// ToUsers 设置接收成员(最多1000个)，"@all" 表示全部成员
+func (m *CorpMsg) ToUsers(userids ...string) *CorpMsg {
+	m.ToUser = strings.Join(userids, "|")
+	return m
+}
+
+// ToParties 设置接收部门(最多100个)
+func (m *CorpMsg) ToParties(partyids ...string) *CorpMsg {
+	m.ToParty = strings.Join(partyids, "|")
+	return m
+}
+
+// ToTags 设置接收标签(最多100个)
+func (m *CorpMsg) ToTags(tagids ...string) *CorpMsg {
+	m.ToTag = strings.Join(tagids, "|")
+	return m
+}
+
+// ToAll 发送给应用可见范围内的全部成员
+func (m *CorpMsg) ToAll() *CorpMsg {
+	m.ToUser = "@all"
+	return m
+}
+
+// SetSafe 设置为保密消息
+func (m *CorpMsg) SetSafe() *CorpMsg {
+	m.Safe = 1
+	return m
+}
+
+// SetIDTrans 开启id转译
+func (m *CorpMsg) SetIDTrans() *CorpMsg {
+	m.EnableIDTrans = 1
+	return m
+}
+
+// SetDuplicateCheck 开启重复消息检查，interval 为检查的时间间隔(秒，默认1800，最大4小时)
+func (m *CorpMsg) SetDuplicateCheck(interval int) *CorpMsg {
+	m.EnableDuplicateCheck = 1
+	m.DuplicateCheckInterval = interval
+	return m
+}
+
+// NewCorpTextMsg 文本消息
+func NewCorpTextMsg(content string) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgText, Text: &CorpText{Content: content}}
+}
+
+// NewCorpImageMsg 图片消息
+func NewCorpImageMsg(mediaID string) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgImage, Image: &CorpMedia{MediaID: mediaID}}
+}
+
+// NewCorpFileMsg 文件消息
+func NewCorpFileMsg(mediaID string) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgFile, File: &CorpMedia{MediaID: mediaID}}
+}
+
+// NewCorpTextCardMsg 文本卡片消息
+func NewCorpTextCardMsg(card *CorpTextCard) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgTextCard, TextCard: card}
+}
+
+// NewCorpNewsMsg 图文消息(1~8条)
+func NewCorpNewsMsg(articles ...*CorpArticle) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgNews, News: &CorpNews{Articles: articles}}
+}
+
+// NewCorpMPNewsMsg 图文消息(mpnews，1~8条)
+func NewCorpMPNewsMsg(articles ...*CorpMPArticle) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgMPNews, MPNews: &CorpMPNews{Articles: articles}}
+}
+
+// NewCorpMarkdownMsg markdown消息
+func NewCorpMarkdownMsg(content string) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgMarkdown, Markdown: &CorpText{Content: content}}
+}
+
+// NewCorpMiniProgramNoticeMsg 小程序通知消息
+func NewCorpMiniProgramNoticeMsg(notice *CorpMiniProgramNotice) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgMiniProgramNotice, MiniProgramNotice: notice}
+}
+
+// NewCorpTemplateCardMsg 模板卡片消息
+func NewCorpTemplateCardMsg(card *TemplateCard) *CorpMsg {
+	return &CorpMsg{MsgType: CorpMsgTemplateCard, TemplateCard: card}
+}
+
+// CorpMsgResult 应用消息发送结果
+type CorpMsgResult struct {
+	MsgID          string   `json:"msgid"`
+	InvalidUser    []string `json:"invaliduser"`
+	InvalidParty   []string `json:"invalidparty"`
+	InvalidTag     []string `json:"invalidtag"`
+	UnlicensedUser []string `json:"unlicenseduser"`
+	ResponseCode   string   `json:"response_code"` // 仅消息类型为“按钮交互型”，“投票选择型”和“多项选择型”的模板卡片消息返回，用于更新卡片
+}
+
+// HasInvalid 是否存在无效或无权限的接收人
+func (r *CorpMsgResult) HasInvalid() bool {
+	return len(r.InvalidUser)+len(r.InvalidParty)+len(r.InvalidTag)+len(r.UnlicensedUser) != 0
+}
+
+func splitPipe(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(s, "|")
+}
+
+// SendMsg 发送应用消息；部分接收人无效时不会返回错误，需检查 CorpMsgResult
+// [参考](https://developer.work.weixin.qq.com/document/path/90236)
+func (c *Corp) SendMsg(ctx context.Context, agentID int64, msg *CorpMsg) (*CorpMsgResult, error) {
+	if len(msg.ToUser) == 0 && len(msg.ToParty) == 0 && len(msg.ToTag) == 0 {
+		return nil, errors.New("touser, toparty, totag cannot be empty at the same time")
+	}
+
+	msg.AgentID = agentID
+
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/message/send", params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CorpMsgResult{
+		MsgID:          ret.Get("msgid").String(),
+		InvalidUser:    splitPipe(ret.Get("invaliduser").String()),
+		InvalidParty:   splitPipe(ret.Get("invalidparty").String()),
+		InvalidTag:     splitPipe(ret.Get("invalidtag").String()),
+		UnlicensedUser: splitPipe(ret.Get("unlicenseduser").String()),
+		ResponseCode:   ret.Get("response_code").String(),
+	}
+	return result, nil
+}
+
+// RecallMsg 撤回24小时内的应用消息
+// [参考](https://developer.work.weixin.qq.com/document/path/94867)
+func (c *Corp) RecallMsg(ctx context.Context, msgID string) error {
+	_, err := c.PostJSON(ctx, "/cgi-bin/message/recall", lib.X{"msgid": msgID})
+	return err
+}
+
+// AppChat 应用群聊
+type AppChat struct {
+	ChatID   string   `json:"chatid,omitempty"`
+	Name     string   `json:"name"`
+	Owner    string   `json:"owner,omitempty"`
+	UserList []string `json:"userlist"`
+}
+
+// CreateAppChat 创建群聊会话，返回群聊ID
+// [参考](https://developer.work.weixin.qq.com/document/path/90245)
+func (c *Corp) CreateAppChat(ctx context.Context, chat *AppChat) (string, error) {
+	if len(chat.UserList) < 2 {
+		return "", errors.New("userlist requires at least 2 members")
+	}
+
+	params, err := lib.ToX(chat)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := c.PostJSON(ctx, "/cgi-bin/appchat/create", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("chatid").String(), nil
+}
+
+// UpdateAppChat 修改群聊会话，name、owner 为空时不修改
+// [参考](https://developer.work.weixin.qq.com/document/path/98913)
+func (c *Corp) UpdateAppChat(ctx context.Context, chatID, name, owner string, addUsers, delUsers []string) error {
+	params := lib.X{"chatid": chatID}
+	if len(name) != 0 {
+		params["name"] = name
+	}
+	if len(owner) != 0 {
+		params["owner"] = owner
+	}
+	if len(addUsers) != 0 {
+		params["add_user_list"] = addUsers
+	}
+	if len(delUsers) != 0 {
+		params["del_user_list"] = delUsers
+	}
+
+	_, err := c.PostJSON(ctx, "/cgi-bin/appchat/update", params)
+	return err
+}
+
+// GetAppChat 获取群聊会话
+// [参考](https://developer.work.weixin.qq.com/document/path/98914)
+func (c *Corp) GetAppChat(ctx context.Context, chatID string) (*AppChat, error) {
+	query := url.Values{}
+	query.Set("chatid", chatID)
+
+	ret, err := c.GetJSON(ctx, "/cgi-bin/appchat/get", query)
+	if err != nil {
+		return nil, err
+	}
+
+	chat := new(AppChat)
+	if err = unmarshalResult(ret.Get("chat_info"), chat); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+// SendAppChatMsg 发送群聊消息(不支持 miniprogram_notice、template_card，接收人设置将被忽略)
+// [参考](https://developer.work.weixin.qq.com/document/path/90248)
+func (c *Corp) SendAppChatMsg(ctx context.Context, chatID string, msg *CorpMsg) error {
+	switch msg.MsgType {
+	case CorpMsgMiniProgramNotice, CorpMsgTemplateCard:
+		return errors.New("appchat does not support msgtype: " + msg.MsgType)
+	}
+
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return err
+	}
+	for _, k := range []string{"touser", "toparty", "totag", "agentid", "enable_id_trans", "enable_duplicate_check", "duplicate_check_interval"} {
+		delete(params, k)
+	}
+	params["chatid"] = chatID
+
+	_, err = c.PostJSON(ctx, "/cgi-bin/appchat/send", params)
+	return err
+}
@@ -0,0 +1,59 @@
+package wechat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestCorpSendMsg(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ret := gjson.ParseBytes(body)
+
+		switch r.URL.Path {
+		case "/cgi-bin/message/send":
+			assert.Equal(t, "UserID1|UserID2", ret.Get("touser").String())
+			assert.Equal(t, int64(1000002), ret.Get("agentid").Int())
+			assert.Equal(t, "textcard", ret.Get("msgtype").String())
+			assert.Equal(t, "领奖通知", ret.Get("textcard.title").String())
+			assert.Equal(t, int64(1), ret.Get("enable_duplicate_check").Int())
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","invaliduser":"UserID2","invalidparty":"","invalidtag":"","unlicenseduser":"UserID3|UserID4","msgid":"xxxx"}`))
+		case "/cgi-bin/appchat/send":
+			assert.Equal(t, "CHATID", ret.Get("chatid").String())
+			assert.Equal(t, "**hello**", ret.Get("markdown.content").String())
+			assert.False(t, ret.Get("touser").Exists())
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}
+	}))
+	defer srv.Close()
+
+	corp := NewCorp("ww4f4bc4dec97d474b", "secret")
+	corp.host = srv.URL
+	corp.token.Store("ACCESS_TOKEN")
+
+	msg := NewCorpTextCardMsg(&CorpTextCard{
+		Title:       "领奖通知",
+		Description: "恭喜你抽中iPhone 15一台",
+		URL:         "https://work.weixin.qq.com",
+	}).ToUsers("UserID1", "UserID2").SetDuplicateCheck(1800)
+
+	result, err := corp.SendMsg(context.Background(), 1000002, msg)
+	assert.Nil(t, err)
+	assert.Equal(t, "xxxx", result.MsgID)
+	assert.Equal(t, []string{"UserID2"}, result.InvalidUser)
+	assert.Nil(t, result.InvalidParty)
+	assert.Equal(t, []string{"UserID3", "UserID4"}, result.UnlicensedUser)
+	assert.True(t, result.HasInvalid())
+
+	_, err = corp.SendMsg(context.Background(), 1000002, NewCorpTextMsg("hello"))
+	assert.NotNil(t, err)
+
+	assert.Nil(t, corp.SendAppChatMsg(context.Background(), "CHATID", NewCorpMarkdownMsg("**hello**").ToUsers("UserID1")))
+	assert.NotNil(t, corp.SendAppChatMsg(context.Background(), "CHATID", NewCorpTemplateCardMsg(&TemplateCard{CardType: "text_notice"})))
+}
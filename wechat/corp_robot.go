@@ -0,0 +1,370 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/xhash"
+)
+
+// 群机器人消息类型
+const (
+	RobotMsgText         = "text"
+	RobotMsgMarkdown     = "markdown"
+	RobotMsgImage        = "image"
+	RobotMsgNews         = "news"
+	RobotMsgFile         = "file"
+	RobotMsgVoice        = "voice"
+	RobotMsgTemplateCard = "template_card"
+)
+
+// 群机器人内容限制
+const (
+	RobotTextMaxBytes     = 2048             // 文本内容最长2048个字节
+	RobotMarkdownMaxBytes = 4096             // markdown内容最长4096个字节
+	RobotImageMaxSize     = 2 << 20          // 图片(base64编码前)最大2M
+	RobotNewsMaxArticles  = 8                // 图文最多8条
+	RobotFileMinSize      = 5                // 文件最小5B
+	RobotFileMaxSize      = 20 << 20         // 文件最大20M
+	RobotVoiceMaxSize     = 2 << 20          // 语音(amr)最大2M
+	robotMediaMaxRead     = RobotFileMaxSize // 上传读取的最大长度
+)
+
+// RobotMediaType 群机器人上传的文件类型
+type RobotMediaType string
+
+const (
+	RobotMediaFile  RobotMediaType = "file"
+	RobotMediaVoice RobotMediaType = "voice"
+)
+
+// RobotText 群机器人文本消息
+type RobotText struct {
+	Content             string   `json:"content"`
+	MentionedList       []string `json:"mentioned_list,omitempty"`        // userid列表，"@all" 表示提醒所有人
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"` // 手机号列表，"@all" 表示提醒所有人
+}
+
+// RobotImage 群机器人图片消息
+type RobotImage struct {
+	Base64 string `json:"base64"`
+	MD5    string `json:"md5"`
+}
+
+// RobotNews 群机器人图文消息
+type RobotNews struct {
+	Articles []*CorpArticle `json:"articles"`
+}
+
+// RobotMsg 群机器人消息
+type RobotMsg struct {
+	MsgType      string        `json:"msgtype"`
+	Text         *RobotText    `json:"text,omitempty"`
+	Markdown     *CorpText     `json:"markdown,omitempty"`
+	Image        *RobotImage   `json:"image,omitempty"`
+	News         *RobotNews    `json:"news,omitempty"`
+	File         *CorpMedia    `json:"file,omitempty"`
+	Voice        *CorpMedia    `json:"voice,omitempty"`
+	TemplateCard *TemplateCard `json:"template_card,omitempty"`
+}
+
+// Validate 校验消息内容的长度限制
+func (m *RobotMsg) Validate() error {
+	switch m.MsgType {
+	case RobotMsgText:
+		if m.Text == nil || len(m.Text.Content) == 0 {
+			return errors.New("text content is empty")
+		}
+		if len(m.Text.Content) > RobotTextMaxBytes {
+			return fmt.Errorf("text content exceeds %d bytes", RobotTextMaxBytes)
+		}
+	case RobotMsgMarkdown:
+		if m.Markdown == nil || len(m.Markdown.Content) == 0 {
+			return errors.New("markdown content is empty")
+		}
+		if len(m.Markdown.Content) > RobotMarkdownMaxBytes {
+			return fmt.Errorf("markdown content exceeds %d bytes", RobotMarkdownMaxBytes)
+		}
+	case RobotMsgImage:
+		if m.Image == nil || len(m.Image.Base64) == 0 || len(m.Image.MD5) == 0 {
+			return errors.New("image base64 or md5 is empty")
+		}
+		if base64.StdEncoding.DecodedLen(len(m.Image.Base64)) > RobotImageMaxSize+2 {
+			return fmt.Errorf("image exceeds %d bytes", RobotImageMaxSize)
+		}
+	case RobotMsgNews:
+		if m.News == nil || len(m.News.Articles) == 0 || len(m.News.Articles) > RobotNewsMaxArticles {
+			return fmt.Errorf("news articles must be between 1 and %d", RobotNewsMaxArticles)
+		}
+		for _, v := range m.News.Articles {
+			if len(v.Title) == 0 || len(v.URL) == 0 {
+				return errors.New("news article title and url are required")
+			}
+			if len(v.Title) > 128 || len(v.Description) > 512 {
+				return errors.New("news article title exceeds 128 bytes or description exceeds 512 bytes")
+			}
+		}
+	case RobotMsgFile:
+		if m.File == nil || len(m.File.MediaID) == 0 {
+			return errors.New("file media_id is empty")
+		}
+	case RobotMsgVoice:
+		if m.Voice == nil || len(m.Voice.MediaID) == 0 {
+			return errors.New("voice media_id is empty")
+		}
+	case RobotMsgTemplateCard:
+		if m.TemplateCard == nil || len(m.TemplateCard.CardType) == 0 {
+			return errors.New("template_card card_type is empty")
+		}
+	default:
+		return fmt.Errorf("unsupported msgtype: %s", m.MsgType)
+	}
+	return nil
+}
+
+// NewRobotTextMsg 群机器人文本消息
+func NewRobotTextMsg(content string, mentionedList, mentionedMobileList []string) *RobotMsg {
+	return &RobotMsg{
+		MsgType: RobotMsgText,
+		Text: &RobotText{
+			Content:             content,
+			MentionedList:       mentionedList,
+			MentionedMobileList: mentionedMobileList,
+		},
+	}
+}
+
+// NewRobotMarkdownMsg 群机器人markdown消息
+func NewRobotMarkdownMsg(content string) *RobotMsg {
+	return &RobotMsg{MsgType: RobotMsgMarkdown, Markdown: &CorpText{Content: content}}
+}
+
+// NewRobotImageMsg 群机器人图片消息(jpg、png)，自动计算base64和md5
+func NewRobotImageMsg(data []byte) *RobotMsg {
+	return &RobotMsg{
+		MsgType: RobotMsgImage,
+		Image: &RobotImage{
+			Base64: base64.StdEncoding.EncodeToString(data),
+			MD5:    xhash.MD5(string(data)),
+		},
+	}
+}
+
+// NewRobotNewsMsg 群机器人图文消息(1~8条)
+func NewRobotNewsMsg(articles ...*CorpArticle) *RobotMsg {
+	return &RobotMsg{MsgType: RobotMsgNews, News: &RobotNews{Articles: articles}}
+}
+
+// NewRobotFileMsg 群机器人文件消息，mediaID 通过 UploadMedia 获取
+func NewRobotFileMsg(mediaID string) *RobotMsg {
+	return &RobotMsg{MsgType: RobotMsgFile, File: &CorpMedia{MediaID: mediaID}}
+}
+
+// NewRobotVoiceMsg 群机器人语音消息，mediaID 通过 UploadMedia 获取
+func NewRobotVoiceMsg(mediaID string) *RobotMsg {
+	return &RobotMsg{MsgType: RobotMsgVoice, Voice: &CorpMedia{MediaID: mediaID}}
+}
+
+// NewRobotTemplateCardMsg 群机器人模板卡片消息
+func NewRobotTemplateCardMsg(card *TemplateCard) *RobotMsg {
+	return &RobotMsg{MsgType: RobotMsgTemplateCard, TemplateCard: card}
+}
+
+// Robot 企业微信群机器人
+type Robot struct {
+	host   string
+	key    string
+	client *resty.Client
+	logger func(ctx context.Context, err error, data map[string]string)
+}
+
+// Key 返回webhook的key
+func (r *Robot) Key() string {
+	return r.key
+}
+
+func (r *Robot) url(path string, query url.Values) string {
+	var builder strings.Builder
+
+	builder.WriteString(r.host)
+	if len(path) != 0 && path[0] != '/' {
+		builder.WriteString("/")
+	}
+	builder.WriteString(path)
+	if len(query) != 0 {
+		builder.WriteString("?")
+		builder.WriteString(query.Encode())
+	}
+
+	return builder.String()
+}
+
+// Send 发送群机器人消息(每个机器人发送的消息不能超过20条/分钟)
+// [参考](https://developer.work.weixin.qq.com/document/path/91770)
+func (r *Robot) Send(ctx context.Context, msg *RobotMsg) error {
+	if err := msg.Validate(); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("key", r.key)
+
+	reqURL := r.url("/cgi-bin/webhook/send", query)
+
+	log := lib.NewReqLog(http.MethodPost, reqURL)
+	defer log.Do(ctx, r.logger)
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		log.SetError(err)
+		return err
+	}
+	log.SetReqBody(string(body))
+
+	resp, err := r.client.R().
+		SetContext(ctx).
+		SetHeader(lib.HeaderContentType, lib.ContentJSON).
+		SetBody(body).
+		Post(reqURL)
+	if err != nil {
+		log.SetError(err)
+		return err
+	}
+	log.SetRespHeader(resp.Header())
+	log.SetStatusCode(resp.StatusCode())
+	log.SetRespBody(string(resp.Body()))
+	if !resp.IsSuccess() {
+		return fmt.Errorf("HTTP Request Error, StatusCode = %d", resp.StatusCode())
+	}
+
+	ret := gjson.ParseBytes(resp.Body())
+	if code := ret.Get("errcode").Int(); code != 0 {
+		return fmt.Errorf("%d | %s", code, ret.Get("errmsg").String())
+	}
+	return nil
+}
+
+// UploadMedia 上传文件(文件5B~20M，语音仅支持amr且不超过2M)，返回 media_id (3天内有效)
+// [参考](https://developer.work.weixin.qq.com/document/path/91770)
+func (r *Robot) UploadMedia(ctx context.Context, mediaType RobotMediaType, filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	return r.UploadMediaWithReader(ctx, mediaType, filepath.Base(filePath), f)
+}
+
+// UploadMediaWithReader 上传文件(文件5B~20M，语音仅支持amr且不超过2M)，返回 media_id (3天内有效)
+// [参考](https://developer.work.weixin.qq.com/document/path/91770)
+func (r *Robot) UploadMediaWithReader(ctx context.Context, mediaType RobotMediaType, fileName string, reader io.Reader) (string, error) {
+	b, err := io.ReadAll(io.LimitReader(reader, robotMediaMaxRead+1))
+	if err != nil {
+		return "", err
+	}
+
+	maxSize := RobotFileMaxSize
+	if mediaType == RobotMediaVoice {
+		maxSize = RobotVoiceMaxSize
+	}
+	if len(b) < RobotFileMinSize || len(b) > maxSize {
+		return "", fmt.Errorf("%s size must be between %d and %d bytes", mediaType, RobotFileMinSize, maxSize)
+	}
+
+	query := url.Values{}
+	query.Set("key", r.key)
+	query.Set("type", string(mediaType))
+
+	reqURL := r.url("/cgi-bin/webhook/upload_media", query)
+
+	log := lib.NewReqLog(http.MethodPost, reqURL)
+	defer log.Do(ctx, r.logger)
+
+	resp, err := r.client.R().
+		SetContext(ctx).
+		SetMultipartField("media", fileName, "", bytes.NewReader(b)).
+		Post(reqURL)
+	if err != nil {
+		log.SetError(err)
+		return "", err
+	}
+	log.SetRespHeader(resp.Header())
+	log.SetStatusCode(resp.StatusCode())
+	log.SetRespBody(string(resp.Body()))
+	if !resp.IsSuccess() {
+		return "", fmt.Errorf("HTTP Request Error, StatusCode = %d", resp.StatusCode())
+	}
+
+	ret := gjson.ParseBytes(resp.Body())
+	if code := ret.Get("errcode").Int(); code != 0 {
+		return "", fmt.Errorf("%d | %s", code, ret.Get("errmsg").String())
+	}
+	return ret.Get("media_id").String(), nil
+}
+
+// SendText 发送文本消息(超出长度限制时按字符截断)
+func (r *Robot) SendText(ctx context.Context, content string, mentionedList ...string) error {
+	return r.Send(ctx, NewRobotTextMsg(truncateBytes(content, RobotTextMaxBytes), mentionedList, nil))
+}
+
+// SendMarkdown 发送markdown消息(超出长度限制时按字符截断)
+func (r *Robot) SendMarkdown(ctx context.Context, content string) error {
+	return r.Send(ctx, NewRobotMarkdownMsg(truncateBytes(content, RobotMarkdownMaxBytes)))
+}
+
+// truncateBytes 按字节数截断字符串，不截断多字节字符
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// RobotOption 群机器人设置项
+type RobotOption func(r *Robot)
+
+// WithRobotClient 设置群机器人请求的 HTTP Client
+func WithRobotClient(cli *http.Client) RobotOption {
+	return func(r *Robot) {
+		r.client = resty.NewWithClient(cli)
+	}
+}
+
+// WithRobotLogger 设置群机器人日志记录
+func WithRobotLogger(fn func(ctx context.Context, err error, data map[string]string)) RobotOption {
+	return func(r *Robot) {
+		r.logger = fn
+	}
+}
+
+// NewRobot 生成一个企业微信群机器人实例，key 为webhook地址中的key
+func NewRobot(key string, options ...RobotOption) *Robot {
+	r := &Robot{
+		host:   "https://qyapi.weixin.qq.com",
+		key:    key,
+		client: lib.NewClient(),
+	}
+	for _, f := range options {
+		f(r)
+	}
+	return r
+}
@@ -0,0 +1,58 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestRobot(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "693a91f6-7xxx-4bc4-97a0-0ec2sifa5aaa", r.URL.Query().Get("key"))
+		assert.Empty(t, r.URL.Query().Get(AccessToken))
+
+		switch r.URL.Path {
+		case "/cgi-bin/webhook/send":
+			body, _ := io.ReadAll(r.Body)
+			ret := gjson.ParseBytes(body)
+			if ret.Get("msgtype").String() == RobotMsgImage {
+				assert.Equal(t, "e2fc714c4727ee9395f324cd2e7f331f", ret.Get("image.md5").String())
+				assert.Equal(t, "YWJjZA==", ret.Get("image.base64").String())
+			}
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		case "/cgi-bin/webhook/upload_media":
+			assert.Equal(t, "file", r.URL.Query().Get("type"))
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","type":"file","media_id":"1G6nrLmr5EC3MMb_-zK1dDdzmd0p7cNliYu9V5w7o8K0","created_at":"1380000000"}`))
+		}
+	}))
+	defer srv.Close()
+
+	robot := NewRobot("693a91f6-7xxx-4bc4-97a0-0ec2sifa5aaa")
+	robot.host = srv.URL
+
+	ctx := context.Background()
+
+	assert.Nil(t, robot.SendText(ctx, "hello world", "@all"))
+	assert.Nil(t, robot.SendMarkdown(ctx, strings.Repeat("字", 2000)))
+	assert.Nil(t, robot.Send(ctx, NewRobotImageMsg([]byte("abcd"))))
+
+	mediaID, err := robot.UploadMediaWithReader(ctx, RobotMediaFile, "test.txt", bytes.NewReader([]byte("hello world")))
+	assert.Nil(t, err)
+	assert.Equal(t, "1G6nrLmr5EC3MMb_-zK1dDdzmd0p7cNliYu9V5w7o8K0", mediaID)
+
+	_, err = robot.UploadMediaWithReader(ctx, RobotMediaFile, "test.txt", bytes.NewReader([]byte("abc")))
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, robot.Send(ctx, NewRobotTextMsg(strings.Repeat("a", RobotTextMaxBytes+1), nil, nil)))
+	assert.NotNil(t, robot.Send(ctx, NewRobotNewsMsg()))
+	assert.NotNil(t, robot.Send(ctx, &RobotMsg{MsgType: "unknown"}))
+
+	assert.Equal(t, "中", truncateBytes("中文", 4))
+}
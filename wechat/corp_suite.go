@@ -0,0 +1,519 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+const (
+	SuiteAccessToken    = "suite_access_token"
+	ProviderAccessToken = "provider_access_token"
+)
+
+// 企业微信第三方应用指令回调(InfoType)
+const (
+	SuiteInfoTicket        = "suite_ticket"         // 推送suite_ticket
+	SuiteInfoCreateAuth    = "create_auth"          // 授权成功
+	SuiteInfoChangeAuth    = "change_auth"          // 变更授权
+	SuiteInfoCancelAuth    = "cancel_auth"          // 取消授权
+	SuiteInfoResetCode     = "reset_permanent_code" // 重置永久授权码
+	SuiteInfoChangeContact = "change_contact"       // 通讯录变更
+)
+
+// PermanentCodeStore 授权企业永久授权码存储
+type PermanentCodeStore interface {
+	// GetPermanentCode 获取授权企业的永久授权码
+	GetPermanentCode(ctx context.Context, corpid string) (string, error)
+
+	// SetPermanentCode 保存授权企业的永久授权码
+	SetPermanentCode(ctx context.Context, corpid, code string) error
+
+	// DelPermanentCode 删除授权企业的永久授权码(取消授权时)
+	DelPermanentCode(ctx context.Context, corpid string) error
+}
+
+// CorpSuite 企业微信(第三方应用)
+type CorpSuite struct {
+	openClient
+
+	suiteid string
+	secret  string
+	srvCfg  *ServerConfig
+	ticket  atomic.Value
+	token   atomic.Value
+}
+
+// SuiteID 返回SuiteID
+func (s *CorpSuite) SuiteID() string {
+	return s.suiteid
+}
+
+// Secret 返回SuiteSecret
+func (s *CorpSuite) Secret() string {
+	return s.secret
+}
+
+// SetSuiteTicket 设置suite_ticket，用于多实例部署时从共享存储中恢复票据
+func (s *CorpSuite) SetSuiteTicket(ticket string) {
+	s.ticket.Store(ticket)
+}
+
+// SuiteTicket 返回最近一次推送的suite_ticket
+func (s *CorpSuite) SuiteTicket() (string, error) {
+	v := s.ticket.Load()
+	if v == nil {
+		return "", errors.New("suite_ticket is empty (not received yet?)")
+	}
+	ticket, ok := v.(string)
+	if !ok {
+		return "", errors.New("suite_ticket is not a string")
+	}
+	return ticket, nil
+}
+
+// SuiteAccessToken 获取第三方应用凭证
+// [参考](https://developer.work.weixin.qq.com/document/path/90600)
+func (s *CorpSuite) SuiteAccessToken(ctx context.Context) (gjson.Result, error) {
+	ticket, err := s.SuiteTicket()
+	if err != nil {
+		return lib.Fail(err)
+	}
+
+	params := lib.X{
+		"suite_id":     s.suiteid,
+		"suite_secret": s.secret,
+		"suite_ticket": ticket,
+	}
+
+	return s.postJSON(ctx, "/cgi-bin/service/get_suite_token", nil, params)
+}
+
+// AutoLoadAccessToken 自动加载SuiteAccessToken(需先收到suite_ticket)
+func (s *CorpSuite) AutoLoadAccessToken(interval time.Duration) error {
+	ctx := context.Background()
+
+	// 初始化AccessToken
+	ret, err := s.SuiteAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	s.token.Store(ret.Get(SuiteAccessToken).String())
+
+	// 异步定时加载
+	go func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			_ret, _ := s.SuiteAccessToken(ctx)
+			if token := _ret.Get(SuiteAccessToken).String(); len(token) != 0 {
+				s.token.Store(token)
+			}
+		}
+	}(ctx)
+
+	return nil
+}
+
+// CustomAccessTokenLoad 自定义加载SuiteAccessToken
+func (s *CorpSuite) CustomAccessTokenLoad(fn func(ctx context.Context, s *CorpSuite) (string, error), interval time.Duration) error {
+	ctx := context.Background()
+
+	// 初始化AccessToken
+	token, err := fn(ctx, s)
+	if err != nil {
+		return err
+	}
+	s.token.Store(token)
+
+	// 异步定时加载
+	go func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _token, _ := fn(ctx, s); len(_token) != 0 {
+				s.token.Store(_token)
+			}
+		}
+	}(ctx)
+
+	return nil
+}
+
+func (s *CorpSuite) getToken() (string, error) {
+	v := s.token.Load()
+	if v == nil {
+		return "", errors.New("suite_access_token is empty (forgotten auto load?)")
+	}
+	token, ok := v.(string)
+	if !ok {
+		return "", errors.New("suite_access_token is not a string")
+	}
+	return token, nil
+}
+
+// GetJSON GET请求JSON数据(使用suite_access_token)
+func (s *CorpSuite) GetJSON(ctx context.Context, path string, query url.Values) (gjson.Result, error) {
+	token, err := s.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set(SuiteAccessToken, token)
+
+	return s.getJSON(ctx, path, query)
+}
+
+// PostJSON POST请求JSON数据(使用suite_access_token)
+func (s *CorpSuite) PostJSON(ctx context.Context, path string, params lib.X) (gjson.Result, error) {
+	token, err := s.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+	query := url.Values{}
+	query.Set(SuiteAccessToken, token)
+
+	return s.postJSON(ctx, path, query, params)
+}
+
+// PreAuthCode 获取预授权码
+// [参考](https://developer.work.weixin.qq.com/document/path/90601)
+func (s *CorpSuite) PreAuthCode(ctx context.Context) (string, error) {
+	ret, err := s.GetJSON(ctx, "/cgi-bin/service/get_pre_auth_code", nil)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("pre_auth_code").String(), nil
+}
+
+// SetSessionInfo 设置授权配置，authType：0-正式授权，1-测试授权
+// [参考](https://developer.work.weixin.qq.com/document/path/90602)
+func (s *CorpSuite) SetSessionInfo(ctx context.Context, preAuthCode string, authType int, appids ...uint32) error {
+	info := lib.X{"auth_type": authType}
+	if len(appids) != 0 {
+		info["appid"] = appids
+	}
+
+	params := lib.X{
+		"pre_auth_code": preAuthCode,
+		"session_info":  info,
+	}
+
+	_, err := s.PostJSON(ctx, "/cgi-bin/service/set_session_info", params)
+	return err
+}
+
+// InstallURL 生成从服务商网站发起的应用授权链接
+// [参考](https://developer.work.weixin.qq.com/document/path/90597)
+func (s *CorpSuite) InstallURL(preAuthCode, redirectURI, state string) string {
+	query := url.Values{}
+
+	query.Set("suite_id", s.suiteid)
+	query.Set("pre_auth_code", preAuthCode)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+
+	return fmt.Sprintf("https://open.work.weixin.qq.com/3rdapp/install?%s", query.Encode())
+}
+
+// PermanentCode 使用临时授权码获取企业永久授权码及授权信息
+// [参考](https://developer.work.weixin.qq.com/document/path/90603)
+func (s *CorpSuite) PermanentCode(ctx context.Context, authCode string) (gjson.Result, error) {
+	return s.PostJSON(ctx, "/cgi-bin/service/get_permanent_code", lib.X{"auth_code": authCode})
+}
+
+// AuthInfo 获取企业授权信息
+// [参考](https://developer.work.weixin.qq.com/document/path/90604)
+func (s *CorpSuite) AuthInfo(ctx context.Context, authCorpID, permanentCode string) (gjson.Result, error) {
+	params := lib.X{
+		"auth_corpid":    authCorpID,
+		"permanent_code": permanentCode,
+	}
+	return s.PostJSON(ctx, "/cgi-bin/service/get_auth_info", params)
+}
+
+// CorpToken 获取授权企业的access_token
+// [参考](https://developer.work.weixin.qq.com/document/path/90605)
+func (s *CorpSuite) CorpToken(ctx context.Context, authCorpID, permanentCode string) (gjson.Result, error) {
+	params := lib.X{
+		"auth_corpid":    authCorpID,
+		"permanent_code": permanentCode,
+	}
+	return s.PostJSON(ctx, "/cgi-bin/service/get_corp_token", params)
+}
+
+// Corp 生成授权企业实例，接口调用使用 get_corp_token 获取的 access_token，并按 interval 定时刷新
+func (s *CorpSuite) Corp(corpid string, store PermanentCodeStore, interval time.Duration, options ...CorpOption) (*Corp, error) {
+	c := &Corp{
+		host:   s.host,
+		corpid: corpid,
+		srvCfg: &ServerConfig{token: s.srvCfg.token, aeskey: s.srvCfg.aeskey},
+		client: s.client,
+		logger: s.logger,
+	}
+	for _, f := range options {
+		f(c)
+	}
+
+	fn := func(ctx context.Context, c *Corp) (string, error) {
+		code, err := store.GetPermanentCode(ctx, c.corpid)
+		if err != nil {
+			return "", err
+		}
+		ret, err := s.CorpToken(ctx, c.corpid, code)
+		if err != nil {
+			return "", err
+		}
+		return ret.Get(AccessToken).String(), nil
+	}
+	if err := c.AutoLoadAccessToken(fn, interval); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// VerifyURL 服务器URL验证，使用：msg_signature、timestamp、nonce、echostr（若验证成功，解密echostr后返回msg字段内容）
+// [参考](https://developer.work.weixin.qq.com/document/path/90930)
+func (s *CorpSuite) VerifyURL(signature, timestamp, nonce, echoStr string) (string, error) {
+	if SignWithSHA1(s.srvCfg.token, timestamp, nonce, echoStr) != signature {
+		return "", errors.New("signature verified fail")
+	}
+	b, err := EventDecrypt(s.suiteid, s.srvCfg.aeskey, echoStr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecodeEventMsg 解析指令回调消息，使用：msg_signature、timestamp、nonce、msg_encrypt
+// 若为suite_ticket推送，会自动更新票据
+// [参考](https://developer.work.weixin.qq.com/document/path/90628)
+func (s *CorpSuite) DecodeEventMsg(signature, timestamp, nonce, encryptMsg string) (value.V, error) {
+	if SignWithSHA1(s.srvCfg.token, timestamp, nonce, encryptMsg) != signature {
+		return nil, errors.New("signature verified fail")
+	}
+
+	b, err := EventDecrypt(s.suiteid, s.srvCfg.aeskey, encryptMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := XMLToValue(b)
+	if err != nil {
+		return nil, err
+	}
+	if msg.Get("InfoType") == SuiteInfoTicket {
+		s.SetSuiteTicket(msg.Get("SuiteTicket"))
+	}
+	return msg, nil
+}
+
+// HandleAuthEvent 处理授权相关的指令回调(已通过DecodeEventMsg解析)：
+// 1. create_auth / reset_permanent_code：使用AuthCode换取永久授权码并保存；
+// 2. cancel_auth：删除对应企业的永久授权码；
+// 3. change_auth：永久授权码不变，故不做处理，如需最新授权信息请调用 AuthInfo；
+// 其它类型的指令忽略
+func (s *CorpSuite) HandleAuthEvent(ctx context.Context, msg value.V, store PermanentCodeStore) error {
+	switch msg.Get("InfoType") {
+	case SuiteInfoCreateAuth, SuiteInfoResetCode:
+		ret, err := s.PermanentCode(ctx, msg.Get("AuthCode"))
+		if err != nil {
+			return err
+		}
+		return store.SetPermanentCode(ctx, ret.Get("auth_corp_info.corpid").String(), ret.Get("permanent_code").String())
+	case SuiteInfoCancelAuth:
+		return store.DelPermanentCode(ctx, msg.Get("AuthCorpId"))
+	}
+	return nil
+}
+
+// CorpSuiteOption 第三方应用设置项
+type CorpSuiteOption func(s *CorpSuite)
+
+// WithSuiteSrvCfg 设置第三方应用回调配置
+// [参考](https://developer.work.weixin.qq.com/document/path/90968)
+func WithSuiteSrvCfg(token, aeskey string) CorpSuiteOption {
+	return func(s *CorpSuite) {
+		s.srvCfg.token = token
+		s.srvCfg.aeskey = aeskey
+	}
+}
+
+// WithSuiteClient 设置第三方应用请求的 HTTP Client
+func WithSuiteClient(cli *http.Client) CorpSuiteOption {
+	return func(s *CorpSuite) {
+		s.client = resty.NewWithClient(cli)
+	}
+}
+
+// WithSuiteLogger 设置第三方应用日志记录
+func WithSuiteLogger(fn func(ctx context.Context, err error, data map[string]string)) CorpSuiteOption {
+	return func(s *CorpSuite) {
+		s.logger = fn
+	}
+}
+
+// NewCorpSuite 生成一个企业微信(第三方应用)实例
+func NewCorpSuite(suiteid, secret string, options ...CorpSuiteOption) *CorpSuite {
+	s := &CorpSuite{
+		openClient: openClient{
+			host:   "https://qyapi.weixin.qq.com",
+			client: lib.NewClient(),
+		},
+		suiteid: suiteid,
+		secret:  secret,
+		srvCfg:  new(ServerConfig),
+	}
+	for _, f := range options {
+		f(s)
+	}
+	return s
+}
+
+// CorpProvider 企业微信服务商
+type CorpProvider struct {
+	openClient
+
+	corpid string
+	secret string
+	token  atomic.Value
+}
+
+// CorpID 返回服务商CorpID
+func (p *CorpProvider) CorpID() string {
+	return p.corpid
+}
+
+// Secret 返回服务商ProviderSecret
+func (p *CorpProvider) Secret() string {
+	return p.secret
+}
+
+// ProviderAccessToken 获取服务商凭证
+// [参考](https://developer.work.weixin.qq.com/document/path/91200)
+func (p *CorpProvider) ProviderAccessToken(ctx context.Context) (gjson.Result, error) {
+	params := lib.X{
+		"corpid":          p.corpid,
+		"provider_secret": p.secret,
+	}
+
+	return p.postJSON(ctx, "/cgi-bin/service/get_provider_token", nil, params)
+}
+
+// AutoLoadAccessToken 自动加载ProviderAccessToken
+func (p *CorpProvider) AutoLoadAccessToken(interval time.Duration) error {
+	ctx := context.Background()
+
+	// 初始化AccessToken
+	ret, err := p.ProviderAccessToken(ctx)
+	if err != nil {
+		return err
+	}
+	p.token.Store(ret.Get(ProviderAccessToken).String())
+
+	// 异步定时加载
+	go func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			_ret, _ := p.ProviderAccessToken(ctx)
+			if token := _ret.Get(ProviderAccessToken).String(); len(token) != 0 {
+				p.token.Store(token)
+			}
+		}
+	}(ctx)
+
+	return nil
+}
+
+func (p *CorpProvider) getToken() (string, error) {
+	v := p.token.Load()
+	if v == nil {
+		return "", errors.New("provider_access_token is empty (forgotten auto load?)")
+	}
+	token, ok := v.(string)
+	if !ok {
+		return "", errors.New("provider_access_token is not a string")
+	}
+	return token, nil
+}
+
+// GetJSON GET请求JSON数据(使用provider_access_token)
+func (p *CorpProvider) GetJSON(ctx context.Context, path string, query url.Values) (gjson.Result, error) {
+	token, err := p.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set(ProviderAccessToken, token)
+
+	return p.getJSON(ctx, path, query)
+}
+
+// PostJSON POST请求JSON数据(使用provider_access_token)
+func (p *CorpProvider) PostJSON(ctx context.Context, path string, params lib.X) (gjson.Result, error) {
+	token, err := p.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+	query := url.Values{}
+	query.Set(ProviderAccessToken, token)
+
+	return p.postJSON(ctx, path, query, params)
+}
+
+// LoginInfo 获取登录用户信息(企业管理员扫码登录服务商网站)
+// [参考](https://developer.work.weixin.qq.com/document/path/91125)
+func (p *CorpProvider) LoginInfo(ctx context.Context, authCode string) (gjson.Result, error) {
+	return p.PostJSON(ctx, "/cgi-bin/service/get_login_info", lib.X{"auth_code": authCode})
+}
+
+// CorpProviderOption 服务商设置项
+type CorpProviderOption func(p *CorpProvider)
+
+// WithProviderClient 设置服务商请求的 HTTP Client
+func WithProviderClient(cli *http.Client) CorpProviderOption {
+	return func(p *CorpProvider) {
+		p.client = resty.NewWithClient(cli)
+	}
+}
+
+// WithProviderLogger 设置服务商日志记录
+func WithProviderLogger(fn func(ctx context.Context, err error, data map[string]string)) CorpProviderOption {
+	return func(p *CorpProvider) {
+		p.logger = fn
+	}
+}
+
+// NewCorpProvider 生成一个企业微信服务商实例
+func NewCorpProvider(corpid, providerSecret string, options ...CorpProviderOption) *CorpProvider {
+	p := &CorpProvider{
+		openClient: openClient{
+			host:   "https://qyapi.weixin.qq.com",
+			client: lib.NewClient(),
+		},
+		corpid: corpid,
+		secret: providerSecret,
+	}
+	for _, f := range options {
+		f(p)
+	}
+	return p
+}
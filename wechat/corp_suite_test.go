@@ -0,0 +1,167 @@
+package wechat
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib/value"
+)
+
+type testPermanentCodeStore struct {
+	mutex sync.Mutex
+	codes map[string]string
+}
+
+func (s *testPermanentCodeStore) GetPermanentCode(ctx context.Context, corpid string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	code, ok := s.codes[corpid]
+	if !ok {
+		return "", errors.New("permanent code not found")
+	}
+	return code, nil
+}
+
+func (s *testPermanentCodeStore) SetPermanentCode(ctx context.Context, corpid, code string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.codes[corpid] = code
+	return nil
+}
+
+func (s *testPermanentCodeStore) DelPermanentCode(ctx context.Context, corpid string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.codes, corpid)
+	return nil
+}
+
+func TestCorpSuite(t *testing.T) {
+	suiteID := "ww4asffe99e54c0f4c"
+	authCorpID := "wxf8b4f85f3a794e77"
+	token := "suite_token"
+	aeskey := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))[:43]
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		params := gjson.ParseBytes(body)
+
+		switch r.URL.Path {
+		case "/cgi-bin/service/get_suite_token":
+			assert.Equal(t, suiteID, params.Get("suite_id").String())
+			assert.Equal(t, "TICKET", params.Get("suite_ticket").String())
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","suite_access_token":"SUITE_TOKEN","expires_in":7200}`))
+		case "/cgi-bin/service/get_permanent_code":
+			assert.Equal(t, "SUITE_TOKEN", r.URL.Query().Get(SuiteAccessToken))
+			assert.Equal(t, "AUTH_CODE", params.Get("auth_code").String())
+			_, _ = w.Write([]byte(`{"access_token":"CORP_TOKEN_0","expires_in":7200,"permanent_code":"PERMANENT_CODE","auth_corp_info":{"corpid":"` + authCorpID + `","corp_name":"name"}}`))
+		case "/cgi-bin/service/get_corp_token":
+			assert.Equal(t, "SUITE_TOKEN", r.URL.Query().Get(SuiteAccessToken))
+			assert.Equal(t, authCorpID, params.Get("auth_corpid").String())
+			assert.Equal(t, "PERMANENT_CODE", params.Get("permanent_code").String())
+			_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","access_token":"CORP_TOKEN_1","expires_in":7200}`))
+		default:
+			_, _ = w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+		}
+	}))
+	defer srv.Close()
+
+	s := NewCorpSuite(suiteID, "SECRET", WithSuiteSrvCfg(token, aeskey))
+	s.host = srv.URL
+
+	ctx := context.Background()
+
+	// 未收到suite_ticket
+	assert.NotNil(t, s.AutoLoadAccessToken(time.Hour))
+
+	// suite_ticket推送
+	msg, err := s.DecodeEventMsg(genTestEventMsg(t, suiteID, token, aeskey, value.V{
+		"SuiteId":     suiteID,
+		"InfoType":    SuiteInfoTicket,
+		"SuiteTicket": "TICKET",
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, SuiteInfoTicket, msg.Get("InfoType"))
+
+	ticket, err := s.SuiteTicket()
+	assert.Nil(t, err)
+	assert.Equal(t, "TICKET", ticket)
+
+	assert.Nil(t, s.AutoLoadAccessToken(time.Hour))
+
+	suiteToken, err := s.getToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "SUITE_TOKEN", suiteToken)
+
+	store := &testPermanentCodeStore{codes: make(map[string]string)}
+
+	// 授权成功
+	msg, err = s.DecodeEventMsg(genTestEventMsg(t, suiteID, token, aeskey, value.V{
+		"SuiteId":  suiteID,
+		"InfoType": SuiteInfoCreateAuth,
+		"AuthCode": "AUTH_CODE",
+	}))
+	assert.Nil(t, err)
+	assert.Nil(t, s.HandleAuthEvent(ctx, msg, store))
+	assert.Equal(t, map[string]string{authCorpID: "PERMANENT_CODE"}, store.codes)
+
+	// 授权企业access_token
+	corp, err := s.Corp(authCorpID, store, time.Hour)
+	assert.Nil(t, err)
+
+	corpToken, err := corp.getToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "CORP_TOKEN_1", corpToken)
+
+	// 变更授权(永久授权码不变)
+	assert.Nil(t, s.HandleAuthEvent(ctx, value.V{"InfoType": SuiteInfoChangeAuth, "AuthCorpId": authCorpID}, store))
+	assert.Equal(t, map[string]string{authCorpID: "PERMANENT_CODE"}, store.codes)
+
+	// 取消授权
+	assert.Nil(t, s.HandleAuthEvent(ctx, value.V{"InfoType": SuiteInfoCancelAuth, "AuthCorpId": authCorpID}, store))
+	assert.Empty(t, store.codes)
+
+	_, err = s.Corp(authCorpID, store, time.Hour)
+	assert.NotNil(t, err)
+}
+
+func TestCorpProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		params := gjson.ParseBytes(body)
+
+		switch r.URL.Path {
+		case "/cgi-bin/service/get_provider_token":
+			assert.Equal(t, "CORPID", params.Get("corpid").String())
+			assert.Equal(t, "SECRET", params.Get("provider_secret").String())
+			_, _ = w.Write([]byte(`{"provider_access_token":"PROVIDER_TOKEN","expires_in":7200}`))
+		case "/cgi-bin/service/get_login_info":
+			assert.Equal(t, "PROVIDER_TOKEN", r.URL.Query().Get(ProviderAccessToken))
+			assert.Equal(t, "AUTH_CODE", params.Get("auth_code").String())
+			_, _ = w.Write([]byte(`{"usertype":1,"user_info":{"userid":"xxxx"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	p := NewCorpProvider("CORPID", "SECRET")
+	p.host = srv.URL
+
+	assert.Nil(t, p.AutoLoadAccessToken(time.Hour))
+
+	ret, err := p.LoginInfo(context.Background(), "AUTH_CODE")
+	assert.Nil(t, err)
+	assert.Equal(t, "xxxx", ret.Get("user_info.userid").String())
+}
@@ -0,0 +1,74 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrIterDone 迭代结束
+var ErrIterDone = errors.New("no more items in iterator")
+
+// PageFetcher 分页拉取，返回本页数据和下一页游标(游标为空表示没有下一页)
+type PageFetcher[T any] func(ctx context.Context, cursor string) ([]T, string, error)
+
+// Iterator 基于游标(如：next_openid、next_cursor)的分页迭代器
+type Iterator[T any] struct {
+	fetch  PageFetcher[T]
+	cursor string
+	items  []T
+	done   bool
+}
+
+// Next 返回下一个元素，迭代结束返回 ErrIterDone
+func (it *Iterator[T]) Next(ctx context.Context) (T, error) {
+	var zero T
+
+	for len(it.items) == 0 {
+		if it.done {
+			return zero, ErrIterDone
+		}
+
+		items, cursor, err := it.fetch(ctx, it.cursor)
+		if err != nil {
+			return zero, err
+		}
+		if len(items) == 0 || len(cursor) == 0 || cursor == it.cursor {
+			it.done = true
+		}
+		it.items = items
+		it.cursor = cursor
+	}
+
+	item := it.items[0]
+	it.items = it.items[1:]
+
+	return item, nil
+}
+
+// Cursor 返回当前游标，可用于断点续拉
+func (it *Iterator[T]) Cursor() string {
+	return it.cursor
+}
+
+// All 拉取全部元素
+func (it *Iterator[T]) All(ctx context.Context) ([]T, error) {
+	var list []T
+	for {
+		item, err := it.Next(ctx)
+		if err != nil {
+			if errors.Is(err, ErrIterDone) {
+				return list, nil
+			}
+			return nil, err
+		}
+		list = append(list, item)
+	}
+}
+
+// NewIterator 生成一个分页迭代器，cursor 为起始游标(可为空)
+func NewIterator[T any](fetch PageFetcher[T], cursor string) *Iterator[T] {
+	return &Iterator[T]{
+		fetch:  fetch,
+		cursor: cursor,
+	}
+}
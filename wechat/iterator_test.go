@@ -0,0 +1,32 @@
+package wechat
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterator(t *testing.T) {
+	pages := map[string][]string{
+		"":    {"a", "b"},
+		"b":   {"c"},
+		"c":   {},
+		"end": nil,
+	}
+
+	it := NewIterator(func(ctx context.Context, cursor string) ([]string, string, error) {
+		items := pages[cursor]
+		if len(items) == 0 {
+			return nil, "", nil
+		}
+		return items, items[len(items)-1], nil
+	}, "")
+
+	list, err := it.All(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, list)
+
+	_, err = it.Next(context.Background())
+	assert.ErrorIs(t, err, ErrIterDone)
+}
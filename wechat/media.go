@@ -0,0 +1,106 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// MediaMeta 媒体资源信息
+type MediaMeta struct {
+	FileName    string // 文件名(来自 Content-Disposition)
+	ContentType string // 文件类型(来自 Content-Type)
+	Size        int64  // 写入的字节数
+}
+
+// IsJSON 资源是否为JSON(如：永久视频素材、图文素材)
+func (m *MediaMeta) IsJSON() bool {
+	return isJSONContent(m.ContentType)
+}
+
+func isJSONContent(contentType string) bool {
+	return strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/plain")
+}
+
+// maxJSONBody JSON返回的最大读取长度，超过的视为二进制资源
+const maxJSONBody = 1 << 20
+
+// doStream 请求并以流的方式将资源写入w；若返回的是JSON且errcode不为0，则返回错误
+func doStream(ctx context.Context, client *resty.Client, logger func(ctx context.Context, err error, data map[string]string),
+	method, reqURL string, header http.Header, body []byte, w io.Writer) (*MediaMeta, error) {
+	log := lib.NewReqLog(method, reqURL)
+	defer log.Do(ctx, logger)
+
+	if len(body) != 0 {
+		log.SetReqBody(string(body))
+	}
+
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeaderMultiValues(header).
+		SetBody(body).
+		SetDoNotParseResponse(true).
+		Execute(method, reqURL)
+	if err != nil {
+		log.SetError(err)
+		return nil, err
+	}
+
+	rawBody := resp.RawBody()
+	defer rawBody.Close()
+
+	log.SetRespHeader(resp.Header())
+	log.SetStatusCode(resp.StatusCode())
+	if !resp.IsSuccess() {
+		err = fmt.Errorf("HTTP Request Error, StatusCode = %d", resp.StatusCode())
+		log.SetError(err)
+		return nil, err
+	}
+
+	meta := &MediaMeta{
+		ContentType: resp.Header().Get(lib.HeaderContentType),
+	}
+	if _, params, _err := mime.ParseMediaType(resp.Header().Get("Content-Disposition")); _err == nil {
+		meta.FileName = params["filename"]
+	}
+
+	var reader io.Reader = rawBody
+
+	// JSON可能为错误信息
+	if isJSONContent(meta.ContentType) {
+		b, _err := io.ReadAll(io.LimitReader(rawBody, maxJSONBody))
+		if _err != nil {
+			log.SetError(_err)
+			return nil, _err
+		}
+		log.SetRespBody(string(b))
+
+		ret := gjson.ParseBytes(b)
+		if code := ret.Get("errcode").Int(); code != 0 {
+			err = fmt.Errorf("%d | %s", code, ret.Get("errmsg").String())
+			log.SetError(err)
+			return nil, err
+		}
+		reader = io.MultiReader(bytes.NewReader(b), rawBody)
+	}
+
+	n, err := io.Copy(w, reader)
+	if err != nil {
+		log.SetError(err)
+		return nil, err
+	}
+	meta.Size = n
+	log.Set("response_size", strconv.FormatInt(n, 10))
+
+	return meta, nil
+}
@@ -0,0 +1,43 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+func TestDoStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("media_id") == "invalid" {
+			w.Header().Set(lib.HeaderContentType, "application/json; encoding=utf-8")
+			w.Write([]byte(`{"errcode":40007,"errmsg":"invalid media_id"}`))
+			return
+		}
+		w.Header().Set(lib.HeaderContentType, "image/jpeg")
+		w.Header().Set("Content-Disposition", `attachment; filename="MEDIA_ID.jpg"`)
+		w.Write([]byte("jpeg-bytes"))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+
+	meta, err := doStream(context.Background(), lib.NewClient(), nil, http.MethodGet, srv.URL+"?media_id=MEDIA_ID", nil, nil, &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "MEDIA_ID.jpg", meta.FileName)
+	assert.Equal(t, "image/jpeg", meta.ContentType)
+	assert.Equal(t, int64(10), meta.Size)
+	assert.False(t, meta.IsJSON())
+	assert.Equal(t, "jpeg-bytes", buf.String())
+
+	buf.Reset()
+
+	_, err = doStream(context.Background(), lib.NewClient(), nil, http.MethodGet, srv.URL+"?media_id=invalid", nil, nil, &buf)
+	assert.EqualError(t, err, "40007 | invalid media_id")
+	assert.Equal(t, 0, buf.Len())
+}
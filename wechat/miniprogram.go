@@ -4,8 +4,10 @@ import (
 	"context"
 	"crypto"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +15,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -26,11 +29,51 @@ import (
 
 // SafeMode 安全鉴权模式配置
 type SafeMode struct {
-	aesSN  string
-	aeskey string
-	prvKey *xcrypto.PrivateKey
-	pubSN  string
-	pubKey *xcrypto.PublicKey
+	mutex   sync.RWMutex
+	aesSN   string
+	aeskey  string
+	prvKey  *xcrypto.PrivateKey
+	pubKeys map[string]*xcrypto.PublicKey // 平台证书编号 -> 公钥，轮换期间新旧证书同时有效
+}
+
+func (sm *SafeMode) aesKey() (string, string) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	return sm.aesSN, sm.aeskey
+}
+
+func (sm *SafeMode) privateKey() *xcrypto.PrivateKey {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	return sm.prvKey
+}
+
+func (sm *SafeMode) publicKey(serialNO string) *xcrypto.PublicKey {
+	if len(serialNO) == 0 {
+		return nil
+	}
+
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	return sm.pubKeys[serialNO]
+}
+
+func newSafeMode() *SafeMode {
+	return &SafeMode{
+		pubKeys: make(map[string]*xcrypto.PublicKey),
+	}
+}
+
+// PlatformCert 小程序平台证书
+type PlatformCert struct {
+	CertType      string `json:"cert_type"`
+	SerialNO      string `json:"cert_sn"`
+	CertData      string `json:"cert_data"`
+	EffectiveTime int64  `json:"cert_effective_time"`
+	ExpireTime    int64  `json:"cert_expire_time"`
 }
 
 // MiniProgram 小程序
@@ -111,15 +154,19 @@ func (mp *MiniProgram) do(ctx context.Context, method, path string, header http.
 }
 
 func (mp *MiniProgram) doSafe(ctx context.Context, method, path string, query url.Values, params lib.X) ([]byte, error) {
-	reqURL := mp.url(path, query)
+	// 除 access_token 外的query参数均加密后放入请求体
+	q := url.Values{}
+	q.Set(AccessToken, query.Get(AccessToken))
+	reqURL := mp.url(path, q)
 
 	log := lib.NewReqLog(method, reqURL)
 	defer log.Do(ctx, mp.logger)
 
 	now := time.Now().Unix()
+	aesSN, aeskey := mp.sfMode.aesKey()
 
 	// 加密
-	params, err := mp.encrypt(log, path, query, params, now)
+	params, err := mp.encrypt(log, aesSN, aeskey, path, query, params, now)
 	if err != nil {
 		log.SetError(err)
 		return nil, err
@@ -132,17 +179,34 @@ func (mp *MiniProgram) doSafe(ctx context.Context, method, path string, query ur
 	}
 	log.SetReqBody(string(body))
 
-	// 签名
-	sign, err := mp.sign(path, now, body)
+	header, respBody, err := mp.sendSafe(ctx, log, method, path, reqURL, body, now)
+	if err != nil {
+		log.SetError(err)
+		return nil, err
+	}
+
+	// 解密
+	data, err := mp.decrypt(aesSN, aeskey, path, header, respBody)
 	if err != nil {
 		log.SetError(err)
 		return nil, err
 	}
+	log.Set("origin_response_body", string(data))
+	return data, nil
+}
+
+// sendSafe 签名并发送请求，返回验签通过的响应
+func (mp *MiniProgram) sendSafe(ctx context.Context, log *lib.ReqLog, method, path, reqURL string, body []byte, timestamp int64) (http.Header, []byte, error) {
+	// 签名
+	sign, err := mp.sign(path, timestamp, body)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	reqHeader := http.Header{}
 	reqHeader.Set(lib.HeaderContentType, lib.ContentJSON)
 	reqHeader.Set(HeaderMPAppID, mp.appid)
-	reqHeader.Set(HeaderMPTimestamp, strconv.FormatInt(now, 10))
+	reqHeader.Set(HeaderMPTimestamp, strconv.FormatInt(timestamp, 10))
 	reqHeader.Set(HeaderMPSignature, sign)
 	log.SetReqHeader(reqHeader)
 
@@ -152,34 +216,24 @@ func (mp *MiniProgram) doSafe(ctx context.Context, method, path string, query ur
 		SetBody(body).
 		Execute(method, reqURL)
 	if err != nil {
-		log.SetError(err)
-		return nil, err
+		return nil, nil, err
 	}
 	log.SetRespHeader(resp.Header())
 	log.SetStatusCode(resp.StatusCode())
 	log.SetRespBody(string(resp.Body()))
 	if !resp.IsSuccess() {
-		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", resp.StatusCode())
+		return nil, nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", resp.StatusCode())
 	}
 
 	// 验签
 	if err = mp.verify(path, resp.Header(), resp.Body()); err != nil {
-		log.SetError(err)
-		return nil, err
+		return nil, nil, err
 	}
-
-	// 解密
-	data, err := mp.decrypt(path, resp.Header(), resp.Body())
-	if err != nil {
-		log.SetError(err)
-		return nil, err
-	}
-	log.Set("origin_response_body", string(data))
-	return data, nil
+	return resp.Header(), resp.Body(), nil
 }
 
-func (mp *MiniProgram) encrypt(log *lib.ReqLog, path string, query url.Values, params lib.X, timestamp int64) (lib.X, error) {
-	if len(mp.sfMode.aeskey) == 0 {
+func (mp *MiniProgram) encrypt(log *lib.ReqLog, aesSN, aeskey, path string, query url.Values, params lib.X, timestamp int64) (lib.X, error) {
+	if len(aeskey) == 0 {
 		return nil, errors.New("aes-gcm key not found (forgotten configure?)")
 	}
 
@@ -205,14 +259,14 @@ func (mp *MiniProgram) encrypt(log *lib.ReqLog, path string, query url.Values, p
 
 	log.Set("origin_request_body", string(data))
 
-	key, err := base64.StdEncoding.DecodeString(mp.sfMode.aeskey)
+	key, err := base64.StdEncoding.DecodeString(aeskey)
 	if err != nil {
 		log.SetError(err)
 		return nil, err
 	}
 
 	iv := lib.NonceByte(12)
-	aad := fmt.Sprintf("%s|%s|%d|%s", mp.url(path, nil), mp.appid, timestamp, mp.sfMode.aesSN)
+	aad := fmt.Sprintf("%s|%s|%d|%s", mp.url(path, nil), mp.appid, timestamp, aesSN)
 
 	ct, err := xcrypto.AESEncryptGCM(key, iv, data, []byte(aad), nil)
 	if err != nil {
@@ -229,7 +283,8 @@ func (mp *MiniProgram) encrypt(log *lib.ReqLog, path string, query url.Values, p
 }
 
 func (mp *MiniProgram) sign(path string, timestamp int64, body []byte) (string, error) {
-	if mp.sfMode.prvKey == nil {
+	prvKey := mp.sfMode.privateKey()
+	if prvKey == nil {
 		return "", errors.New("private key not found (forgotten configure?)")
 	}
 
@@ -243,7 +298,7 @@ func (mp *MiniProgram) sign(path string, timestamp int64, body []byte) (string,
 	builder.WriteString("\n")
 	builder.Write(body)
 
-	b, err := mp.sfMode.prvKey.SignPSS(crypto.SHA256, []byte(builder.String()), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	b, err := prvKey.SignPSS(crypto.SHA256, []byte(builder.String()), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
 	if err != nil {
 		return "", err
 	}
@@ -251,23 +306,21 @@ func (mp *MiniProgram) sign(path string, timestamp int64, body []byte) (string,
 }
 
 func (mp *MiniProgram) verify(path string, header http.Header, body []byte) error {
-	if mp.sfMode.pubKey == nil {
-		return errors.New("public key not found (forgotten configure?)")
-	}
-
 	if appid := header.Get(HeaderMPAppID); appid != mp.appid {
 		return fmt.Errorf("header appid mismatch, expect = %s", mp.appid)
 	}
 
-	var sign string
-	if serial := header.Get(HeaderMPSerial); serial == mp.sfMode.pubSN {
+	// 证书轮换期间，平台会同时返回新旧证书的签名，任一证书可用即可验签
+	var (
+		sign   string
+		pubKey *xcrypto.PublicKey
+	)
+	if pubKey = mp.sfMode.publicKey(header.Get(HeaderMPSerial)); pubKey != nil {
 		sign = header.Get(HeaderMPSignature)
-	} else {
-		serialDeprecated := header.Get(HeaderMPSerialDeprecated)
-		if serialDeprecated != mp.sfMode.pubSN {
-			return fmt.Errorf("header serial mismatch, expect = %s", mp.sfMode.pubSN)
-		}
+	} else if pubKey = mp.sfMode.publicKey(header.Get(HeaderMPSerialDeprecated)); pubKey != nil {
 		sign = header.Get(HeaderMPSignatureDeprecated)
+	} else {
+		return fmt.Errorf("public key not found (serial = %s, deprecated = %s)", header.Get(HeaderMPSerial), header.Get(HeaderMPSerialDeprecated))
 	}
 	b, err := base64.StdEncoding.DecodeString(sign)
 	if err != nil {
@@ -284,15 +337,15 @@ func (mp *MiniProgram) verify(path string, header http.Header, body []byte) erro
 	builder.WriteString("\n")
 	builder.Write(body)
 
-	return mp.sfMode.pubKey.VerifyPSS(crypto.SHA256, []byte(builder.String()), b, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	return pubKey.VerifyPSS(crypto.SHA256, []byte(builder.String()), b, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
 }
 
-func (mp *MiniProgram) decrypt(path string, header http.Header, body []byte) ([]byte, error) {
-	if len(mp.sfMode.aeskey) == 0 {
+func (mp *MiniProgram) decrypt(aesSN, aeskey, path string, header http.Header, body []byte) ([]byte, error) {
+	if len(aeskey) == 0 {
 		return nil, errors.New("aes-gcm key not found (forgotten configure?)")
 	}
 
-	key, err := base64.StdEncoding.DecodeString(mp.sfMode.aeskey)
+	key, err := base64.StdEncoding.DecodeString(aeskey)
 	if err != nil {
 		return nil, err
 	}
@@ -314,7 +367,7 @@ func (mp *MiniProgram) decrypt(path string, header http.Header, body []byte) ([]
 		return nil, err
 	}
 
-	aad := fmt.Sprintf("%s|%s|%s|%s", mp.url(path, nil), mp.appid, header.Get(HeaderMPTimestamp), mp.sfMode.aesSN)
+	aad := fmt.Sprintf("%s|%s|%s|%s", mp.url(path, nil), mp.appid, header.Get(HeaderMPTimestamp), aesSN)
 
 	return xcrypto.AESDecryptGCM(key, iv, append(data, tag...), []byte(aad), nil)
 }
@@ -545,6 +598,40 @@ func (mp *MiniProgram) PostBuffer(ctx context.Context, path string, params lib.X
 	return b, nil
 }
 
+// GetStream GET请求获取资源并写入w，返回的JSON错误会被识别为error
+func (mp *MiniProgram) GetStream(ctx context.Context, path string, query url.Values, w io.Writer) (*MediaMeta, error) {
+	token, err := mp.getToken()
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set(AccessToken, token)
+
+	return doStream(ctx, mp.client, mp.logger, http.MethodGet, mp.url(path, query), nil, nil, w)
+}
+
+// PostStream POST请求获取资源并写入w (如：获取小程序码)，返回的JSON错误会被识别为error
+func (mp *MiniProgram) PostStream(ctx context.Context, path string, params lib.X, w io.Writer) (*MediaMeta, error) {
+	token, err := mp.getToken()
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set(AccessToken, token)
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set(lib.HeaderContentType, lib.ContentJSON)
+
+	return doStream(ctx, mp.client, mp.logger, http.MethodPost, mp.url(path, query), header, body, w)
+}
+
 // SafePostJSON POST请求JSON数据
 // 安全鉴权模式 https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/getting_started/api_signature.html
 // 支持的api可参考 https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc
@@ -591,7 +678,135 @@ func (mp *MiniProgram) SafePostBuffer(ctx context.Context, path string, params l
 	return b, nil
 }
 
-// Upload 上传媒体资源
+// SafeGetJSON 以安全鉴权模式请求GET类接口
+// 安全鉴权模式仅支持POST，query参数(access_token除外)将加密后放入请求体；
+// 注意：暂不支持以安全鉴权模式上传文件(multipart/form-data)，请使用 Upload / UploadWithReader
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/getting_started/api_signature.html)
+func (mp *MiniProgram) SafeGetJSON(ctx context.Context, path string, query url.Values) (gjson.Result, error) {
+	token, err := mp.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set(AccessToken, token)
+
+	b, err := mp.doSafe(ctx, http.MethodPost, path, query, nil)
+	if err != nil {
+		return lib.Fail(err)
+	}
+
+	ret := gjson.ParseBytes(b)
+	if code := ret.Get("errcode").Int(); code != 0 {
+		return lib.Fail(fmt.Errorf("%d | %s", code, ret.Get("errmsg").String()))
+	}
+	return ret, nil
+}
+
+// SetSafeAesKey 设置(轮换) AES-GCM 加密Key
+func (mp *MiniProgram) SetSafeAesKey(serialNO, key string) {
+	mp.sfMode.mutex.Lock()
+	defer mp.sfMode.mutex.Unlock()
+
+	mp.sfMode.aesSN = serialNO
+	mp.sfMode.aeskey = key
+}
+
+// SetSafePrivateKey 设置(轮换) RSA私钥
+func (mp *MiniProgram) SetSafePrivateKey(key *xcrypto.PrivateKey) {
+	mp.sfMode.mutex.Lock()
+	defer mp.sfMode.mutex.Unlock()
+
+	mp.sfMode.prvKey = key
+}
+
+// AddPlatformPublicKey 添加平台RSA公钥
+func (mp *MiniProgram) AddPlatformPublicKey(serialNO string, key *xcrypto.PublicKey) {
+	mp.sfMode.mutex.Lock()
+	defer mp.sfMode.mutex.Unlock()
+
+	mp.sfMode.pubKeys[serialNO] = key
+}
+
+// DelPlatformPublicKey 删除平台RSA公钥(如：旧证书已过期)
+func (mp *MiniProgram) DelPlatformPublicKey(serialNO string) {
+	mp.sfMode.mutex.Lock()
+	defer mp.sfMode.mutex.Unlock()
+
+	delete(mp.sfMode.pubKeys, serialNO)
+}
+
+// LoadPlatformCerts 从微信获取平台证书列表，并合并到当前的平台公钥(同编号覆盖，其它公钥保留，过期的旧公钥可通过 DelPlatformPublicKey 删除)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/getting_started/api_signature.html)
+func (mp *MiniProgram) LoadPlatformCerts(ctx context.Context) ([]*PlatformCert, error) {
+	ret, err := mp.PostJSON(ctx, "/wxa/getwxaapicert", lib.X{"appid": mp.appid})
+	if err != nil {
+		return nil, err
+	}
+
+	certs := make([]*PlatformCert, 0)
+	if err = unmarshalResult(ret.Get("cert_list"), &certs); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*xcrypto.PublicKey, len(certs))
+	for _, v := range certs {
+		key, _err := parsePlatformCert(v.CertData)
+		if _err != nil {
+			return nil, fmt.Errorf("cert(%s) parse error: %w", v.SerialNO, _err)
+		}
+		keys[v.SerialNO] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("platform cert not found")
+	}
+
+	mp.sfMode.mutex.Lock()
+	for sn, key := range keys {
+		mp.sfMode.pubKeys[sn] = key
+	}
+	mp.sfMode.mutex.Unlock()
+
+	return certs, nil
+}
+
+// parsePlatformCert 解析PEM格式的平台证书，返回RSA公钥
+func parsePlatformCert(certData string) (*xcrypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(certData))
+	if block == nil {
+		return nil, errors.New("no PEM data is found")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return xcrypto.NewPublicKeyFromCert(cert)
+}
+
+// AutoLoadPlatformCerts 定时获取平台证书列表(证书轮换时自动生效)
+func (mp *MiniProgram) AutoLoadPlatformCerts(interval time.Duration) error {
+	ctx := context.Background()
+
+	// 初始化平台证书
+	if _, err := mp.LoadPlatformCerts(ctx); err != nil {
+		return err
+	}
+
+	// 异步定时加载
+	go func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			_, _ = mp.LoadPlatformCerts(ctx)
+		}
+	}(ctx)
+	return nil
+}
+
+// Upload 上传媒体资源(不支持安全鉴权模式)
 func (mp *MiniProgram) Upload(ctx context.Context, reqPath, fieldName, filePath string, formData lib.Form, query url.Values) (gjson.Result, error) {
 	token, err := mp.getToken()
 	if err != nil {
@@ -631,7 +846,7 @@ func (mp *MiniProgram) Upload(ctx context.Context, reqPath, fieldName, filePath
 	return ret, nil
 }
 
-// UploadWithReader 上传媒体资源
+// UploadWithReader 上传媒体资源(不支持安全鉴权模式)
 func (mp *MiniProgram) UploadWithReader(ctx context.Context, reqPath, fieldName, fileName string, reader io.Reader, formData lib.Form, query url.Values) (gjson.Result, error) {
 	token, err := mp.getToken()
 	if err != nil {
@@ -705,7 +920,7 @@ func (mp *MiniProgram) DecodeEventMsg(signature, timestamp, nonce, encryptMsg st
 		return nil, errors.New("signature verified fail")
 	}
 
-	b, err := EventDecrypt(mp.appid, mp.srvCfg.aeskey, encryptMsg)
+	b, err := EventDecrypt(mp.srvCfg.receiver(mp.appid), mp.srvCfg.aeskey, encryptMsg)
 	if err != nil {
 		return nil, err
 	}
@@ -714,7 +929,7 @@ func (mp *MiniProgram) DecodeEventMsg(signature, timestamp, nonce, encryptMsg st
 
 // ReplyEventMsg 事件消息回复
 func (mp *MiniProgram) ReplyEventMsg(msg value.V) (value.V, error) {
-	return EventReply(mp.appid, mp.srvCfg.token, mp.srvCfg.aeskey, msg)
+	return EventReply(mp.srvCfg.receiver(mp.appid), mp.srvCfg.token, mp.srvCfg.aeskey, msg)
 }
 
 // MPOption 小程序设置项
@@ -746,23 +961,21 @@ func WithMPLogger(fn func(ctx context.Context, err error, data map[string]string
 // WithMPAesKey 设置小程序 AES-GCM 加密Key
 func WithMPAesKey(serialNO, key string) MPOption {
 	return func(mp *MiniProgram) {
-		mp.sfMode.aesSN = serialNO
-		mp.sfMode.aeskey = key
+		mp.SetSafeAesKey(serialNO, key)
 	}
 }
 
 // WithMPPrivateKey 设置小程序RSA私钥
 func WithMPPrivateKey(key *xcrypto.PrivateKey) MPOption {
 	return func(mp *MiniProgram) {
-		mp.sfMode.prvKey = key
+		mp.SetSafePrivateKey(key)
 	}
 }
 
-// WithMPPublicKey 设置小程序平台RSA公钥
+// WithMPPublicKey 设置小程序平台RSA公钥(可多次设置，证书轮换期间新旧证书同时有效)
 func WithMPPublicKey(serialNO string, key *xcrypto.PublicKey) MPOption {
 	return func(mp *MiniProgram) {
-		mp.sfMode.pubSN = serialNO
-		mp.sfMode.pubKey = key
+		mp.AddPlatformPublicKey(serialNO, key)
 	}
 }
 
@@ -773,6 +986,7 @@ func NewMiniProgram(appid, secret string, options ...MPOption) *MiniProgram {
 		appid:  appid,
 		secret: secret,
 		srvCfg: new(ServerConfig),
+		sfMode: newSafeMode(),
 		client: lib.NewClient(),
 	}
 	for _, f := range options {
@@ -0,0 +1,313 @@
+package wechat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// EnvVersion 小程序版本
+type EnvVersion string
+
+const (
+	EnvRelease EnvVersion = "release" // 正式版
+	EnvTrial   EnvVersion = "trial"   // 体验版
+	EnvDevelop EnvVersion = "develop" // 开发版
+)
+
+// 小程序码限制
+const (
+	WXACodeMinWidth    = 280
+	WXACodeMaxWidth    = 1280
+	WXACodeMaxPathLen  = 1024
+	WXACodeMaxSceneLen = 32
+)
+
+// LineColor 小程序码线条颜色
+type LineColor struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+type wxaCodeOptions struct {
+	envVersion EnvVersion
+	width      int
+	autoColor  bool
+	lineColor  *LineColor
+	hyaline    bool
+	checkPath  *bool
+}
+
+// WXACodeOption 小程序码设置项
+type WXACodeOption func(o *wxaCodeOptions)
+
+// WithCodeEnvVersion 设置要打开的小程序版本(默认：release)
+func WithCodeEnvVersion(v EnvVersion) WXACodeOption {
+	return func(o *wxaCodeOptions) {
+		o.envVersion = v
+	}
+}
+
+// WithCodeWidth 设置二维码的宽度，单位px(280~1280，默认：430)
+func WithCodeWidth(width int) WXACodeOption {
+	return func(o *wxaCodeOptions) {
+		o.width = width
+	}
+}
+
+// WithCodeAutoColor 自动配置线条颜色
+func WithCodeAutoColor() WXACodeOption {
+	return func(o *wxaCodeOptions) {
+		o.autoColor = true
+		o.lineColor = nil
+	}
+}
+
+// WithCodeLineColor 设置线条颜色(RGB：0~255)
+func WithCodeLineColor(r, g, b int) WXACodeOption {
+	return func(o *wxaCodeOptions) {
+		o.autoColor = false
+		o.lineColor = &LineColor{R: r, G: g, B: b}
+	}
+}
+
+// WithCodeHyaline 设置透明底色
+func WithCodeHyaline() WXACodeOption {
+	return func(o *wxaCodeOptions) {
+		o.hyaline = true
+	}
+}
+
+// WithCodeCheckPath 设置是否检查page是否存在(默认：true)，未发布的小程序需设为false
+func WithCodeCheckPath(check bool) WXACodeOption {
+	return func(o *wxaCodeOptions) {
+		o.checkPath = &check
+	}
+}
+
+func (o *wxaCodeOptions) validate() error {
+	switch o.envVersion {
+	case "", EnvRelease, EnvTrial, EnvDevelop:
+	default:
+		return fmt.Errorf("invalid env_version: %s", o.envVersion)
+	}
+	if o.width != 0 && (o.width < WXACodeMinWidth || o.width > WXACodeMaxWidth) {
+		return fmt.Errorf("width must be between %d and %d", WXACodeMinWidth, WXACodeMaxWidth)
+	}
+	if c := o.lineColor; c != nil {
+		for _, v := range []int{c.R, c.G, c.B} {
+			if v < 0 || v > 255 {
+				return errors.New("line_color must be between 0 and 255")
+			}
+		}
+	}
+	return nil
+}
+
+func (o *wxaCodeOptions) params(params lib.X) {
+	if len(o.envVersion) != 0 {
+		params["env_version"] = o.envVersion
+	}
+	if o.width != 0 {
+		params["width"] = o.width
+	}
+	if o.autoColor {
+		params["auto_color"] = true
+	}
+	if o.lineColor != nil {
+		params["line_color"] = o.lineColor
+	}
+	if o.hyaline {
+		params["is_hyaline"] = true
+	}
+	if o.checkPath != nil {
+		params["check_path"] = *o.checkPath
+	}
+}
+
+func buildWXACodeOptions(options ...WXACodeOption) (*wxaCodeOptions, error) {
+	o := new(wxaCodeOptions)
+	for _, f := range options {
+		f(o)
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// ValidateScene 校验 getwxacodeunlimit 的 scene 参数：
+// 最大32个可见字符，只支持数字、大小写英文以及部分特殊字符：!#$&'()*+,/:;=?@-._~
+func ValidateScene(scene string) error {
+	if len(scene) == 0 {
+		return errors.New("scene is empty")
+	}
+	if len(scene) > WXACodeMaxSceneLen {
+		return fmt.Errorf("scene exceeds %d characters", WXACodeMaxSceneLen)
+	}
+	for _, c := range scene {
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case strings.ContainsRune("!#$&'()*+,/:;=?@-._~", c):
+		default:
+			return fmt.Errorf("scene contains invalid character: %q", c)
+		}
+	}
+	return nil
+}
+
+// GetWXACode 获取小程序码(适用于需要的码数量较少的业务场景，永久有效，数量有限)，图片写入w
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/qr-code/getQRCode.html)
+func (mp *MiniProgram) GetWXACode(ctx context.Context, path string, w io.Writer, options ...WXACodeOption) (*MediaMeta, error) {
+	if len(path) == 0 || utf8.RuneCountInString(path) > WXACodeMaxPathLen {
+		return nil, fmt.Errorf("path must be between 1 and %d characters", WXACodeMaxPathLen)
+	}
+
+	o, err := buildWXACodeOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	params := lib.X{"path": path}
+	o.params(params)
+
+	return mp.PostStream(ctx, "/wxa/getwxacode", params, w)
+}
+
+// GetWXACodeUnlimit 获取小程序码(适用于需要的码数量极多的业务场景，永久有效，数量不限)，图片写入w；
+// page 为已发布小程序的页面(不能携带参数，根路径前不要填加 /)，为空则默认跳主页面
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/qr-code/getUnlimitedQRCode.html)
+func (mp *MiniProgram) GetWXACodeUnlimit(ctx context.Context, scene, page string, w io.Writer, options ...WXACodeOption) (*MediaMeta, error) {
+	if err := ValidateScene(scene); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(page, "/") || strings.Contains(page, "?") {
+		return nil, errors.New("page must not start with '/' or carry query")
+	}
+
+	o, err := buildWXACodeOptions(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	params := lib.X{"scene": scene}
+	if len(page) != 0 {
+		params["page"] = page
+	}
+	o.params(params)
+
+	return mp.PostStream(ctx, "/wxa/getwxacodeunlimit", params, w)
+}
+
+// CreateWXAQRCode 获取小程序二维码(适用于需要的码数量较少的业务场景，永久有效，数量有限)，图片写入w
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/qr-code/createQRCode.html)
+func (mp *MiniProgram) CreateWXAQRCode(ctx context.Context, path string, width int, w io.Writer) (*MediaMeta, error) {
+	if len(path) == 0 || utf8.RuneCountInString(path) > WXACodeMaxPathLen {
+		return nil, fmt.Errorf("path must be between 1 and %d characters", WXACodeMaxPathLen)
+	}
+	if width != 0 && (width < WXACodeMinWidth || width > WXACodeMaxWidth) {
+		return nil, fmt.Errorf("width must be between %d and %d", WXACodeMinWidth, WXACodeMaxWidth)
+	}
+
+	params := lib.X{"path": path}
+	if width != 0 {
+		params["width"] = width
+	}
+
+	return mp.PostStream(ctx, "/cgi-bin/wxaapp/createwxaqrcode", params, w)
+}
+
+// ExpireType 到期失效类型
+type ExpireType int
+
+const (
+	ExpireByTime     ExpireType = 0 // 指定时间失效
+	ExpireByInterval ExpireType = 1 // 指定间隔天数失效
+)
+
+// SchemeJumpWxa 跳转到的目标小程序信息
+type SchemeJumpWxa struct {
+	Path       string     `json:"path,omitempty"`
+	Query      string     `json:"query,omitempty"`
+	EnvVersion EnvVersion `json:"env_version,omitempty"`
+}
+
+// URLScheme 小程序 scheme 参数
+type URLScheme struct {
+	JumpWxa        *SchemeJumpWxa `json:"jump_wxa,omitempty"`
+	ExpireType     ExpireType     `json:"expire_type"`
+	ExpireTime     int64          `json:"expire_time,omitempty"`     // 到期失效的 scheme 码的失效时间，为 Unix 时间戳
+	ExpireInterval int            `json:"expire_interval,omitempty"` // 到期失效的 scheme 码的失效间隔天数，最长30天
+}
+
+// URLLink 小程序 URL Link 参数
+type URLLink struct {
+	Path           string     `json:"path,omitempty"`
+	Query          string     `json:"query,omitempty"`
+	EnvVersion     EnvVersion `json:"env_version,omitempty"`
+	ExpireType     ExpireType `json:"expire_type"`
+	ExpireTime     int64      `json:"expire_time,omitempty"`
+	ExpireInterval int        `json:"expire_interval,omitempty"`
+}
+
+// GenerateScheme 获取小程序 scheme 码，返回 openlink
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/url-scheme/generateScheme.html)
+func (mp *MiniProgram) GenerateScheme(ctx context.Context, scheme *URLScheme) (string, error) {
+	params, err := lib.ToX(scheme)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := mp.PostJSON(ctx, "/wxa/generatescheme", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("openlink").String(), nil
+}
+
+// GenerateURLLink 获取小程序 URL Link，返回 url_link
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/url-link/generateUrlLink.html)
+func (mp *MiniProgram) GenerateURLLink(ctx context.Context, link *URLLink) (string, error) {
+	params, err := lib.ToX(link)
+	if err != nil {
+		return "", err
+	}
+
+	ret, err := mp.PostJSON(ctx, "/wxa/generate_urllink", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("url_link").String(), nil
+}
+
+// GenerateShortLink 获取小程序 Short Link，返回 link；
+// pageURL 为带参数的页面路径(最大1024字符)，pageTitle 为页面标题(不能包含违法信息，最大64字符)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/qrcode-link/short-link/generateShortLink.html)
+func (mp *MiniProgram) GenerateShortLink(ctx context.Context, pageURL, pageTitle string, permanent bool) (string, error) {
+	if len(pageURL) == 0 || utf8.RuneCountInString(pageURL) > WXACodeMaxPathLen {
+		return "", fmt.Errorf("page_url must be between 1 and %d characters", WXACodeMaxPathLen)
+	}
+	if utf8.RuneCountInString(pageTitle) > 64 {
+		return "", errors.New("page_title exceeds 64 characters")
+	}
+
+	params := lib.X{
+		"page_url":     pageURL,
+		"is_permanent": permanent,
+	}
+	if len(pageTitle) != 0 {
+		params["page_title"] = pageTitle
+	}
+
+	ret, err := mp.PostJSON(ctx, "/wxa/genwxashortlink", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("link").String(), nil
+}
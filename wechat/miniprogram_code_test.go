@@ -0,0 +1,70 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func TestValidateScene(t *testing.T) {
+	assert.Nil(t, ValidateScene("id=1&from=share"))
+	assert.Nil(t, ValidateScene("a!#$&'()*+,/:;=?@-._~"))
+	assert.NotNil(t, ValidateScene(""))
+	assert.NotNil(t, ValidateScene("0123456789012345678901234567890123"))
+	assert.NotNil(t, ValidateScene("id=中文"))
+	assert.NotNil(t, ValidateScene("a b"))
+}
+
+func TestGetWXACodeUnlimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ret := gjson.ParseBytes(body)
+		if ret.Get("scene").String() == "err" {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+			_, _ = w.Write([]byte(`{"errcode":41030,"errmsg":"invalid page rid: 6536148a-1c5a3e9c-2e0a4b1f"}`))
+			return
+		}
+
+		assert.Equal(t, "trial", ret.Get("env_version").String())
+		assert.Equal(t, int64(300), ret.Get("width").Int())
+		assert.Equal(t, int64(255), ret.Get("line_color.r").Int())
+		assert.True(t, ret.Get("is_hyaline").Bool())
+		assert.False(t, ret.Get("check_path").Bool())
+
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG"))
+	}))
+	defer srv.Close()
+
+	mp := NewMiniProgram("wx4f4bc4dec97d474b", "secret")
+	mp.host = srv.URL
+	mp.token.Store("ACCESS_TOKEN")
+
+	var buf bytes.Buffer
+
+	meta, err := mp.GetWXACodeUnlimit(context.Background(), "id=1", "pages/index/index", &buf,
+		WithCodeEnvVersion(EnvTrial),
+		WithCodeWidth(300),
+		WithCodeLineColor(255, 0, 0),
+		WithCodeHyaline(),
+		WithCodeCheckPath(false),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", meta.ContentType)
+	assert.Equal(t, "\x89PNG", buf.String())
+
+	_, err = mp.GetWXACodeUnlimit(context.Background(), "err", "", io.Discard)
+	assert.EqualError(t, err, "41030 | invalid page rid: 6536148a-1c5a3e9c-2e0a4b1f")
+
+	_, err = mp.GetWXACodeUnlimit(context.Background(), "id=1", "/pages/index", io.Discard)
+	assert.NotNil(t, err)
+
+	_, err = mp.GetWXACodeUnlimit(context.Background(), "id=1", "", io.Discard, WithCodeWidth(100))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,341 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// 跳转小程序类型
+const (
+	MPStateDeveloper = "developer" // 开发版
+	MPStateTrial     = "trial"     // 体验版
+	MPStateFormal    = "formal"    // 正式版
+)
+
+var (
+	subscribeNumberRegex = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+	subscribeLetterRegex = regexp.MustCompile(`^[A-Za-z]+$`)
+	subscribePhoneRegex  = regexp.MustCompile(`^[\d+\-() ]+$`)
+	subscribeAmountRegex = regexp.MustCompile(`^[¥￥$€£]?\d{1,10}(\.\d{1,2})?元?$`)
+
+	// 支持：15:01、2019年10月1日、2019年10月1日 15:01、2019-10-01 15:01，以及用「~」连接的时间段
+	subscribeDateTimeRegex = regexp.MustCompile(`^((\d{4}[年/\-.])?\d{1,2}[月/\-.]\d{1,2}日?)?\s*(\d{1,2}:\d{2}(:\d{2})?)?$`)
+)
+
+// SubscribeValue 订阅消息数据项
+type SubscribeValue struct {
+	Value string `json:"value"`
+}
+
+// SubscribeData 订阅消息数据，Key为模板关键词(如：thing1、number2、time3)
+type SubscribeData map[string]*SubscribeValue
+
+// Set 设置数据项
+func (d SubscribeData) Set(key, value string) SubscribeData {
+	d[key] = &SubscribeValue{Value: value}
+	return d
+}
+
+// Validate 根据关键词类型(Key去掉末尾数字，如：thing1 -> thing)校验数据项的格式和长度
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/sendMessage.html)
+func (d SubscribeData) Validate() error {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := d[k]
+		if v == nil {
+			return fmt.Errorf("subscribe data(%s): value is nil", k)
+		}
+		if err := validateSubscribeValue(strings.TrimRight(k, "0123456789"), v.Value); err != nil {
+			return fmt.Errorf("subscribe data(%s): %w", k, err)
+		}
+	}
+	return nil
+}
+
+func validateSubscribeValue(kind, value string) error {
+	n := utf8.RuneCountInString(value)
+	if n == 0 {
+		return fmt.Errorf("%s can not be empty", kind)
+	}
+
+	maxLen := func(limit int) error {
+		if n > limit {
+			return fmt.Errorf("%s length must be <= %d (got %d)", kind, limit, n)
+		}
+		return nil
+	}
+
+	switch kind {
+	case "thing":
+		return maxLen(20)
+	case "number":
+		if !subscribeNumberRegex.MatchString(value) {
+			return fmt.Errorf("number must be numeric (got %q)", value)
+		}
+		return maxLen(32)
+	case "letter":
+		if !subscribeLetterRegex.MatchString(value) {
+			return fmt.Errorf("letter must be letters only (got %q)", value)
+		}
+		return maxLen(32)
+	case "symbol":
+		return maxLen(5)
+	case "character_string":
+		for _, r := range value {
+			if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+				return fmt.Errorf("character_string must be digits, letters or symbols (got %q)", value)
+			}
+		}
+		return maxLen(32)
+	case "time", "date":
+		for _, part := range strings.Split(value, "~") {
+			if s := strings.TrimSpace(part); len(s) == 0 || !subscribeDateTimeRegex.MatchString(s) {
+				return fmt.Errorf("%s format invalid (got %q)", kind, value)
+			}
+		}
+		return nil
+	case "amount":
+		if !subscribeAmountRegex.MatchString(value) {
+			return fmt.Errorf("amount format invalid (got %q)", value)
+		}
+		return nil
+	case "phone_number":
+		if !subscribePhoneRegex.MatchString(value) {
+			return fmt.Errorf("phone_number format invalid (got %q)", value)
+		}
+		return maxLen(17)
+	case "car_number":
+		return maxLen(8)
+	case "name":
+		if len(value) == n { // 纯英文
+			return maxLen(20)
+		}
+		return maxLen(10)
+	case "phrase":
+		return maxLen(5)
+	}
+	return nil
+}
+
+// SubscribeMsg 订阅消息
+type SubscribeMsg struct {
+	ToUser           string        `json:"touser"`
+	TemplateID       string        `json:"template_id"`
+	Page             string        `json:"page,omitempty"`
+	MiniProgramState string        `json:"miniprogram_state,omitempty"`
+	Lang             string        `json:"lang,omitempty"`
+	Data             SubscribeData `json:"data"`
+}
+
+// PubTemplateTitle 模板库标题
+type PubTemplateTitle struct {
+	TID        int64  `json:"tid"`
+	Title      string `json:"title"`
+	Type       int    `json:"type"` // 2-一次性订阅，3-长期订阅
+	CategoryID string `json:"categoryId"`
+}
+
+// PubTemplateKeyword 模板库关键词
+type PubTemplateKeyword struct {
+	KID     int64  `json:"kid"`
+	Name    string `json:"name"`
+	Example string `json:"example"`
+	Rule    string `json:"rule"`
+}
+
+// SubscribeTemplate 个人模板
+type SubscribeTemplate struct {
+	PriTmplID string `json:"priTmplId"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	Example   string `json:"example"`
+	Type      int    `json:"type"`
+}
+
+// TemplateCategory 类目
+type TemplateCategory struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// SendSubscribeMsg 发送订阅消息(发送前会校验数据项)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/sendMessage.html)
+func (mp *MiniProgram) SendSubscribeMsg(ctx context.Context, msg *SubscribeMsg) error {
+	if err := msg.Data.Validate(); err != nil {
+		return err
+	}
+
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = mp.PostJSON(ctx, "/cgi-bin/message/subscribe/send", params)
+	return err
+}
+
+// GetTemplateCategory 获取小程序账号的类目
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/getCategory.html)
+func (mp *MiniProgram) GetTemplateCategory(ctx context.Context) ([]*TemplateCategory, error) {
+	ret, err := mp.GetJSON(ctx, "/wxaapi/newtmpl/getcategory", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*TemplateCategory
+	if err = unmarshalResult(ret.Get("data"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetPubTemplateTitles 获取类目下的公共模板标题，返回总数和当前页数据(limit 最大30)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/getPubTemplateTitleList.html)
+func (mp *MiniProgram) GetPubTemplateTitles(ctx context.Context, categoryIDs []int64, start, limit int) (int64, []*PubTemplateTitle, error) {
+	ids := make([]string, 0, len(categoryIDs))
+	for _, v := range categoryIDs {
+		ids = append(ids, strconv.FormatInt(v, 10))
+	}
+
+	query := url.Values{}
+
+	query.Set("ids", strings.Join(ids, ","))
+	query.Set("start", strconv.Itoa(start))
+	query.Set("limit", strconv.Itoa(limit))
+
+	ret, err := mp.GetJSON(ctx, "/wxaapi/newtmpl/getpubtemplatetitles", query)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var list []*PubTemplateTitle
+	if err = unmarshalResult(ret.Get("data"), &list); err != nil {
+		return 0, nil, err
+	}
+	return ret.Get("count").Int(), list, nil
+}
+
+// GetPubTemplateKeywords 获取模板标题下的关键词
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/getPubTemplateKeyWordsById.html)
+func (mp *MiniProgram) GetPubTemplateKeywords(ctx context.Context, tid int64) ([]*PubTemplateKeyword, error) {
+	query := url.Values{}
+	query.Set("tid", strconv.FormatInt(tid, 10))
+
+	ret, err := mp.GetJSON(ctx, "/wxaapi/newtmpl/getpubtemplatekeywords", query)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*PubTemplateKeyword
+	if err = unmarshalResult(ret.Get("data"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AddSubscribeTemplate 组合模板并添加至个人模板库，返回priTmplId
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/addMessageTemplate.html)
+func (mp *MiniProgram) AddSubscribeTemplate(ctx context.Context, tid int64, kidList []int64, sceneDesc string) (string, error) {
+	if n := len(kidList); n < 2 || n > 5 {
+		return "", fmt.Errorf("kidList length must be in [2, 5] (got %d)", n)
+	}
+
+	params := lib.X{
+		"tid":       strconv.FormatInt(tid, 10),
+		"kidList":   kidList,
+		"sceneDesc": sceneDesc,
+	}
+
+	ret, err := mp.PostJSON(ctx, "/wxaapi/newtmpl/addtemplate", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("priTmplId").String(), nil
+}
+
+// GetSubscribeTemplates 获取个人模板列表
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/getMessageTemplateList.html)
+func (mp *MiniProgram) GetSubscribeTemplates(ctx context.Context) ([]*SubscribeTemplate, error) {
+	ret, err := mp.GetJSON(ctx, "/wxaapi/newtmpl/gettemplate", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*SubscribeTemplate
+	if err = unmarshalResult(ret.Get("data"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DelSubscribeTemplate 删除个人模板
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/subscribe-message/deleteMessageTemplate.html)
+func (mp *MiniProgram) DelSubscribeTemplate(ctx context.Context, priTmplID string) error {
+	_, err := mp.PostJSON(ctx, "/wxaapi/newtmpl/deltemplate", lib.X{"priTmplId": priTmplID})
+	return err
+}
+
+// UniformMPTemplateMsg 统一服务消息(公众号模板消息)
+type UniformMPTemplateMsg struct {
+	AppID       string                   `json:"appid"`
+	TemplateID  string                   `json:"template_id"`
+	URL         string                   `json:"url,omitempty"`
+	MiniProgram *TemplateMiniProgram     `json:"miniprogram,omitempty"`
+	Data        map[string]*TemplateData `json:"data"`
+}
+
+// SendUniformMsg 下发统一服务消息(通过小程序给同主体公众号的用户发送模板消息)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/mp-message-management/uniform-message/sendUniformMessage.html)
+func (mp *MiniProgram) SendUniformMsg(ctx context.Context, toUser string, msg *UniformMPTemplateMsg) error {
+	params := lib.X{
+		"touser":          toUser,
+		"mp_template_msg": msg,
+	}
+
+	_, err := mp.PostJSON(ctx, "/cgi-bin/message/wxopen/template/uniform_send", params)
+	return err
+}
+
+// SendKFMsg 发送客服消息(小程序仅支持：text、image、link、miniprogrampage)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/kf-mgnt/kf-message/sendCustomMessage.html)
+func (mp *MiniProgram) SendKFMsg(ctx context.Context, msg *KFMsg) error {
+	switch msg.MsgType {
+	case KFMsgText, KFMsgImage, KFMsgLink, KFMsgMiniProgramPage:
+	default:
+		return fmt.Errorf("msgtype(%s) is not supported by miniprogram", msg.MsgType)
+	}
+
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = mp.PostJSON(ctx, "/cgi-bin/message/custom/send", params)
+	return err
+}
+
+// SetKFTyping 下发客服输入状态，typing=false 表示取消
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/kf-mgnt/kf-message/setTyping.html)
+func (mp *MiniProgram) SetKFTyping(ctx context.Context, openid string, typing bool) error {
+	command := "Typing"
+	if !typing {
+		command = "CancelTyping"
+	}
+
+	_, err := mp.PostJSON(ctx, "/cgi-bin/message/custom/typing", lib.X{"touser": openid, "command": command})
+	return err
+}
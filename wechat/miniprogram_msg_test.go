@@ -0,0 +1,33 @@
+package wechat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribeDataValidate(t *testing.T) {
+	data := SubscribeData{}
+	data.Set("thing1", "您的订单已发货").
+		Set("number2", "20231019001").
+		Set("time3", "2019年10月1日 15:01").
+		Set("date4", "2019-10-01 ~ 2019-10-07").
+		Set("amount5", "¥100.50").
+		Set("character_string6", "TS-2019").
+		Set("phrase7", "已发货").
+		Set("name8", "Shenghui")
+	assert.Nil(t, data.Validate())
+
+	tests := []SubscribeData{
+		SubscribeData{}.Set("thing1", "超过二十个字符的事物描述超过二十个字符的事物描述"),
+		SubscribeData{}.Set("number1", "12a"),
+		SubscribeData{}.Set("time1", "明天下午"),
+		SubscribeData{}.Set("letter1", "abc1"),
+		SubscribeData{}.Set("character_string1", "中文"),
+		SubscribeData{}.Set("phrase1", "超过五个汉字"),
+		SubscribeData{}.Set("thing1", ""),
+	}
+	for _, v := range tests {
+		assert.NotNil(t, v.Validate())
+	}
+}
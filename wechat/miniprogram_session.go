@@ -0,0 +1,275 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/xhash"
+)
+
+// ErrSessionNotFound 会话不存在或已过期
+var ErrSessionNotFound = errors.New("session not found or expired")
+
+// MPSession 小程序登录会话
+type MPSession struct {
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	SessionKey string `json:"session_key"`
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// Watermark 加密数据水印
+type Watermark struct {
+	AppID     string `json:"appid"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// SessionStore 会话存储，Key为业务自定义的会话ID
+type SessionStore interface {
+	// Get 获取会话，不存在时返回 ErrSessionNotFound
+	Get(ctx context.Context, sessionID string) (*MPSession, error)
+
+	// Set 保存会话
+	Set(ctx context.Context, sessionID string, sess *MPSession, ttl time.Duration) error
+
+	// Del 删除会话
+	Del(ctx context.Context, sessionID string) error
+}
+
+// SessionManager 小程序会话管理
+type SessionManager struct {
+	mp     *MiniProgram
+	store  SessionStore
+	ttl    time.Duration
+	maxAge time.Duration
+	genID  func() string
+}
+
+// Login 使用 wx.login 获取的code登录，返回新生成的会话ID
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/user-login/code2Session.html)
+func (sm *SessionManager) Login(ctx context.Context, code string) (string, *MPSession, error) {
+	ret, err := sm.mp.Code2Session(ctx, code)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sess := &MPSession{
+		OpenID:     ret.Get("openid").String(),
+		UnionID:    ret.Get("unionid").String(),
+		SessionKey: ret.Get("session_key").String(),
+		CreatedAt:  time.Now().Unix(),
+	}
+
+	sessionID := sm.genID()
+	if err = sm.store.Set(ctx, sessionID, sess, sm.ttl); err != nil {
+		return "", nil, err
+	}
+	return sessionID, sess, nil
+}
+
+// Session 获取会话
+func (sm *SessionManager) Session(ctx context.Context, sessionID string) (*MPSession, error) {
+	return sm.store.Get(ctx, sessionID)
+}
+
+// Logout 删除会话
+func (sm *SessionManager) Logout(ctx context.Context, sessionID string) error {
+	return sm.store.Del(ctx, sessionID)
+}
+
+// CheckSessionKey 检验会话的 session_key 是否仍然有效
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/user-login/checkSessionKey.html)
+func (sm *SessionManager) CheckSessionKey(ctx context.Context, sessionID string) error {
+	sess, err := sm.store.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+
+	query.Set("openid", sess.OpenID)
+	query.Set("signature", xhash.HMacSHA256(sess.SessionKey, ""))
+	query.Set("sig_method", "hmac_sha256")
+
+	_, err = sm.mp.GetJSON(ctx, "/wxa/checksession", query)
+	return err
+}
+
+// ResetSessionKey 重置会话的 session_key 并更新存储
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/user-login/ResetUserSessionKey.html)
+func (sm *SessionManager) ResetSessionKey(ctx context.Context, sessionID string) (*MPSession, error) {
+	sess, err := sm.store.Get(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+
+	query.Set("openid", sess.OpenID)
+	query.Set("signature", xhash.HMacSHA256(sess.SessionKey, ""))
+	query.Set("sig_method", "hmac_sha256")
+
+	ret, err := sm.mp.GetJSON(ctx, "/wxa/resetusersessionkey", query)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.SessionKey = ret.Get("session_key").String()
+	if err = sm.store.Set(ctx, sessionID, sess, sm.ttl); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+// DecodeEncryptData 使用会话的 session_key 解密数据(如：wx.getUserInfo)，校验水印后解析到 v
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/framework/open-ability/signature.html)
+func (sm *SessionManager) DecodeEncryptData(ctx context.Context, sessionID, iv, encryptData string, v any) error {
+	sess, err := sm.store.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	b, err := sm.mp.DecodeEncryptData(sess.SessionKey, iv, encryptData)
+	if err != nil {
+		return err
+	}
+
+	if err = VerifyWatermark(gjson.GetBytes(b, "watermark"), sm.mp.appid, sm.maxAge); err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// VerifyWatermark 校验加密数据中的水印，maxAge<=0 时不校验时间戳
+func VerifyWatermark(ret gjson.Result, appid string, maxAge time.Duration) error {
+	if !ret.Exists() {
+		return errors.New("watermark not found")
+	}
+
+	wm := new(Watermark)
+	if err := json.Unmarshal([]byte(ret.Raw), wm); err != nil {
+		return err
+	}
+	if wm.AppID != appid {
+		return fmt.Errorf("watermark appid mismatch, want: %s, got: %s", appid, wm.AppID)
+	}
+	if maxAge > 0 {
+		if d := time.Since(time.Unix(wm.Timestamp, 0)); d > maxAge || d < -maxAge {
+			return fmt.Errorf("watermark timestamp(%d) expired", wm.Timestamp)
+		}
+	}
+	return nil
+}
+
+// SessionOption 会话管理设置项
+type SessionOption func(sm *SessionManager)
+
+// WithSessionTTL 设置会话有效期(默认：7天)
+func WithSessionTTL(ttl time.Duration) SessionOption {
+	return func(sm *SessionManager) {
+		sm.ttl = ttl
+	}
+}
+
+// WithWatermarkMaxAge 设置加密数据水印时间戳的最大时差(默认：10分钟，<=0 表示不校验)
+func WithWatermarkMaxAge(d time.Duration) SessionOption {
+	return func(sm *SessionManager) {
+		sm.maxAge = d
+	}
+}
+
+// WithSessionIDGenerator 设置会话ID生成方法(默认：32位随机串)
+func WithSessionIDGenerator(fn func() string) SessionOption {
+	return func(sm *SessionManager) {
+		sm.genID = fn
+	}
+}
+
+// NewSessionManager 生成小程序会话管理实例
+func NewSessionManager(mp *MiniProgram, store SessionStore, options ...SessionOption) *SessionManager {
+	sm := &SessionManager{
+		mp:     mp,
+		store:  store,
+		ttl:    7 * 24 * time.Hour,
+		maxAge: 10 * time.Minute,
+		genID:  func() string { return lib.Nonce(32) },
+	}
+	for _, f := range options {
+		f(sm)
+	}
+	return sm
+}
+
+type memSession struct {
+	sess     *MPSession
+	expireAt time.Time
+}
+
+// MemSessionStore 基于内存的会话存储(仅适用于单实例部署或测试)
+type MemSessionStore struct {
+	mutex sync.RWMutex
+	data  map[string]*memSession
+}
+
+// Get 获取会话
+func (s *MemSessionStore) Get(ctx context.Context, sessionID string) (*MPSession, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	v, ok := s.data[sessionID]
+	if !ok || (!v.expireAt.IsZero() && time.Now().After(v.expireAt)) {
+		return nil, ErrSessionNotFound
+	}
+
+	sess := *v.sess
+	return &sess, nil
+}
+
+// Set 保存会话
+func (s *MemSessionStore) Set(ctx context.Context, sessionID string, sess *MPSession, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cp := *sess
+	v := &memSession{sess: &cp}
+	if ttl > 0 {
+		v.expireAt = time.Now().Add(ttl)
+	}
+	s.data[sessionID] = v
+
+	// 顺便清理过期会话
+	now := time.Now()
+	for k, v := range s.data {
+		if !v.expireAt.IsZero() && now.After(v.expireAt) {
+			delete(s.data, k)
+		}
+	}
+	return nil
+}
+
+// Del 删除会话
+func (s *MemSessionStore) Del(ctx context.Context, sessionID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.data, sessionID)
+	return nil
+}
+
+// NewMemSessionStore 生成基于内存的会话存储
+func NewMemSessionStore() *MemSessionStore {
+	return &MemSessionStore{
+		data: make(map[string]*memSession),
+	}
+}
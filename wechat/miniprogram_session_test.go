@@ -0,0 +1,75 @@
+package wechat
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+)
+
+func TestSessionDecodeEncryptData(t *testing.T) {
+	ctx := context.Background()
+
+	mp := NewMiniProgram("wx4f4bc4dec97d474b", "secret")
+	store := NewMemSessionStore()
+	sm := NewSessionManager(mp, store, WithSessionIDGenerator(func() string { return "sid" }))
+
+	key := []byte("0123456789abcdef")
+	iv := []byte("fedcba9876543210")
+	assert.Nil(t, store.Set(ctx, "sid", &MPSession{OpenID: "oGZUI0egBJY1zhBYw2KhdUfwVJJE", SessionKey: base64.StdEncoding.EncodeToString(key)}, time.Minute))
+
+	encrypt := func(appid string, ts int64) string {
+		plain := fmt.Sprintf(`{"phoneNumber":"13580006666","watermark":{"appid":"%s","timestamp":%d}}`, appid, ts)
+		ct, err := xcrypto.AESEncryptCBC(key, iv, []byte(plain))
+		assert.Nil(t, err)
+		return ct.String()
+	}
+	ivStr := base64.StdEncoding.EncodeToString(iv)
+
+	ret := struct {
+		PhoneNumber string    `json:"phoneNumber"`
+		Watermark   Watermark `json:"watermark"`
+	}{}
+	assert.Nil(t, sm.DecodeEncryptData(ctx, "sid", ivStr, encrypt("wx4f4bc4dec97d474b", time.Now().Unix()), &ret))
+	assert.Equal(t, "13580006666", ret.PhoneNumber)
+	assert.Equal(t, "wx4f4bc4dec97d474b", ret.Watermark.AppID)
+
+	assert.NotNil(t, sm.DecodeEncryptData(ctx, "sid", ivStr, encrypt("wx0000000000000000", time.Now().Unix()), nil))
+	assert.NotNil(t, sm.DecodeEncryptData(ctx, "sid", ivStr, encrypt("wx4f4bc4dec97d474b", time.Now().Add(-time.Hour).Unix()), nil))
+	assert.ErrorIs(t, sm.DecodeEncryptData(ctx, "none", ivStr, encrypt("wx4f4bc4dec97d474b", time.Now().Unix()), nil), ErrSessionNotFound)
+
+	assert.Nil(t, sm.Logout(ctx, "sid"))
+	_, err := sm.Session(ctx, "sid")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestMemSessionStore(t *testing.T) {
+	store := NewMemSessionStore()
+	ctx := context.Background()
+
+	sess := &MPSession{OpenID: "OPENID", SessionKey: "SESSION_KEY"}
+	assert.Nil(t, store.Set(ctx, "SID", sess, time.Minute))
+
+	// 修改传入/取出的会话不影响已保存的会话
+	sess.SessionKey = "CHANGED"
+
+	ret, err := store.Get(ctx, "SID")
+	assert.Nil(t, err)
+	assert.Equal(t, "SESSION_KEY", ret.SessionKey)
+
+	ret.OpenID = "CHANGED"
+
+	ret, err = store.Get(ctx, "SID")
+	assert.Nil(t, err)
+	assert.Equal(t, "OPENID", ret.OpenID)
+
+	assert.Nil(t, store.Del(ctx, "SID"))
+
+	_, err = store.Get(ctx, "SID")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
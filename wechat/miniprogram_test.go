@@ -0,0 +1,214 @@
+package wechat
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+)
+
+func genRSAKey(t *testing.T) (*xcrypto.PrivateKey, *xcrypto.PublicKey) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	prvKey, err := xcrypto.NewPrivateKeyFromPemBlock(xcrypto.RSA_PKCS1, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+	}))
+	assert.Nil(t, err)
+
+	pubKey, err := xcrypto.NewPublicKeyFromPemBlock(xcrypto.RSA_PKCS1, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey),
+	}))
+	assert.Nil(t, err)
+
+	return prvKey, pubKey
+}
+
+func TestSafeModeKeyRotation(t *testing.T) {
+	appid := "wx4f4bc4dec97d474b"
+	aesSN := "fa05fe1e5bcc79b81ad5ad4b58acf787"
+	aeskey := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+	appPrvKey, appPubKey := genRSAKey(t)
+	oldPrvKey, oldPubKey := genRSAKey(t)
+	newPrvKey, newPubKey := genRSAKey(t)
+
+	pssSign := func(key *xcrypto.PrivateKey, s string) string {
+		b, err := key.SignPSS(crypto.SHA256, []byte(s), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		assert.Nil(t, err)
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	var srvURL string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqURL := srvURL + r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+
+		// 验签
+		sign, _ := base64.StdEncoding.DecodeString(r.Header.Get(HeaderMPSignature))
+		err := appPubKey.VerifyPSS(crypto.SHA256, []byte(fmt.Sprintf("%s\n%s\n%s\n%s", reqURL, appid, r.Header.Get(HeaderMPTimestamp), body)), sign,
+			&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		assert.Nil(t, err)
+
+		// 解密
+		key, _ := base64.StdEncoding.DecodeString(aeskey)
+		ret := gjson.ParseBytes(body)
+		iv, _ := base64.StdEncoding.DecodeString(ret.Get("iv").String())
+		data, _ := base64.StdEncoding.DecodeString(ret.Get("data").String())
+		tag, _ := base64.StdEncoding.DecodeString(ret.Get("authtag").String())
+		plain, err := xcrypto.AESDecryptGCM(key, iv, append(data, tag...), []byte(fmt.Sprintf("%s|%s|%s|%s", reqURL, appid, r.Header.Get(HeaderMPTimestamp), aesSN)), nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "oGZUI0egBJY1zhBYw2KhdUfwVJJE", gjson.GetBytes(plain, "openid").String())
+		assert.Empty(t, r.URL.Query().Get("openid"))
+
+		// 加密返回
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		respIV := lib.NonceByte(12)
+		ct, err := xcrypto.AESEncryptGCM(key, respIV, []byte(`{"errcode":0,"errmsg":"ok","risk_rank":1}`), []byte(fmt.Sprintf("%s|%s|%s|%s", reqURL, appid, now, aesSN)), nil)
+		assert.Nil(t, err)
+		respBody, _ := json.Marshal(lib.X{
+			"iv":      base64.StdEncoding.EncodeToString(respIV),
+			"data":    base64.StdEncoding.EncodeToString(ct.Data()),
+			"authtag": base64.StdEncoding.EncodeToString(ct.Tag()),
+		})
+
+		signStr := fmt.Sprintf("%s\n%s\n%s\n%s", reqURL, appid, now, respBody)
+
+		w.Header().Set(HeaderMPAppID, appid)
+		w.Header().Set(HeaderMPTimestamp, now)
+		w.Header().Set(HeaderMPSerial, "new_sn")
+		w.Header().Set(HeaderMPSignature, pssSign(newPrvKey, signStr))
+		w.Header().Set(HeaderMPSerialDeprecated, "old_sn")
+		w.Header().Set(HeaderMPSignatureDeprecated, pssSign(oldPrvKey, signStr))
+		w.Header().Set(lib.HeaderContentType, lib.ContentJSON)
+		_, _ = w.Write(respBody)
+	}))
+	defer srv.Close()
+
+	srvURL = srv.URL
+
+	mp := NewMiniProgram(appid, "secret", WithMPAesKey(aesSN, aeskey), WithMPPrivateKey(appPrvKey), WithMPPublicKey("old_sn", oldPubKey))
+	mp.host = srv.URL
+	mp.token.Store("ACCESS_TOKEN")
+
+	query := url.Values{"openid": {"oGZUI0egBJY1zhBYw2KhdUfwVJJE"}}
+
+	// 仅有旧证书，使用 Deprecated 签名验签
+	ret, err := mp.SafeGetJSON(context.Background(), "/wxa/getuserriskrank", query)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), ret.Get("risk_rank").Int())
+
+	// 新证书生效后删除旧证书
+	mp.AddPlatformPublicKey("new_sn", newPubKey)
+	mp.DelPlatformPublicKey("old_sn")
+	_, err = mp.SafeGetJSON(context.Background(), "/wxa/getuserriskrank", query)
+	assert.Nil(t, err)
+
+	// 无可用证书
+	mp.DelPlatformPublicKey("new_sn")
+	_, err = mp.SafeGetJSON(context.Background(), "/wxa/getuserriskrank", query)
+	assert.NotNil(t, err)
+}
+
+func genTestCertPEM(t *testing.T, pub, priv any) string {
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "wechat platform"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, pub, priv)
+	assert.Nil(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestLoadPlatformCerts(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	rsaCert := genTestCertPEM(t, &rsaKey.PublicKey, rsaKey)
+	ecCert := genTestCertPEM(t, &ecKey.PublicKey, ecKey)
+
+	var certData atomic.Value
+	certData.Store(rsaCert)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/wxa/getwxaapicert", r.URL.Path)
+		assert.Equal(t, "ACCESS_TOKEN", r.URL.Query().Get(AccessToken))
+
+		b, _ := json.Marshal(lib.X{
+			"errcode": 0,
+			"errmsg":  "ok",
+			"cert_list": []lib.X{
+				{
+					"cert_type":           "RSA",
+					"cert_sn":             "platform_sn",
+					"cert_data":           certData.Load().(string),
+					"cert_effective_time": time.Now().Unix(),
+					"cert_expire_time":    time.Now().Add(time.Hour).Unix(),
+				},
+			},
+		})
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	_, oldPubKey := genRSAKey(t)
+
+	mp := NewMiniProgram("wx4f4bc4dec97d474b", "secret", WithMPPublicKey("old_sn", oldPubKey))
+	mp.host = srv.URL
+	mp.token.Store("ACCESS_TOKEN")
+
+	assert.NotNil(t, mp.sfMode.publicKey("old_sn"))
+	assert.Nil(t, mp.AutoLoadPlatformCerts(time.Hour))
+	assert.NotNil(t, mp.sfMode.publicKey("platform_sn"))
+	// 合并而非替换，已有的公钥保留
+	assert.NotNil(t, mp.sfMode.publicKey("old_sn"))
+
+	_, addPubKey := genRSAKey(t)
+	mp.AddPlatformPublicKey("add_sn", addPubKey)
+
+	// 定时加载同样保留已有的公钥
+	_, err = mp.LoadPlatformCerts(context.Background())
+	assert.Nil(t, err)
+	assert.NotNil(t, mp.sfMode.publicKey("old_sn"))
+	assert.NotNil(t, mp.sfMode.publicKey("add_sn"))
+
+	mp.DelPlatformPublicKey("old_sn")
+	assert.Nil(t, mp.sfMode.publicKey("old_sn"))
+
+	// 非RSA证书返回错误，且不替换当前的平台公钥
+	certData.Store(ecCert)
+
+	_, err = mp.LoadPlatformCerts(context.Background())
+	assert.NotNil(t, err)
+	assert.NotNil(t, mp.sfMode.publicKey("platform_sn"))
+}
@@ -0,0 +1,91 @@
+package wechat
+
+import (
+	"context"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// PhoneInfo 用户手机号信息
+type PhoneInfo struct {
+	PhoneNumber     string    `json:"phoneNumber"`     // 用户绑定的手机号(国外手机号会有区号)
+	PurePhoneNumber string    `json:"purePhoneNumber"` // 没有区号的手机号
+	CountryCode     string    `json:"countryCode"`     // 区号
+	Watermark       Watermark `json:"watermark"`
+}
+
+// RiskScene 用户风险等级的场景
+type RiskScene int
+
+const (
+	RiskSceneRegister  RiskScene = 0 // 注册
+	RiskSceneMarketing RiskScene = 1 // 营销作弊
+)
+
+// UserRiskRank 用户风险等级查询参数
+type UserRiskRank struct {
+	OpenID       string    `json:"openid"`
+	Scene        RiskScene `json:"scene"`
+	ClientIP     string    `json:"client_ip"`
+	MobileNO     string    `json:"mobile_no,omitempty"`
+	EmailAddress string    `json:"email_address,omitempty"`
+	ExtendedInfo string    `json:"extended_info,omitempty"`
+	IsTest       bool      `json:"is_test,omitempty"`
+}
+
+// UserRiskResult 用户风险等级
+type UserRiskResult struct {
+	RiskRank int   `json:"risk_rank"` // 0-4，数值越大风险越高
+	UnoinID  int64 `json:"unoin_id"`  // 唯一请求标识，用于问题排查(微信返回字段即为unoin_id)
+}
+
+// GetUserPhoneNumber 通过 getPhoneNumber 返回的code获取用户手机号，并校验水印中的appid
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/user-info/phone-number/getPhoneNumber.html)
+func (mp *MiniProgram) GetUserPhoneNumber(ctx context.Context, code string) (*PhoneInfo, error) {
+	ret, err := mp.PostJSON(ctx, "/wxa/business/getuserphonenumber", lib.X{"code": code})
+	if err != nil {
+		return nil, err
+	}
+
+	phone := ret.Get("phone_info")
+	if err = VerifyWatermark(phone.Get("watermark"), mp.appid, 0); err != nil {
+		return nil, err
+	}
+
+	info := new(PhoneInfo)
+	if err = unmarshalResult(phone, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// GetUserRiskRank 获取用户安全等级
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/sec-center/safety-control-capability/getUserRiskRank.html)
+func (mp *MiniProgram) GetUserRiskRank(ctx context.Context, rank *UserRiskRank) (*UserRiskResult, error) {
+	params, err := lib.ToX(rank)
+	if err != nil {
+		return nil, err
+	}
+	params["appid"] = mp.appid
+
+	ret, err := mp.PostJSON(ctx, "/wxa/getuserriskrank", params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(UserRiskResult)
+	if err = unmarshalResult(ret, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetPluginOpenPID 通过插件内 wx.pluginLogin 返回的code获取插件用户的 openpid
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/user-info/basic-info/getPluginOpenPId.html)
+func (mp *MiniProgram) GetPluginOpenPID(ctx context.Context, code string) (string, error) {
+	ret, err := mp.PostJSON(ctx, "/wxa/getpluginopenpid", lib.X{"code": code})
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("openpid").String(), nil
+}
@@ -0,0 +1,43 @@
+package wechat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetUserPhoneNumber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"errcode": 0,
+			"errmsg": "ok",
+			"phone_info": {
+				"phoneNumber": "+86-13580006666",
+				"purePhoneNumber": "13580006666",
+				"countryCode": "86",
+				"watermark": {"timestamp": 1637744274, "appid": "wx4f4bc4dec97d474b"}
+			}
+		}`))
+	}))
+	defer srv.Close()
+
+	mp := NewMiniProgram("wx4f4bc4dec97d474b", "secret")
+	mp.host = srv.URL
+	mp.token.Store("ACCESS_TOKEN")
+
+	info, err := mp.GetUserPhoneNumber(context.Background(), "code")
+	assert.Nil(t, err)
+	assert.Equal(t, "13580006666", info.PurePhoneNumber)
+	assert.Equal(t, "86", info.CountryCode)
+	assert.Equal(t, int64(1637744274), info.Watermark.Timestamp)
+
+	other := NewMiniProgram("wx0000000000000000", "secret")
+	other.host = srv.URL
+	other.token.Store("ACCESS_TOKEN")
+
+	_, err = other.GetUserPhoneNumber(context.Background(), "code")
+	assert.NotNil(t, err)
+}
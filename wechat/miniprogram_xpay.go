@@ -0,0 +1,397 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+	"github.com/shenghui0779/sdk-go/lib/xhash"
+)
+
+// XPayEnv 虚拟支付环境
+type XPayEnv int
+
+const (
+	XPayEnvProd    XPayEnv = 0 // 现网环境
+	XPayEnvSandbox XPayEnv = 1 // 沙箱环境
+)
+
+// XPayMode 虚拟支付模式
+type XPayMode string
+
+const (
+	XPayModeGoods XPayMode = "short_series_goods" // 道具直购
+	XPayModeCoin  XPayMode = "short_series_coin"  // 代币充值
+)
+
+// 虚拟支付推送事件
+const (
+	EventXPayGoodsDeliver = "xpay_goods_deliver_notify" // 道具发货推送
+	EventXPayCoinPay      = "xpay_coin_pay_notify"      // 代币支付推送
+	EventXPayRefund       = "xpay_refund_notify"        // 退款推送
+)
+
+// XPaySignData 虚拟支付 wx.requestVirtualPayment 的 signData
+type XPaySignData struct {
+	OfferID      string  `json:"offerId"`
+	BuyQuantity  int     `json:"buyQuantity"`
+	Env          XPayEnv `json:"env"`
+	CurrencyType string  `json:"currencyType"`
+	ProductID    string  `json:"productId,omitempty"` // 道具直购必填
+	GoodsPrice   int     `json:"goodsPrice,omitempty"`
+	OutTradeNo   string  `json:"outTradeNo"`
+	Attach       string  `json:"attach"`
+}
+
+// XPayParams 虚拟支付 wx.requestVirtualPayment 的参数
+type XPayParams struct {
+	SignData  string   `json:"signData"`
+	Mode      XPayMode `json:"mode"`
+	PaySig    string   `json:"paySig"`
+	Signature string   `json:"signature"`
+}
+
+// XPayOrder 虚拟支付订单
+type XPayOrder struct {
+	OrderID        string `json:"order_id"`
+	CreateTime     int64  `json:"create_time"`
+	UpdateTime     int64  `json:"update_time"`
+	Status         int    `json:"status"`   // 0-订单初始化 1-订单创建成功 2-订单已支付 3-支付中 4-支付失败 5-用户退款成功 6-订单已退款 7-订单取消 8-订单已发货
+	BizType        int    `json:"biz_type"` // 0-短剧
+	OrderFee       int64  `json:"order_fee"`
+	CouponFee      int64  `json:"coupon_fee"`
+	PaidFee        int64  `json:"paid_fee"`
+	OrderType      int    `json:"order_type"` // 0-支付单 1-退款单
+	RefundFee      int64  `json:"refund_fee"`
+	PaidTime       int64  `json:"paid_time"`
+	ProvideTime    int64  `json:"provide_time"`
+	BizMeta        string `json:"biz_meta"`
+	EnvType        int    `json:"env_type"`
+	Token          string `json:"token"`
+	LeftFee        int64  `json:"left_fee"`
+	WxOrderID      string `json:"wx_order_id"`
+	ChannelOrderID string `json:"channel_order_id"`
+	WxPayOrderID   string `json:"wxpay_order_id"`
+	SettTime       int64  `json:"sett_time"`
+	SettState      int    `json:"sett_state"`
+}
+
+// XPayRefund 虚拟支付退款参数
+type XPayRefund struct {
+	OpenID        string `json:"openid"`
+	OrderID       string `json:"order_id,omitempty"`    // 与 wx_order_id 二选一
+	WxOrderID     string `json:"wx_order_id,omitempty"` // 与 order_id 二选一
+	RefundOrderID string `json:"refund_order_id"`
+	LeftFee       int64  `json:"left_fee"`
+	RefundFee     int64  `json:"refund_fee"`
+	BizMeta       string `json:"biz_meta,omitempty"`
+	RefundReason  string `json:"refund_reason"` // 0-暂无描述 1-产品问题 2-售后问题 3-意愿问题 4-价格问题 5-其他原因
+	ReqFrom       string `json:"req_from"`      // 1-人工客服退款 2-用户自己退款 3-其他
+}
+
+// XPayRefundResult 虚拟支付退款结果
+type XPayRefundResult struct {
+	RefundOrderID   string `json:"refund_order_id"`
+	RefundWxOrderID string `json:"refund_wx_order_id"`
+	PayOrderID      string `json:"pay_order_id"`
+	PayWxOrderID    string `json:"pay_wx_order_id"`
+}
+
+// XPayWithdraw 虚拟支付提现结果
+type XPayWithdraw struct {
+	WithdrawNo   string `json:"withdraw_no"`
+	WxWithdrawNo string `json:"wx_withdraw_no"`
+}
+
+// XPayWeChatPayInfo 微信支付信息
+type XPayWeChatPayInfo struct {
+	MchOrderNo    string `xml:"MchOrderNo" json:"MchOrderNo"`
+	TransactionID string `xml:"TransactionId" json:"TransactionId"`
+	PaidTime      int64  `xml:"PaidTime" json:"PaidTime"`
+}
+
+// XPayGoodsInfo 道具/代币信息
+type XPayGoodsInfo struct {
+	ProductID   string `xml:"ProductId" json:"ProductId"`
+	Quantity    int    `xml:"Quantity" json:"Quantity"`
+	OrigPrice   int64  `xml:"OrigPrice" json:"OrigPrice"`
+	ActualPrice int64  `xml:"ActualPrice" json:"ActualPrice"`
+	Attach      string `xml:"Attach" json:"Attach"`
+}
+
+// XPayNotify 虚拟支付推送事件(发货、代币支付、退款)
+type XPayNotify struct {
+	ToUserName    string             `xml:"ToUserName" json:"ToUserName"`
+	FromUserName  string             `xml:"FromUserName" json:"FromUserName"`
+	CreateTime    int64              `xml:"CreateTime" json:"CreateTime"`
+	MsgType       string             `xml:"MsgType" json:"MsgType"`
+	Event         string             `xml:"Event" json:"Event"`
+	OpenID        string             `xml:"OpenId" json:"OpenId"`
+	OutTradeNo    string             `xml:"OutTradeNo" json:"OutTradeNo"`
+	Env           XPayEnv            `xml:"Env" json:"Env"`
+	WeChatPayInfo *XPayWeChatPayInfo `xml:"WeChatPayInfo" json:"WeChatPayInfo"`
+	GoodsInfo     *XPayGoodsInfo     `xml:"GoodsInfo" json:"GoodsInfo"` // 道具发货推送
+	CoinInfo      *XPayGoodsInfo     `xml:"CoinInfo" json:"CoinInfo"`   // 代币支付推送
+
+	// 退款推送
+	WxRefundID    string `xml:"WxRefundId" json:"WxRefundId"`
+	MchRefundID   string `xml:"MchRefundId" json:"MchRefundId"`
+	WxOrderID     string `xml:"WxOrderId" json:"WxOrderId"`
+	MchOrderID    string `xml:"MchOrderId" json:"MchOrderId"`
+	RefundFee     int64  `xml:"RefundFee" json:"RefundFee"`
+	RetCode       int    `xml:"RetCode" json:"RetCode"`
+	RetMsg        string `xml:"RetMsg" json:"RetMsg"`
+	RefundSuccess int64  `xml:"RefundSuccTimestamp" json:"RefundSuccTimestamp"`
+	RetryTimes    int    `xml:"RetryTimes" json:"RetryTimes"`
+}
+
+// XPay 小程序虚拟支付
+type XPay struct {
+	mp      *MiniProgram
+	offerID string
+	appKey  string
+	env     XPayEnv
+}
+
+// OfferID 返回虚拟支付的 offerId
+func (xp *XPay) OfferID() string {
+	return xp.offerID
+}
+
+// Env 返回虚拟支付环境
+func (xp *XPay) Env() XPayEnv {
+	return xp.env
+}
+
+// PaySig 支付签名，uri 为接口路径(如：/xpay/query_order)或 requestVirtualPayment
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/framework/virtual-payment.html#_3-%E7%AD%BE%E5%90%8D%E8%AF%A6%E8%A7%A3)
+func (xp *XPay) PaySig(uri string, body []byte) string {
+	return xhash.HMacSHA256(xp.appKey, uri+"&"+string(body))
+}
+
+// UserSignature 用户态签名
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/framework/virtual-payment.html#_3-%E7%AD%BE%E5%90%8D%E8%AF%A6%E8%A7%A3)
+func UserSignature(sessionKey string, body []byte) string {
+	return xhash.HMacSHA256(sessionKey, string(body))
+}
+
+// ClientParams 生成小程序端 wx.requestVirtualPayment 所需参数(offerId、env 自动填充)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/api/payment/wx.requestVirtualPayment.html)
+func (xp *XPay) ClientParams(sessionKey string, mode XPayMode, data *XPaySignData) (*XPayParams, error) {
+	if mode == XPayModeGoods && len(data.ProductID) == 0 {
+		return nil, errors.New("productId is required for short_series_goods")
+	}
+
+	data.OfferID = xp.offerID
+	data.Env = xp.env
+	if len(data.CurrencyType) == 0 {
+		data.CurrencyType = "CNY"
+	}
+
+	b, err := lib.MarshalNoEscapeHTML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &XPayParams{
+		SignData:  string(b),
+		Mode:      mode,
+		PaySig:    xp.PaySig("requestVirtualPayment", b),
+		Signature: UserSignature(sessionKey, b),
+	}
+	return params, nil
+}
+
+// PostJSON 请求虚拟支付服务端接口(自动添加 env 参数和 pay_sig 签名)
+func (xp *XPay) PostJSON(ctx context.Context, path string, params lib.X) (gjson.Result, error) {
+	token, err := xp.mp.getToken()
+	if err != nil {
+		return lib.Fail(err)
+	}
+
+	if params == nil {
+		params = lib.X{}
+	}
+	params["env"] = xp.env
+
+	// json.Marshal 对map按key排序，与 do 中的序列化结果一致
+	body, err := json.Marshal(params)
+	if err != nil {
+		return lib.Fail(err)
+	}
+
+	query := url.Values{}
+	query.Set(AccessToken, token)
+	query.Set("pay_sig", xp.PaySig(path, body))
+
+	header := http.Header{}
+	header.Set(lib.HeaderContentType, lib.ContentJSON)
+
+	b, err := xp.mp.do(ctx, http.MethodPost, path, header, query, params)
+	if err != nil {
+		return lib.Fail(err)
+	}
+
+	ret := gjson.ParseBytes(b)
+	if code := ret.Get("errcode").Int(); code != 0 {
+		return lib.Fail(fmt.Errorf("%d | %s", code, ret.Get("errmsg").String()))
+	}
+	return ret, nil
+}
+
+// QueryOrder 查询订单，orderID 为商户订单号
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/platform-capabilities/industry/virtual-payment.html#_2-3-%E6%9F%A5%E8%AF%A2%E5%88%9B%E5%BB%BA%E7%9A%84%E8%AE%A2%E5%8D%95)
+func (xp *XPay) QueryOrder(ctx context.Context, openid, orderID string) (*XPayOrder, error) {
+	return xp.queryOrder(ctx, lib.X{"openid": openid, "order_id": orderID})
+}
+
+// QueryOrderByWxOrderID 通过微信内部单号查询订单
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/platform-capabilities/industry/virtual-payment.html#_2-3-%E6%9F%A5%E8%AF%A2%E5%88%9B%E5%BB%BA%E7%9A%84%E8%AE%A2%E5%8D%95)
+func (xp *XPay) QueryOrderByWxOrderID(ctx context.Context, openid, wxOrderID string) (*XPayOrder, error) {
+	return xp.queryOrder(ctx, lib.X{"openid": openid, "wx_order_id": wxOrderID})
+}
+
+func (xp *XPay) queryOrder(ctx context.Context, params lib.X) (*XPayOrder, error) {
+	ret, err := xp.PostJSON(ctx, "/xpay/query_order", params)
+	if err != nil {
+		return nil, err
+	}
+
+	order := new(XPayOrder)
+	if err = unmarshalResult(ret.Get("order"), order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// NotifyProvideGoods 通知已经发货完成(只能通知现网环境的订单)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/platform-capabilities/industry/virtual-payment.html#_2-9-%E9%80%9A%E7%9F%A5%E5%B7%B2%E7%BB%8F%E5%8F%91%E8%B4%A7%E5%AE%8C%E6%88%90)
+func (xp *XPay) NotifyProvideGoods(ctx context.Context, orderID, wxOrderID string) error {
+	params := lib.X{}
+	if len(orderID) != 0 {
+		params["order_id"] = orderID
+	}
+	if len(wxOrderID) != 0 {
+		params["wx_order_id"] = wxOrderID
+	}
+
+	_, err := xp.PostJSON(ctx, "/xpay/notify_provide_goods", params)
+	return err
+}
+
+// RefundOrder 启动订单退款任务
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/platform-capabilities/industry/virtual-payment.html#_2-6-%E5%90%AF%E5%8A%A8%E8%AE%A2%E5%8D%95%E9%80%80%E6%AC%BE%E4%BB%BB%E5%8A%A1)
+func (xp *XPay) RefundOrder(ctx context.Context, refund *XPayRefund) (*XPayRefundResult, error) {
+	if len(refund.OrderID) == 0 && len(refund.WxOrderID) == 0 {
+		return nil, errors.New("order_id or wx_order_id is required")
+	}
+
+	params, err := lib.ToX(refund)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := xp.PostJSON(ctx, "/xpay/refund_order", params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(XPayRefundResult)
+	if err = unmarshalResult(ret, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateWithdrawOrder 创建提现单，amount 为提现金额(单位：元，如：0.01)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/platform-capabilities/industry/virtual-payment.html#_2-7-%E5%88%9B%E5%BB%BA%E6%8F%90%E7%8E%B0%E5%8D%95)
+func (xp *XPay) CreateWithdrawOrder(ctx context.Context, withdrawNo, amount string) (*XPayWithdraw, error) {
+	params := lib.X{
+		"withdraw_no":     withdrawNo,
+		"withdraw_amount": amount,
+	}
+
+	ret, err := xp.PostJSON(ctx, "/xpay/create_withdraw_order", params)
+	if err != nil {
+		return nil, err
+	}
+
+	withdraw := new(XPayWithdraw)
+	if err = unmarshalResult(ret, withdraw); err != nil {
+		return nil, err
+	}
+	return withdraw, nil
+}
+
+// ParseNotify 解析虚拟支付推送事件(明文，支持XML和JSON)，并校验环境是否一致
+func (xp *XPay) ParseNotify(b []byte) (*XPayNotify, error) {
+	b = bytes.TrimSpace(b)
+
+	notify := new(XPayNotify)
+	if len(b) != 0 && b[0] == '<' {
+		if err := xml.Unmarshal(b, notify); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := json.Unmarshal(b, notify); err != nil {
+			return nil, err
+		}
+	}
+
+	switch notify.Event {
+	case EventXPayGoodsDeliver, EventXPayCoinPay, EventXPayRefund:
+	default:
+		return nil, fmt.Errorf("unexpected xpay event: %s", notify.Event)
+	}
+	if notify.Env != xp.env {
+		return nil, fmt.Errorf("xpay env mismatch, expect = %d", xp.env)
+	}
+	return notify, nil
+}
+
+// DecodeNotify 解析加密的虚拟支付推送事件，使用：msg_signature、timestamp、nonce、msg_encrypt
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/platform-capabilities/industry/virtual-payment.html#_3-%E6%B6%88%E6%81%AF%E6%8E%A8%E9%80%81)
+func (xp *XPay) DecodeNotify(signature, timestamp, nonce, encryptMsg string) (*XPayNotify, error) {
+	if SignWithSHA1(xp.mp.srvCfg.token, timestamp, nonce, encryptMsg) != signature {
+		return nil, errors.New("signature verified fail")
+	}
+
+	b, err := EventDecrypt(xp.mp.appid, xp.mp.srvCfg.aeskey, encryptMsg)
+	if err != nil {
+		return nil, err
+	}
+	return xp.ParseNotify(b)
+}
+
+// XPayNotifyReply 虚拟支付推送事件的回复，err为nil表示处理成功(发货成功)，否则微信将重试推送
+func XPayNotifyReply(err error) []byte {
+	ret := lib.X{
+		"ErrCode": 0,
+		"ErrMsg":  "success",
+	}
+	if err != nil {
+		ret["ErrCode"] = 1
+		ret["ErrMsg"] = err.Error()
+	}
+
+	b, _ := json.Marshal(ret)
+	return b
+}
+
+// XPay 生成虚拟支付实例，appKey 需与环境对应(现网AppKey/沙箱AppKey)
+func (mp *MiniProgram) XPay(offerID, appKey string, env XPayEnv) *XPay {
+	return &XPay{
+		mp:      mp,
+		offerID: offerID,
+		appKey:  appKey,
+		env:     env,
+	}
+}
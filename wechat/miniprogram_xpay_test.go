@@ -0,0 +1,82 @@
+package wechat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib/xhash"
+)
+
+func TestXPayClientParams(t *testing.T) {
+	xp := NewMiniProgram("wx4f4bc4dec97d474b", "secret").XPay("1450000000", "appkey", XPayEnvSandbox)
+
+	params, err := xp.ClientParams("c2Vzc2lvbl9rZXk=", XPayModeGoods, &XPaySignData{
+		BuyQuantity: 1,
+		ProductID:   "goods_1",
+		GoodsPrice:  100,
+		OutTradeNo:  "T20231019001",
+		Attach:      "a&b",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"offerId":"1450000000","buyQuantity":1,"env":1,"currencyType":"CNY","productId":"goods_1","goodsPrice":100,"outTradeNo":"T20231019001","attach":"a&b"}`, params.SignData)
+	assert.Equal(t, xhash.HMacSHA256("appkey", "requestVirtualPayment&"+params.SignData), params.PaySig)
+	assert.Equal(t, xhash.HMacSHA256("c2Vzc2lvbl9rZXk=", params.SignData), params.Signature)
+
+	_, err = xp.ClientParams("c2Vzc2lvbl9rZXk=", XPayModeGoods, &XPaySignData{BuyQuantity: 1, OutTradeNo: "T20231019002"})
+	assert.NotNil(t, err)
+}
+
+func TestXPayQueryOrder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "/xpay/query_order", r.URL.Path)
+		assert.Equal(t, xhash.HMacSHA256("appkey", "/xpay/query_order&"+string(body)), r.URL.Query().Get("pay_sig"))
+		assert.Equal(t, int64(1), gjson.GetBytes(body, "env").Int())
+
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","order":{"order_id":"T20231019001","status":2,"order_fee":100,"wx_order_id":"wx_1"}}`))
+	}))
+	defer srv.Close()
+
+	mp := NewMiniProgram("wx4f4bc4dec97d474b", "secret")
+	mp.host = srv.URL
+	mp.token.Store("ACCESS_TOKEN")
+
+	order, err := mp.XPay("1450000000", "appkey", XPayEnvSandbox).QueryOrder(context.Background(), "oGZUI0egBJY1zhBYw2KhdUfwVJJE", "T20231019001")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, order.Status)
+	assert.Equal(t, int64(100), order.OrderFee)
+	assert.Equal(t, "wx_1", order.WxOrderID)
+}
+
+func TestXPayParseNotify(t *testing.T) {
+	xp := NewMiniProgram("wx4f4bc4dec97d474b", "secret").XPay("1450000000", "appkey", XPayEnvProd)
+
+	notify, err := xp.ParseNotify([]byte(`<xml>
+<ToUserName><![CDATA[gh_3cf62f4f1d52]]></ToUserName>
+<FromUserName><![CDATA[oGZUI0egBJY1zhBYw2KhdUfwVJJE]]></FromUserName>
+<CreateTime>1697700000</CreateTime>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[xpay_goods_deliver_notify]]></Event>
+<OpenId><![CDATA[oGZUI0egBJY1zhBYw2KhdUfwVJJE]]></OpenId>
+<OutTradeNo><![CDATA[T20231019001]]></OutTradeNo>
+<Env>0</Env>
+<WeChatPayInfo><MchOrderNo><![CDATA[mch_1]]></MchOrderNo><TransactionId><![CDATA[tx_1]]></TransactionId><PaidTime>1697700000</PaidTime></WeChatPayInfo>
+<GoodsInfo><ProductId><![CDATA[goods_1]]></ProductId><Quantity>1</Quantity><OrigPrice>100</OrigPrice><ActualPrice>100</ActualPrice><Attach><![CDATA[a&b]]></Attach></GoodsInfo>
+</xml>`))
+	assert.Nil(t, err)
+	assert.Equal(t, "T20231019001", notify.OutTradeNo)
+	assert.Equal(t, "tx_1", notify.WeChatPayInfo.TransactionID)
+	assert.Equal(t, "goods_1", notify.GoodsInfo.ProductID)
+	assert.Equal(t, int64(100), notify.GoodsInfo.ActualPrice)
+
+	_, err = xp.ParseNotify([]byte(`{"Event":"xpay_coin_pay_notify","Env":1}`))
+	assert.NotNil(t, err)
+
+	assert.Equal(t, `{"ErrCode":0,"ErrMsg":"success"}`, string(XPayNotifyReply(nil)))
+}
@@ -0,0 +1,332 @@
+package wechat
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+)
+
+// 会话存档消息类型
+const (
+	ArchiveMsgText     = "text"
+	ArchiveMsgImage    = "image"
+	ArchiveMsgRevoke   = "revoke"
+	ArchiveMsgAgree    = "agree"
+	ArchiveMsgDisagree = "disagree"
+	ArchiveMsgVoice    = "voice"
+	ArchiveMsgVideo    = "video"
+	ArchiveMsgCard     = "card"
+	ArchiveMsgLocation = "location"
+	ArchiveMsgEmotion  = "emotion"
+	ArchiveMsgFile     = "file"
+	ArchiveMsgLink     = "link"
+	ArchiveMsgWeapp    = "weapp"
+)
+
+// 会话存档消息动作
+const (
+	ArchiveActionSend   = "send"
+	ArchiveActionRecall = "recall"
+	ArchiveActionSwitch = "switch"
+)
+
+// ArchiveRecord 会话存档原始记录(GetChatData 返回的 chatdata 元素)
+type ArchiveRecord struct {
+	Seq              uint64 `json:"seq"`
+	MsgID            string `json:"msgid"`
+	PublicKeyVer     uint32 `json:"publickey_ver"`
+	EncryptRandomKey string `json:"encrypt_random_key"`
+	EncryptChatMsg   string `json:"encrypt_chat_msg"`
+}
+
+// ArchiveText 文本
+type ArchiveText struct {
+	Content string `json:"content"`
+}
+
+// ArchiveImage 图片
+type ArchiveImage struct {
+	MD5Sum    string `json:"md5sum"`
+	FileSize  int64  `json:"filesize"`
+	SdkFileID string `json:"sdkfileid"`
+}
+
+// ArchiveRevoke 撤回
+type ArchiveRevoke struct {
+	PreMsgID string `json:"pre_msgid"`
+}
+
+// ArchiveAgree 同意/不同意会话存档
+type ArchiveAgree struct {
+	UserID    string `json:"userid"`
+	AgreeTime int64  `json:"agree_time"`
+}
+
+// ArchiveVoice 语音
+type ArchiveVoice struct {
+	MD5Sum     string `json:"md5sum"`
+	VoiceSize  int64  `json:"voice_size"`
+	PlayLength int64  `json:"play_length"`
+	SdkFileID  string `json:"sdkfileid"`
+}
+
+// ArchiveVideo 视频
+type ArchiveVideo struct {
+	MD5Sum     string `json:"md5sum"`
+	FileSize   int64  `json:"filesize"`
+	PlayLength int64  `json:"play_length"`
+	SdkFileID  string `json:"sdkfileid"`
+}
+
+// ArchiveCard 名片
+type ArchiveCard struct {
+	CorpName string `json:"corpname"`
+	UserID   string `json:"userid"`
+}
+
+// ArchiveLocation 位置
+type ArchiveLocation struct {
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+	Address   string  `json:"address"`
+	Title     string  `json:"title"`
+	Zoom      int     `json:"zoom"`
+}
+
+// ArchiveEmotion 表情
+type ArchiveEmotion struct {
+	Type      int    `json:"type"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	ImageSize int64  `json:"imagesize"`
+	MD5Sum    string `json:"md5sum"`
+	SdkFileID string `json:"sdkfileid"`
+}
+
+// ArchiveFile 文件
+type ArchiveFile struct {
+	MD5Sum    string `json:"md5sum"`
+	FileName  string `json:"filename"`
+	FileExt   string `json:"fileext"`
+	FileSize  int64  `json:"filesize"`
+	SdkFileID string `json:"sdkfileid"`
+}
+
+// ArchiveLink 链接
+type ArchiveLink struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	LinkURL     string `json:"link_url"`
+	ImageURL    string `json:"image_url"`
+}
+
+// ArchiveWeapp 小程序
+type ArchiveWeapp struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	UserName    string `json:"username"`
+	DisplayName string `json:"displayname"`
+}
+
+// ArchiveMsg 会话存档解密后的消息
+type ArchiveMsg struct {
+	Seq     uint64   `json:"-"`
+	MsgID   string   `json:"msgid"`
+	Action  string   `json:"action"`
+	From    string   `json:"from"`
+	ToList  []string `json:"tolist"`
+	RoomID  string   `json:"roomid"`
+	MsgTime int64    `json:"msgtime"`
+	MsgType string   `json:"msgtype"`
+
+	Text     *ArchiveText     `json:"text,omitempty"`
+	Image    *ArchiveImage    `json:"image,omitempty"`
+	Revoke   *ArchiveRevoke   `json:"revoke,omitempty"`
+	Agree    *ArchiveAgree    `json:"agree,omitempty"`
+	Disagree *ArchiveAgree    `json:"disagree,omitempty"`
+	Voice    *ArchiveVoice    `json:"voice,omitempty"`
+	Video    *ArchiveVideo    `json:"video,omitempty"`
+	Card     *ArchiveCard     `json:"card,omitempty"`
+	Location *ArchiveLocation `json:"location,omitempty"`
+	Emotion  *ArchiveEmotion  `json:"emotion,omitempty"`
+	File     *ArchiveFile     `json:"file,omitempty"`
+	Link     *ArchiveLink     `json:"link,omitempty"`
+	Weapp    *ArchiveWeapp    `json:"weapp,omitempty"`
+
+	// Raw 解密后的原始消息，用于解析未定义的消息类型
+	Raw json.RawMessage `json:"-"`
+}
+
+// MsgArchive 企业微信会话存档
+type MsgArchive struct {
+	mutex sync.RWMutex
+	keys  map[uint32]*xcrypto.PrivateKey
+}
+
+// SetPrivateKey 设置指定版本(publickey_ver)的RSA私钥，支持多版本共存以便密钥轮换
+func (a *MsgArchive) SetPrivateKey(ver uint32, key *xcrypto.PrivateKey) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.keys[ver] = key
+}
+
+// DelPrivateKey 删除指定版本的RSA私钥
+func (a *MsgArchive) DelPrivateKey(ver uint32) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.keys, ver)
+}
+
+func (a *MsgArchive) privateKey(ver uint32) (*xcrypto.PrivateKey, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	key, ok := a.keys[ver]
+	if !ok {
+		return nil, fmt.Errorf("private key(publickey_ver=%d) not found", ver)
+	}
+	return key, nil
+}
+
+// DecryptChatData 解析并解密 GetChatData 返回的数据
+func (a *MsgArchive) DecryptChatData(b []byte) ([]*ArchiveMsg, error) {
+	var ret struct {
+		ErrCode  int              `json:"errcode"`
+		ErrMsg   string           `json:"errmsg"`
+		ChatData []*ArchiveRecord `json:"chatdata"`
+	}
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, err
+	}
+	if ret.ErrCode != 0 {
+		return nil, fmt.Errorf("%d | %s", ret.ErrCode, ret.ErrMsg)
+	}
+
+	msgs := make([]*ArchiveMsg, 0, len(ret.ChatData))
+	for _, v := range ret.ChatData {
+		msg, err := a.Decrypt(v)
+		if err != nil {
+			return nil, fmt.Errorf("msgid(%s) decrypt error: %w", v.MsgID, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// Decrypt 解密单条会话存档记录：
+// 1. 使用 publickey_ver 对应的私钥解密 encrypt_random_key；
+// 2. 使用解密后的随机密钥解密 encrypt_chat_msg (AES-CBC，IV取密钥前16字节)
+func (a *MsgArchive) Decrypt(record *ArchiveRecord) (*ArchiveMsg, error) {
+	key, err := a.privateKey(record.PublicKeyVer)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherKey, err := base64.StdEncoding.DecodeString(record.EncryptRandomKey)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt_random_key base64.decode error: %w", err)
+	}
+	randomKey, err := key.Decrypt(cipherKey)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt_random_key decrypt error: %w", err)
+	}
+
+	b, err := ArchiveMsgDecrypt(randomKey, record.EncryptChatMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := new(ArchiveMsg)
+	if err = json.Unmarshal(b, msg); err != nil {
+		return nil, err
+	}
+	msg.Seq = record.Seq
+	msg.Raw = b
+
+	return msg, nil
+}
+
+// ArchiveMsgDecrypt 使用随机密钥解密 encrypt_chat_msg
+func ArchiveMsgDecrypt(randomKey []byte, encryptMsg string) ([]byte, error) {
+	if n := len(randomKey); n != 16 && n != 24 && n != 32 {
+		return nil, fmt.Errorf("invalid random key size: %d", n)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encryptMsg)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt_chat_msg base64.decode error: %w", err)
+	}
+	return xcrypto.AESDecryptCBC(randomKey, randomKey[:16], data)
+}
+
+// ArchiveMedia 会话存档媒体分片数据(GetMediaData 返回)
+type ArchiveMedia struct {
+	OutIndexBuf string // 下次拉取需要使用的 indexbuf
+	Data        []byte // 本次拉取的分片数据
+	IsFinish    bool   // 是否已拉取完毕
+}
+
+// ArchiveMediaFetcher 会话存档媒体分片拉取(通常由官方SDK的 GetMediaData 实现)
+type ArchiveMediaFetcher interface {
+	GetMediaData(ctx context.Context, sdkFileID, indexBuf string) (*ArchiveMedia, error)
+}
+
+// DownloadArchiveMedia 按 indexbuf 分片协议拉取会话存档媒体文件并写入 w；
+// 若 md5sum 不为空，则在拉取完成后校验文件md5
+func DownloadArchiveMedia(ctx context.Context, fetcher ArchiveMediaFetcher, sdkFileID, md5sum string, w io.Writer) error {
+	var (
+		indexBuf string
+		h        hash.Hash
+	)
+
+	if len(md5sum) != 0 {
+		h = md5.New()
+		w = io.MultiWriter(w, h)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		media, err := fetcher.GetMediaData(ctx, sdkFileID, indexBuf)
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(media.Data); err != nil {
+			return err
+		}
+		if media.IsFinish {
+			break
+		}
+		if len(media.OutIndexBuf) == 0 {
+			return errors.New("outindexbuf is empty before media finished")
+		}
+		indexBuf = media.OutIndexBuf
+	}
+
+	if h != nil {
+		if v := hex.EncodeToString(h.Sum(nil)); v != md5sum {
+			return fmt.Errorf("media md5sum mismatch, want: %s, got: %s", md5sum, v)
+		}
+	}
+	return nil
+}
+
+// NewMsgArchive 生成一个会话存档实例
+func NewMsgArchive() *MsgArchive {
+	return &MsgArchive{
+		keys: make(map[uint32]*xcrypto.PrivateKey),
+	}
+}
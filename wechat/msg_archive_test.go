@@ -0,0 +1,92 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/shenghui0779/sdk-go/lib/xcrypto"
+)
+
+func TestMsgArchiveDecrypt(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	prvKey, err := xcrypto.NewPrivateKeyFromPemBlock(xcrypto.RSA_PKCS1, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+	}))
+	assert.Nil(t, err)
+
+	pubKey, err := xcrypto.NewPublicKeyFromPemBlock(xcrypto.RSA_PKCS1, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PUBLIC KEY",
+		Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey),
+	}))
+	assert.Nil(t, err)
+
+	randomKey := []byte("0123456789abcdef0123456789abcdef")
+	encryptKey, err := pubKey.Encrypt(randomKey)
+	assert.Nil(t, err)
+
+	plain := `{"msgid":"CAQQluDa4QUY0On2rYSAgAMgzPrShAE=","action":"send","from":"XuJinSheng","tolist":["icefog"],"roomid":"","msgtime":1547087894783,"msgtype":"text","text":{"content":"test"}}`
+	ct, err := xcrypto.AESEncryptCBC(randomKey, randomKey[:16], []byte(plain))
+	assert.Nil(t, err)
+
+	archive := NewMsgArchive()
+	archive.SetPrivateKey(2, prvKey)
+
+	record := &ArchiveRecord{
+		Seq:              10,
+		MsgID:            "CAQQluDa4QUY0On2rYSAgAMgzPrShAE=",
+		PublicKeyVer:     2,
+		EncryptRandomKey: base64.StdEncoding.EncodeToString(encryptKey),
+		EncryptChatMsg:   ct.String(),
+	}
+
+	msg, err := archive.Decrypt(record)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), msg.Seq)
+	assert.Equal(t, ArchiveMsgText, msg.MsgType)
+	assert.Equal(t, []string{"icefog"}, msg.ToList)
+	assert.Equal(t, "test", msg.Text.Content)
+
+	record.PublicKeyVer = 3
+	_, err = archive.Decrypt(record)
+	assert.NotNil(t, err)
+}
+
+type mockMediaFetcher struct {
+	chunks [][]byte
+}
+
+func (f *mockMediaFetcher) GetMediaData(ctx context.Context, sdkFileID, indexBuf string) (*ArchiveMedia, error) {
+	i := 0
+	if len(indexBuf) != 0 {
+		i = int(indexBuf[0] - '0')
+	}
+	return &ArchiveMedia{
+		OutIndexBuf: string(rune('0' + i + 1)),
+		Data:        f.chunks[i],
+		IsFinish:    i == len(f.chunks)-1,
+	}, nil
+}
+
+func TestDownloadArchiveMedia(t *testing.T) {
+	fetcher := &mockMediaFetcher{chunks: [][]byte{[]byte("Hello "), []byte("World")}}
+
+	var buf bytes.Buffer
+	err := DownloadArchiveMedia(context.Background(), fetcher, "sdkfileid", "b10a8db164e0754105b7a99be72e3fe5", &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "Hello World", buf.String())
+
+	buf.Reset()
+	err = DownloadArchiveMedia(context.Background(), fetcher, "sdkfileid", "invalid", &buf)
+	assert.NotNil(t, err)
+}
@@ -21,8 +21,17 @@ import (
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	token  string
-	aeskey string
+	token     string
+	aeskey    string
+	receiveID string // 消息加解密的 receive_id，为空时使用 appid (第三方平台代授权时为第三方平台的 appid)
+}
+
+// receiver 返回消息加解密的 receive_id
+func (cfg *ServerConfig) receiver(appid string) string {
+	if len(cfg.receiveID) != 0 {
+		return cfg.receiveID
+	}
+	return appid
 }
 
 // OfficialAccount 微信公众号
@@ -374,6 +383,40 @@ func (oa *OfficialAccount) PostBuffer(ctx context.Context, path string, params l
 	return b, nil
 }
 
+// GetStream GET请求获取资源并写入w (如：下载媒体资源)，返回的JSON错误会被识别为error
+func (oa *OfficialAccount) GetStream(ctx context.Context, path string, query url.Values, w io.Writer) (*MediaMeta, error) {
+	token, err := oa.getToken()
+	if err != nil {
+		return nil, err
+	}
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set(AccessToken, token)
+
+	return doStream(ctx, oa.client, oa.logger, http.MethodGet, oa.url(path, query), nil, nil, w)
+}
+
+// PostStream POST请求获取资源并写入w (如：下载永久素材)，返回的JSON错误会被识别为error
+func (oa *OfficialAccount) PostStream(ctx context.Context, path string, params lib.X, w io.Writer) (*MediaMeta, error) {
+	token, err := oa.getToken()
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set(AccessToken, token)
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set(lib.HeaderContentType, lib.ContentJSON)
+
+	return doStream(ctx, oa.client, oa.logger, http.MethodPost, oa.url(path, query), header, body, w)
+}
+
 // Upload 上传媒体资源
 func (oa *OfficialAccount) Upload(ctx context.Context, reqPath, fieldName, filePath string, formData lib.Form, query url.Values) (gjson.Result, error) {
 	token, err := oa.getToken()
@@ -470,7 +513,7 @@ func (oa *OfficialAccount) DecodeEventMsg(signature, timestamp, nonce, encryptMs
 		return nil, errors.New("signature verified fail")
 	}
 
-	b, err := EventDecrypt(oa.appid, oa.srvCfg.aeskey, encryptMsg)
+	b, err := EventDecrypt(oa.srvCfg.receiver(oa.appid), oa.srvCfg.aeskey, encryptMsg)
 	if err != nil {
 		return nil, err
 	}
@@ -479,7 +522,7 @@ func (oa *OfficialAccount) DecodeEventMsg(signature, timestamp, nonce, encryptMs
 
 // ReplyEventMsg 事件消息回复
 func (oa *OfficialAccount) ReplyEventMsg(msg value.V) (value.V, error) {
-	return EventReply(oa.appid, oa.srvCfg.token, oa.srvCfg.aeskey, msg)
+	return EventReply(oa.srvCfg.receiver(oa.appid), oa.srvCfg.token, oa.srvCfg.aeskey, msg)
 }
 
 // OAOption 公众号设置项
@@ -0,0 +1,867 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// ------------------------------------ 自定义菜单 ------------------------------------
+
+// 菜单按钮类型
+const (
+	MenuClick           = "click"
+	MenuView            = "view"
+	MenuScanCodePush    = "scancode_push"
+	MenuScanCodeWaitMsg = "scancode_waitmsg"
+	MenuPicSysPhoto     = "pic_sysphoto"
+	MenuPicPhotoOrAlbum = "pic_photo_or_album"
+	MenuPicWeixin       = "pic_weixin"
+	MenuLocationSelect  = "location_select"
+	MenuMediaID         = "media_id"
+	MenuArticleID       = "article_id"
+	MenuArticleViewSelf = "article_view_limited"
+	MenuMiniProgram     = "miniprogram"
+)
+
+// MenuButton 菜单按钮
+type MenuButton struct {
+	Type      string        `json:"type,omitempty"`
+	Name      string        `json:"name"`
+	Key       string        `json:"key,omitempty"`
+	URL       string        `json:"url,omitempty"`
+	MediaID   string        `json:"media_id,omitempty"`
+	ArticleID string        `json:"article_id,omitempty"`
+	AppID     string        `json:"appid,omitempty"`
+	PagePath  string        `json:"pagepath,omitempty"`
+	SubButton []*MenuButton `json:"sub_button,omitempty"`
+}
+
+// MenuMatchRule 个性化菜单匹配规则
+type MenuMatchRule struct {
+	TagID              string `json:"tag_id,omitempty"`
+	ClientPlatformType string `json:"client_platform_type,omitempty"` // 1-IOS，2-Android，3-Others
+}
+
+// ConditionalMenu 个性化菜单
+type ConditionalMenu struct {
+	Button    []*MenuButton  `json:"button"`
+	MatchRule *MenuMatchRule `json:"matchrule"`
+	MenuID    int64          `json:"menuid"`
+}
+
+// MenuInfo 自定义菜单配置
+type MenuInfo struct {
+	Menu struct {
+		Button []*MenuButton `json:"button"`
+		MenuID int64         `json:"menuid"`
+	} `json:"menu"`
+	ConditionalMenu []*ConditionalMenu `json:"conditionalmenu"`
+}
+
+// CreateMenu 创建自定义菜单
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Creating_Custom-Defined_Menu.html)
+func (oa *OfficialAccount) CreateMenu(ctx context.Context, buttons ...*MenuButton) error {
+	_, err := oa.PostJSON(ctx, "/cgi-bin/menu/create", lib.X{"button": buttons})
+	return err
+}
+
+// GetMenu 查询自定义菜单(包含个性化菜单)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Getting_Custom_Menu_Configurations.html)
+func (oa *OfficialAccount) GetMenu(ctx context.Context) (*MenuInfo, error) {
+	ret, err := oa.GetJSON(ctx, "/cgi-bin/menu/get", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := new(MenuInfo)
+	if err = unmarshalResult(ret, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// DeleteMenu 删除自定义菜单(同时删除全部个性化菜单)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Deleting_Custom-Defined_Menu.html)
+func (oa *OfficialAccount) DeleteMenu(ctx context.Context) error {
+	_, err := oa.GetJSON(ctx, "/cgi-bin/menu/delete", nil)
+	return err
+}
+
+// AddConditionalMenu 创建个性化菜单，返回menuid
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Personalized_menu_interface.html)
+func (oa *OfficialAccount) AddConditionalMenu(ctx context.Context, rule *MenuMatchRule, buttons ...*MenuButton) (int64, error) {
+	params := lib.X{
+		"button":    buttons,
+		"matchrule": rule,
+	}
+
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/menu/addconditional", params)
+	if err != nil {
+		return 0, err
+	}
+	return ret.Get("menuid").Int(), nil
+}
+
+// DelConditionalMenu 删除个性化菜单
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Personalized_menu_interface.html)
+func (oa *OfficialAccount) DelConditionalMenu(ctx context.Context, menuID int64) error {
+	_, err := oa.PostJSON(ctx, "/cgi-bin/menu/delconditional", lib.X{"menuid": menuID})
+	return err
+}
+
+// TryMatchMenu 测试个性化菜单匹配结果，userID 可以是粉丝的openid，也可以是粉丝的微信号
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Custom_Menus/Personalized_menu_interface.html)
+func (oa *OfficialAccount) TryMatchMenu(ctx context.Context, userID string) ([]*MenuButton, error) {
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/menu/trymatch", lib.X{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	var buttons []*MenuButton
+	if err = unmarshalResult(ret.Get("button"), &buttons); err != nil {
+		return nil, err
+	}
+	return buttons, nil
+}
+
+// ------------------------------------ 模板消息 ------------------------------------
+
+// TemplateData 模板消息数据项
+type TemplateData struct {
+	Value string `json:"value"`
+}
+
+// TemplateMiniProgram 模板消息跳转小程序
+type TemplateMiniProgram struct {
+	AppID    string `json:"appid"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// TemplateMsg 模板消息
+type TemplateMsg struct {
+	ToUser      string                   `json:"touser"`
+	TemplateID  string                   `json:"template_id"`
+	URL         string                   `json:"url,omitempty"`
+	MiniProgram *TemplateMiniProgram     `json:"miniprogram,omitempty"`
+	Data        map[string]*TemplateData `json:"data"`
+	ClientMsgID string                   `json:"client_msg_id,omitempty"`
+}
+
+// PrivateTemplate 已添加的模板
+type PrivateTemplate struct {
+	TemplateID      string `json:"template_id"`
+	Title           string `json:"title"`
+	PrimaryIndustry string `json:"primary_industry"`
+	DeputyIndustry  string `json:"deputy_industry"`
+	Content         string `json:"content"`
+	Example         string `json:"example"`
+}
+
+// SendTemplateMsg 发送模板消息，返回msgid
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Template_Message_Interface.html)
+func (oa *OfficialAccount) SendTemplateMsg(ctx context.Context, msg *TemplateMsg) (int64, error) {
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/message/template/send", params)
+	if err != nil {
+		return 0, err
+	}
+	return ret.Get("msgid").Int(), nil
+}
+
+// AddTemplate 从模板库选用模板，返回template_id
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Template_Message_Interface.html)
+func (oa *OfficialAccount) AddTemplate(ctx context.Context, shortID string, keywordNames ...string) (string, error) {
+	params := lib.X{"template_id_short": shortID}
+	if len(keywordNames) != 0 {
+		params["keyword_name_list"] = keywordNames
+	}
+
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/template/api_add_template", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("template_id").String(), nil
+}
+
+// GetAllPrivateTemplate 获取已添加的模板列表
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Template_Message_Interface.html)
+func (oa *OfficialAccount) GetAllPrivateTemplate(ctx context.Context) ([]*PrivateTemplate, error) {
+	ret, err := oa.GetJSON(ctx, "/cgi-bin/template/get_all_private_template", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*PrivateTemplate
+	if err = unmarshalResult(ret.Get("template_list"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DelPrivateTemplate 删除模板
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Template_Message_Interface.html)
+func (oa *OfficialAccount) DelPrivateTemplate(ctx context.Context, templateID string) error {
+	_, err := oa.PostJSON(ctx, "/cgi-bin/template/del_private_template", lib.X{"template_id": templateID})
+	return err
+}
+
+// ------------------------------------ 用户管理 ------------------------------------
+
+// UserInfo 用户基本信息
+type UserInfo struct {
+	Subscribe      int     `json:"subscribe"`
+	OpenID         string  `json:"openid"`
+	Language       string  `json:"language"`
+	SubscribeTime  int64   `json:"subscribe_time"`
+	UnionID        string  `json:"unionid"`
+	Remark         string  `json:"remark"`
+	GroupID        int64   `json:"groupid"`
+	TagIDList      []int64 `json:"tagid_list"`
+	SubscribeScene string  `json:"subscribe_scene"`
+	QRScene        int64   `json:"qr_scene"`
+	QRSceneStr     string  `json:"qr_scene_str"`
+}
+
+// FollowerList 关注者列表(单页)
+type FollowerList struct {
+	Total      int64    `json:"total"`
+	Count      int64    `json:"count"`
+	OpenIDs    []string `json:"-"`
+	NextOpenID string   `json:"next_openid"`
+}
+
+// GetUserInfo 获取用户基本信息
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/Get_users_basic_information_UnionID.html)
+func (oa *OfficialAccount) GetUserInfo(ctx context.Context, openid string) (*UserInfo, error) {
+	query := url.Values{}
+
+	query.Set("openid", openid)
+	query.Set("lang", "zh_CN")
+
+	ret, err := oa.GetJSON(ctx, "/cgi-bin/user/info", query)
+	if err != nil {
+		return nil, err
+	}
+
+	info := new(UserInfo)
+	if err = unmarshalResult(ret, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// BatchGetUserInfo 批量获取用户基本信息(最多100个)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/Get_users_basic_information_UnionID.html)
+func (oa *OfficialAccount) BatchGetUserInfo(ctx context.Context, openids ...string) ([]*UserInfo, error) {
+	if len(openids) > 100 {
+		return nil, fmt.Errorf("openid count exceeds 100 (got %d)", len(openids))
+	}
+
+	users := make([]lib.X, 0, len(openids))
+	for _, v := range openids {
+		users = append(users, lib.X{"openid": v, "lang": "zh_CN"})
+	}
+
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/user/info/batchget", lib.X{"user_list": users})
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*UserInfo
+	if err = unmarshalResult(ret.Get("user_info_list"), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateUserRemark 设置用户备注名
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/Configuring_user_notes.html)
+func (oa *OfficialAccount) UpdateUserRemark(ctx context.Context, openid, remark string) error {
+	_, err := oa.PostJSON(ctx, "/cgi-bin/user/info/updateremark", lib.X{"openid": openid, "remark": remark})
+	return err
+}
+
+// GetFollowers 获取关注者列表(单页，每页最多10000个)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/Getting_a_User_List.html)
+func (oa *OfficialAccount) GetFollowers(ctx context.Context, nextOpenID string) (*FollowerList, error) {
+	query := url.Values{}
+	if len(nextOpenID) != 0 {
+		query.Set("next_openid", nextOpenID)
+	}
+
+	ret, err := oa.GetJSON(ctx, "/cgi-bin/user/get", query)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &FollowerList{
+		Total:      ret.Get("total").Int(),
+		Count:      ret.Get("count").Int(),
+		NextOpenID: ret.Get("next_openid").String(),
+	}
+	for _, v := range ret.Get("data.openid").Array() {
+		list.OpenIDs = append(list.OpenIDs, v.String())
+	}
+	return list, nil
+}
+
+// FollowerIterator 关注者迭代器(基于 next_openid 自动翻页)，nextOpenID 为起始位置(可为空)
+func (oa *OfficialAccount) FollowerIterator(nextOpenID string) *Iterator[string] {
+	return NewIterator(func(ctx context.Context, cursor string) ([]string, string, error) {
+		list, err := oa.GetFollowers(ctx, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		return list.OpenIDs, list.NextOpenID, nil
+	}, nextOpenID)
+}
+
+// ------------------------------------ 标签管理 ------------------------------------
+
+// UserTag 用户标签
+type UserTag struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// CreateTag 创建标签
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) CreateTag(ctx context.Context, name string) (*UserTag, error) {
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/tags/create", lib.X{"tag": lib.X{"name": name}})
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &UserTag{
+		ID:   ret.Get("tag.id").Int(),
+		Name: ret.Get("tag.name").String(),
+	}
+	return tag, nil
+}
+
+// GetTags 获取已创建的标签
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) GetTags(ctx context.Context) ([]*UserTag, error) {
+	ret, err := oa.GetJSON(ctx, "/cgi-bin/tags/get", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []*UserTag
+	if err = unmarshalResult(ret.Get("tags"), &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// UpdateTag 编辑标签
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) UpdateTag(ctx context.Context, tagID int64, name string) error {
+	_, err := oa.PostJSON(ctx, "/cgi-bin/tags/update", lib.X{"tag": lib.X{"id": tagID, "name": name}})
+	return err
+}
+
+// DeleteTag 删除标签
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) DeleteTag(ctx context.Context, tagID int64) error {
+	_, err := oa.PostJSON(ctx, "/cgi-bin/tags/delete", lib.X{"tag": lib.X{"id": tagID}})
+	return err
+}
+
+// BatchTagging 批量为用户打标签(最多50个)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) BatchTagging(ctx context.Context, tagID int64, openids ...string) error {
+	if len(openids) > 50 {
+		return fmt.Errorf("openid count exceeds 50 (got %d)", len(openids))
+	}
+
+	_, err := oa.PostJSON(ctx, "/cgi-bin/tags/members/batchtagging", lib.X{"tagid": tagID, "openid_list": openids})
+	return err
+}
+
+// BatchUntagging 批量为用户取消标签(最多50个)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) BatchUntagging(ctx context.Context, tagID int64, openids ...string) error {
+	if len(openids) > 50 {
+		return fmt.Errorf("openid count exceeds 50 (got %d)", len(openids))
+	}
+
+	_, err := oa.PostJSON(ctx, "/cgi-bin/tags/members/batchuntagging", lib.X{"tagid": tagID, "openid_list": openids})
+	return err
+}
+
+// GetUserTags 获取用户身上的标签列表
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) GetUserTags(ctx context.Context, openid string) ([]int64, error) {
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/tags/getidlist", lib.X{"openid": openid})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	for _, v := range ret.Get("tagid_list").Array() {
+		ids = append(ids, v.Int())
+	}
+	return ids, nil
+}
+
+// TagFollowerIterator 标签下粉丝迭代器(基于 next_openid 自动翻页)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/User_Tag_Management.html)
+func (oa *OfficialAccount) TagFollowerIterator(tagID int64, nextOpenID string) *Iterator[string] {
+	return NewIterator(func(ctx context.Context, cursor string) ([]string, string, error) {
+		ret, err := oa.PostJSON(ctx, "/cgi-bin/user/tag/get", lib.X{"tagid": tagID, "next_openid": cursor})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var openids []string
+		for _, v := range ret.Get("data.openid").Array() {
+			openids = append(openids, v.String())
+		}
+		return openids, ret.Get("next_openid").String(), nil
+	}, nextOpenID)
+}
+
+// ------------------------------------ 黑名单管理 ------------------------------------
+
+// BlackListIterator 黑名单迭代器(基于 next_openid 自动翻页)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/Manage_blacklist.html)
+func (oa *OfficialAccount) BlackListIterator(beginOpenID string) *Iterator[string] {
+	return NewIterator(func(ctx context.Context, cursor string) ([]string, string, error) {
+		ret, err := oa.PostJSON(ctx, "/cgi-bin/tags/members/getblacklist", lib.X{"begin_openid": cursor})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var openids []string
+		for _, v := range ret.Get("data.openid").Array() {
+			openids = append(openids, v.String())
+		}
+		return openids, ret.Get("next_openid").String(), nil
+	}, beginOpenID)
+}
+
+// BatchBlackList 拉黑用户(最多20个)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/Manage_blacklist.html)
+func (oa *OfficialAccount) BatchBlackList(ctx context.Context, openids ...string) error {
+	if len(openids) > 20 {
+		return fmt.Errorf("openid count exceeds 20 (got %d)", len(openids))
+	}
+
+	_, err := oa.PostJSON(ctx, "/cgi-bin/tags/members/batchblacklist", lib.X{"openid_list": openids})
+	return err
+}
+
+// BatchUnBlackList 取消拉黑用户(最多20个)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/User_Management/Manage_blacklist.html)
+func (oa *OfficialAccount) BatchUnBlackList(ctx context.Context, openids ...string) error {
+	if len(openids) > 20 {
+		return fmt.Errorf("openid count exceeds 20 (got %d)", len(openids))
+	}
+
+	_, err := oa.PostJSON(ctx, "/cgi-bin/tags/members/batchunblacklist", lib.X{"openid_list": openids})
+	return err
+}
+
+// ------------------------------------ 二维码 & 短链 ------------------------------------
+
+// QRScene 二维码场景值，SceneStr 不为空时使用字符串场景值
+type QRScene struct {
+	SceneID  int64
+	SceneStr string
+}
+
+func (s QRScene) x() lib.X {
+	if len(s.SceneStr) != 0 {
+		return lib.X{"scene": lib.X{"scene_str": s.SceneStr}}
+	}
+	return lib.X{"scene": lib.X{"scene_id": s.SceneID}}
+}
+
+// QRCode 带参数二维码
+type QRCode struct {
+	Ticket        string `json:"ticket"`
+	ExpireSeconds int64  `json:"expire_seconds"`
+	URL           string `json:"url"`
+}
+
+// ShowURL 返回二维码图片地址
+func (qr *QRCode) ShowURL() string {
+	return "https://mp.weixin.qq.com/cgi-bin/showqrcode?ticket=" + url.QueryEscape(qr.Ticket)
+}
+
+func (oa *OfficialAccount) createQRCode(ctx context.Context, params lib.X) (*QRCode, error) {
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/qrcode/create", params)
+	if err != nil {
+		return nil, err
+	}
+
+	qr := new(QRCode)
+	if err = unmarshalResult(ret, qr); err != nil {
+		return nil, err
+	}
+	return qr, nil
+}
+
+// CreateTempQRCode 生成临时二维码，expireSeconds 最大不超过2592000(30天)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Account_Management/Generating_a_Parametric_QR_Code.html)
+func (oa *OfficialAccount) CreateTempQRCode(ctx context.Context, scene QRScene, expireSeconds int64) (*QRCode, error) {
+	action := "QR_SCENE"
+	if len(scene.SceneStr) != 0 {
+		action = "QR_STR_SCENE"
+	}
+
+	params := lib.X{
+		"expire_seconds": expireSeconds,
+		"action_name":    action,
+		"action_info":    scene.x(),
+	}
+	return oa.createQRCode(ctx, params)
+}
+
+// CreatePermQRCode 生成永久二维码，场景值ID范围：1~100000，字符串场景值长度：1~64
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Account_Management/Generating_a_Parametric_QR_Code.html)
+func (oa *OfficialAccount) CreatePermQRCode(ctx context.Context, scene QRScene) (*QRCode, error) {
+	action := "QR_LIMIT_SCENE"
+	if len(scene.SceneStr) != 0 {
+		action = "QR_LIMIT_STR_SCENE"
+	}
+
+	params := lib.X{
+		"action_name": action,
+		"action_info": scene.x(),
+	}
+	return oa.createQRCode(ctx, params)
+}
+
+// GenShortKey 生成短key托管，expireSeconds 为0表示永久(最长30天)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Account_Management/KEY_Shortener.html)
+func (oa *OfficialAccount) GenShortKey(ctx context.Context, longData string, expireSeconds int64) (string, error) {
+	params := lib.X{"long_data": longData}
+	if expireSeconds > 0 {
+		params["expire_seconds"] = expireSeconds
+	}
+
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/shorturl/gen", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("short_key").String(), nil
+}
+
+// FetchShortKey 还原短key，返回长信息
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Account_Management/KEY_Shortener.html)
+func (oa *OfficialAccount) FetchShortKey(ctx context.Context, shortKey string) (string, error) {
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/shorturl/fetch", lib.X{"short_key": shortKey})
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("long_data").String(), nil
+}
+
+// ------------------------------------ 客服消息 ------------------------------------
+
+// 客服消息类型
+const (
+	KFMsgText            = "text"
+	KFMsgImage           = "image"
+	KFMsgVoice           = "voice"
+	KFMsgVideo           = "video"
+	KFMsgMusic           = "music"
+	KFMsgNews            = "news"
+	KFMsgMPNews          = "mpnews"
+	KFMsgMPNewsArticle   = "mpnewsarticle"
+	KFMsgMenu            = "msgmenu"
+	KFMsgWxCard          = "wxcard"
+	KFMsgLink            = "link"
+	KFMsgMiniProgramPage = "miniprogrampage"
+)
+
+// KFText 文本消息
+type KFText struct {
+	Content string `json:"content"`
+}
+
+// KFMedia 媒体消息(图片、语音、图文)
+type KFMedia struct {
+	MediaID string `json:"media_id"`
+}
+
+// KFVideo 视频消息
+type KFVideo struct {
+	MediaID      string `json:"media_id"`
+	ThumbMediaID string `json:"thumb_media_id,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// KFMusic 音乐消息
+type KFMusic struct {
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	MusicURL     string `json:"musicurl"`
+	HQMusicURL   string `json:"hqmusicurl"`
+	ThumbMediaID string `json:"thumb_media_id"`
+}
+
+// KFArticle 图文消息(点击跳转到外链)
+type KFArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl"`
+}
+
+// KFNews 图文消息
+type KFNews struct {
+	Articles []*KFArticle `json:"articles"`
+}
+
+// KFArticleID 图文消息(点击跳转到图文消息页面)
+type KFArticleID struct {
+	ArticleID string `json:"article_id"`
+}
+
+// KFMenuItem 菜单消息选项
+type KFMenuItem struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+// KFMenu 菜单消息
+type KFMenu struct {
+	HeadContent string        `json:"head_content"`
+	List        []*KFMenuItem `json:"list"`
+	TailContent string        `json:"tail_content"`
+}
+
+// KFCard 卡券消息
+type KFCard struct {
+	CardID string `json:"card_id"`
+}
+
+// KFLink 图文链接(小程序)
+type KFLink struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	ThumbURL    string `json:"thumb_url"`
+}
+
+// KFMiniProgramPage 小程序卡片
+type KFMiniProgramPage struct {
+	Title        string `json:"title"`
+	AppID        string `json:"appid,omitempty"`
+	PagePath     string `json:"pagepath"`
+	ThumbMediaID string `json:"thumb_media_id"`
+}
+
+// KFAccount 指定客服帐号发送
+type KFAccount struct {
+	KfAccount string `json:"kf_account"`
+}
+
+// KFMsg 客服消息
+type KFMsg struct {
+	ToUser          string             `json:"touser"`
+	MsgType         string             `json:"msgtype"`
+	Text            *KFText            `json:"text,omitempty"`
+	Image           *KFMedia           `json:"image,omitempty"`
+	Voice           *KFMedia           `json:"voice,omitempty"`
+	Video           *KFVideo           `json:"video,omitempty"`
+	Music           *KFMusic           `json:"music,omitempty"`
+	News            *KFNews            `json:"news,omitempty"`
+	MPNews          *KFMedia           `json:"mpnews,omitempty"`
+	MPNewsArticle   *KFArticleID       `json:"mpnewsarticle,omitempty"`
+	MsgMenu         *KFMenu            `json:"msgmenu,omitempty"`
+	WxCard          *KFCard            `json:"wxcard,omitempty"`
+	Link            *KFLink            `json:"link,omitempty"`
+	MiniProgramPage *KFMiniProgramPage `json:"miniprogrampage,omitempty"`
+	CustomService   *KFAccount         `json:"customservice,omitempty"`
+}
+
+// NewKFTextMsg 文本客服消息
+func NewKFTextMsg(toUser, content string) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgText, Text: &KFText{Content: content}}
+}
+
+// NewKFImageMsg 图片客服消息
+func NewKFImageMsg(toUser, mediaID string) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgImage, Image: &KFMedia{MediaID: mediaID}}
+}
+
+// NewKFVoiceMsg 语音客服消息
+func NewKFVoiceMsg(toUser, mediaID string) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgVoice, Voice: &KFMedia{MediaID: mediaID}}
+}
+
+// NewKFVideoMsg 视频客服消息
+func NewKFVideoMsg(toUser string, video *KFVideo) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgVideo, Video: video}
+}
+
+// NewKFMusicMsg 音乐客服消息
+func NewKFMusicMsg(toUser string, music *KFMusic) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgMusic, Music: music}
+}
+
+// NewKFNewsMsg 图文客服消息(外链)
+func NewKFNewsMsg(toUser string, articles ...*KFArticle) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgNews, News: &KFNews{Articles: articles}}
+}
+
+// NewKFMPNewsArticleMsg 图文客服消息(已发布的图文)
+func NewKFMPNewsArticleMsg(toUser, articleID string) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgMPNewsArticle, MPNewsArticle: &KFArticleID{ArticleID: articleID}}
+}
+
+// NewKFMenuMsg 菜单客服消息
+func NewKFMenuMsg(toUser string, menu *KFMenu) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgMenu, MsgMenu: menu}
+}
+
+// NewKFWxCardMsg 卡券客服消息
+func NewKFWxCardMsg(toUser, cardID string) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgWxCard, WxCard: &KFCard{CardID: cardID}}
+}
+
+// NewKFLinkMsg 图文链接客服消息(小程序)
+func NewKFLinkMsg(toUser string, link *KFLink) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgLink, Link: link}
+}
+
+// NewKFMiniProgramPageMsg 小程序卡片客服消息
+func NewKFMiniProgramPageMsg(toUser string, page *KFMiniProgramPage) *KFMsg {
+	return &KFMsg{ToUser: toUser, MsgType: KFMsgMiniProgramPage, MiniProgramPage: page}
+}
+
+// SendKFMsg 发送客服消息
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Service_Center_messages.html)
+func (oa *OfficialAccount) SendKFMsg(ctx context.Context, msg *KFMsg) error {
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = oa.PostJSON(ctx, "/cgi-bin/message/custom/send", params)
+	return err
+}
+
+// SetKFTyping 下发客服输入状态，typing=false 表示取消
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Service_Center_messages.html)
+func (oa *OfficialAccount) SetKFTyping(ctx context.Context, openid string, typing bool) error {
+	command := "Typing"
+	if !typing {
+		command = "CancelTyping"
+	}
+
+	_, err := oa.PostJSON(ctx, "/cgi-bin/message/custom/typing", lib.X{"touser": openid, "command": command})
+	return err
+}
+
+// ------------------------------------ 群发消息 ------------------------------------
+
+// MassImage 群发图片消息
+type MassImage struct {
+	MediaIDs           []string `json:"media_ids"`
+	Recommend          string   `json:"recommend,omitempty"`
+	NeedOpenComment    int      `json:"need_open_comment,omitempty"`
+	OnlyFansCanComment int      `json:"only_fans_can_comment,omitempty"`
+}
+
+// MassMsg 群发消息
+type MassMsg struct {
+	MsgType           string     `json:"msgtype"`
+	MPNews            *KFMedia   `json:"mpnews,omitempty"`
+	Text              *KFText    `json:"text,omitempty"`
+	Voice             *KFMedia   `json:"voice,omitempty"`
+	Images            *MassImage `json:"images,omitempty"`
+	MPVideo           *KFMedia   `json:"mpvideo,omitempty"`
+	WxCard            *KFCard    `json:"wxcard,omitempty"`
+	SendIgnoreReprint int        `json:"send_ignore_reprint,omitempty"`
+	ClientMsgID       string     `json:"clientmsgid,omitempty"`
+}
+
+// MassResult 群发结果
+type MassResult struct {
+	MsgID     int64 `json:"msg_id"`
+	MsgDataID int64 `json:"msg_data_id"`
+}
+
+func (oa *OfficialAccount) massSend(ctx context.Context, path string, msg *MassMsg, target lib.X) (*MassResult, error) {
+	params, err := lib.ToX(msg)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range target {
+		params[k] = v
+	}
+
+	ret, err := oa.PostJSON(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MassResult{
+		MsgID:     ret.Get("msg_id").Int(),
+		MsgDataID: ret.Get("msg_data_id").Int(),
+	}
+	return result, nil
+}
+
+// MassSendByTag 根据标签进行群发，tagID 为0表示发送给全部用户
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html)
+func (oa *OfficialAccount) MassSendByTag(ctx context.Context, tagID int64, msg *MassMsg) (*MassResult, error) {
+	filter := lib.X{"is_to_all": tagID == 0}
+	if tagID != 0 {
+		filter["tag_id"] = tagID
+	}
+	return oa.massSend(ctx, "/cgi-bin/message/mass/sendall", msg, lib.X{"filter": filter})
+}
+
+// MassSendByOpenID 根据OpenID列表群发(2~10000个)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html)
+func (oa *OfficialAccount) MassSendByOpenID(ctx context.Context, openids []string, msg *MassMsg) (*MassResult, error) {
+	if n := len(openids); n < 2 || n > 10000 {
+		return nil, fmt.Errorf("openid count must be in [2, 10000] (got %d)", n)
+	}
+	return oa.massSend(ctx, "/cgi-bin/message/mass/send", msg, lib.X{"touser": openids})
+}
+
+// MassPreview 群发预览
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html)
+func (oa *OfficialAccount) MassPreview(ctx context.Context, openid string, msg *MassMsg) error {
+	_, err := oa.massSend(ctx, "/cgi-bin/message/mass/preview", msg, lib.X{"touser": openid})
+	return err
+}
+
+// DeleteMass 删除群发，articleIdx 为0表示删除全部文章
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html)
+func (oa *OfficialAccount) DeleteMass(ctx context.Context, msgID int64, articleIdx int) error {
+	params := lib.X{"msg_id": msgID}
+	if articleIdx > 0 {
+		params["article_idx"] = articleIdx
+	}
+
+	_, err := oa.PostJSON(ctx, "/cgi-bin/message/mass/delete", params)
+	return err
+}
+
+// GetMassStatus 查询群发消息发送状态
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Message_Management/Batch_Sends_and_Originality_Checks.html)
+func (oa *OfficialAccount) GetMassStatus(ctx context.Context, msgID int64) (string, error) {
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/message/mass/get", lib.X{"msg_id": strconv.FormatInt(msgID, 10)})
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("msg_status").String(), nil
+}
@@ -0,0 +1,145 @@
+package wechat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+func newTestOA(t *testing.T, fn func(path string, query map[string][]string, params gjson.Result) string) *OfficialAccount {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ACCESS_TOKEN", r.URL.Query().Get(AccessToken))
+
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(fn(r.URL.Path, r.URL.Query(), gjson.ParseBytes(body))))
+	}))
+	t.Cleanup(srv.Close)
+
+	oa := NewOfficialAccount("APPID", "SECRET")
+	oa.host = srv.URL
+	oa.token.Store("ACCESS_TOKEN")
+
+	return oa
+}
+
+func TestOAMenu(t *testing.T) {
+	oa := newTestOA(t, func(path string, query map[string][]string, params gjson.Result) string {
+		switch path {
+		case "/cgi-bin/menu/get":
+			return `{"menu":{"button":[{"type":"click","name":"今日歌曲","key":"V1001_TODAY_MUSIC"}],"menuid":208396938},"conditionalmenu":[{"button":[{"type":"view","name":"搜索","url":"http://www.soso.com/"}],"matchrule":{"tag_id":"2","client_platform_type":"2"},"menuid":208396993}]}`
+		case "/cgi-bin/menu/addconditional":
+			assert.Equal(t, "2", params.Get("matchrule.tag_id").String())
+			assert.Equal(t, "搜索", params.Get("button.0.name").String())
+			return `{"menuid":208396993}`
+		case "/cgi-bin/menu/delconditional":
+			assert.Equal(t, int64(208396993), params.Get("menuid").Int())
+			return `{"errcode":0,"errmsg":"ok"}`
+		}
+		return `{"errcode":40001,"errmsg":"invalid credential"}`
+	})
+
+	ctx := context.Background()
+
+	info, err := oa.GetMenu(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(208396938), info.Menu.MenuID)
+	assert.Equal(t, "V1001_TODAY_MUSIC", info.Menu.Button[0].Key)
+	assert.Equal(t, 1, len(info.ConditionalMenu))
+	assert.Equal(t, int64(208396993), info.ConditionalMenu[0].MenuID)
+	assert.Equal(t, "2", info.ConditionalMenu[0].MatchRule.TagID)
+
+	menuID, err := oa.AddConditionalMenu(ctx, &MenuMatchRule{TagID: "2"}, &MenuButton{Type: MenuView, Name: "搜索", URL: "http://www.soso.com/"})
+	assert.Nil(t, err)
+	assert.Equal(t, info.ConditionalMenu[0].MenuID, menuID)
+
+	assert.Nil(t, oa.DelConditionalMenu(ctx, menuID))
+}
+
+func TestOAUser(t *testing.T) {
+	oa := newTestOA(t, func(path string, query map[string][]string, params gjson.Result) string {
+		switch path {
+		case "/cgi-bin/user/info/batchget":
+			assert.Equal(t, "OPENID1", params.Get("user_list.0.openid").String())
+			return `{"user_info_list":[{"subscribe":1,"openid":"OPENID1","tagid_list":[2,3]},{"subscribe":0,"openid":"OPENID2"}]}`
+		case "/cgi-bin/user/get":
+			switch next := query["next_openid"]; {
+			case len(next) == 0:
+				return `{"total":3,"count":2,"data":{"openid":["OPENID1","OPENID2"]},"next_openid":"OPENID2"}`
+			case next[0] == "OPENID2":
+				return `{"total":3,"count":1,"data":{"openid":["OPENID3"]},"next_openid":"OPENID3"}`
+			}
+			return `{"total":3,"count":0,"next_openid":""}`
+		}
+		return `{"errcode":40001,"errmsg":"invalid credential"}`
+	})
+
+	ctx := context.Background()
+
+	users, err := oa.BatchGetUserInfo(ctx, "OPENID1", "OPENID2")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(users))
+	assert.Equal(t, []int64{2, 3}, users[0].TagIDList)
+
+	openids, err := oa.FollowerIterator("").All(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"OPENID1", "OPENID2", "OPENID3"}, openids)
+}
+
+func TestOABatchLimit(t *testing.T) {
+	var called []string
+
+	oa := newTestOA(t, func(path string, query map[string][]string, params gjson.Result) string {
+		called = append(called, path)
+		return `{"errcode":0,"errmsg":"ok"}`
+	})
+
+	genOpenIDs := func(n int) []string {
+		openids := make([]string, 0, n)
+		for i := 0; i < n; i++ {
+			openids = append(openids, fmt.Sprintf("OPENID%d", i))
+		}
+		return openids
+	}
+
+	ctx := context.Background()
+
+	_, err := oa.BatchGetUserInfo(ctx, genOpenIDs(101)...)
+	assert.EqualError(t, err, "openid count exceeds 100 (got 101)")
+	assert.EqualError(t, oa.BatchTagging(ctx, 2, genOpenIDs(51)...), "openid count exceeds 50 (got 51)")
+	assert.EqualError(t, oa.BatchUntagging(ctx, 2, genOpenIDs(51)...), "openid count exceeds 50 (got 51)")
+	assert.EqualError(t, oa.BatchBlackList(ctx, genOpenIDs(21)...), "openid count exceeds 20 (got 21)")
+	assert.EqualError(t, oa.BatchUnBlackList(ctx, genOpenIDs(21)...), "openid count exceeds 20 (got 21)")
+	assert.Empty(t, called)
+
+	assert.Nil(t, oa.BatchTagging(ctx, 2, genOpenIDs(50)...))
+	assert.Nil(t, oa.BatchUntagging(ctx, 2, genOpenIDs(50)...))
+	assert.Nil(t, oa.BatchBlackList(ctx, genOpenIDs(20)...))
+	assert.Nil(t, oa.BatchUnBlackList(ctx, genOpenIDs(20)...))
+	assert.Equal(t, []string{
+		"/cgi-bin/tags/members/batchtagging",
+		"/cgi-bin/tags/members/batchuntagging",
+		"/cgi-bin/tags/members/batchblacklist",
+		"/cgi-bin/tags/members/batchunblacklist",
+	}, called)
+}
+
+func TestOAQRCode(t *testing.T) {
+	oa := newTestOA(t, func(path string, query map[string][]string, params gjson.Result) string {
+		assert.Equal(t, "/cgi-bin/qrcode/create", path)
+		assert.Equal(t, "QR_STR_SCENE", params.Get("action_name").String())
+		assert.Equal(t, "test", params.Get("action_info.scene.scene_str").String())
+		assert.Equal(t, int64(604800), params.Get("expire_seconds").Int())
+		return `{"ticket":"gQH47joAAAAAAAAAASxodHRwOi8vd2VpeGluLnFxLmNvbS9xL2taZ2Z3TVRtNzJXV1Brb3ZhYmJJAAIEZ23sUwMEmm3sUw==","expire_seconds":604800,"url":"http://weixin.qq.com/q/kZgfwMTm72WWPkovabbI"}`
+	})
+
+	qr, err := oa.CreateTempQRCode(context.Background(), QRScene{SceneStr: "test"}, 604800)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(604800), qr.ExpireSeconds)
+	assert.Equal(t, "https://mp.weixin.qq.com/cgi-bin/showqrcode?ticket=gQH47joAAAAAAAAAASxodHRwOi8vd2VpeGluLnFxLmNvbS9xL2taZ2Z3TVRtNzJXV1Brb3ZhYmJJAAIEZ23sUwMEmm3sUw%3D%3D", qr.ShowURL())
+}
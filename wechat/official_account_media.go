@@ -0,0 +1,247 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// MediaType 素材类型
+type MediaType string
+
+const (
+	MediaImage MediaType = "image"
+	MediaVoice MediaType = "voice"
+	MediaVideo MediaType = "video"
+	MediaThumb MediaType = "thumb"
+)
+
+// MediaUpload 临时素材上传结果
+type MediaUpload struct {
+	Type      string `json:"type"`
+	MediaID   string `json:"media_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// MaterialUpload 永久素材上传结果
+type MaterialUpload struct {
+	MediaID string `json:"media_id"`
+	URL     string `json:"url"` // 仅图片素材返回
+}
+
+// VideoMaterial 永久视频素材
+type VideoMaterial struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	DownURL     string `json:"down_url"`
+}
+
+// MaterialCount 永久素材总数
+type MaterialCount struct {
+	VoiceCount int64 `json:"voice_count"`
+	VideoCount int64 `json:"video_count"`
+	ImageCount int64 `json:"image_count"`
+	NewsCount  int64 `json:"news_count"`
+}
+
+func parseMediaUpload(ret gjson.Result) (*MediaUpload, error) {
+	media := new(MediaUpload)
+	if err := unmarshalResult(ret, media); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+func parseMaterialUpload(ret gjson.Result) (*MaterialUpload, error) {
+	material := new(MaterialUpload)
+	if err := unmarshalResult(ret, material); err != nil {
+		return nil, err
+	}
+	return material, nil
+}
+
+func videoDescription(title, introduction string) (lib.Form, error) {
+	b, err := json.Marshal(lib.X{
+		"title":        title,
+		"introduction": introduction,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lib.Form{"description": string(b)}, nil
+}
+
+// UploadMedia 上传临时素材
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/New_temporary_materials.html)
+func (oa *OfficialAccount) UploadMedia(ctx context.Context, mediaType MediaType, filePath string) (*MediaUpload, error) {
+	query := url.Values{}
+	query.Set("type", string(mediaType))
+
+	ret, err := oa.Upload(ctx, "/cgi-bin/media/upload", "media", filePath, nil, query)
+	if err != nil {
+		return nil, err
+	}
+	return parseMediaUpload(ret)
+}
+
+// UploadMediaWithReader 上传临时素材
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/New_temporary_materials.html)
+func (oa *OfficialAccount) UploadMediaWithReader(ctx context.Context, mediaType MediaType, fileName string, reader io.Reader) (*MediaUpload, error) {
+	query := url.Values{}
+	query.Set("type", string(mediaType))
+
+	ret, err := oa.UploadWithReader(ctx, "/cgi-bin/media/upload", "media", fileName, reader, nil, query)
+	if err != nil {
+		return nil, err
+	}
+	return parseMediaUpload(ret)
+}
+
+// DownloadMedia 下载临时素材并写入w，返回文件名和类型
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_temporary_materials.html)
+func (oa *OfficialAccount) DownloadMedia(ctx context.Context, mediaID string, w io.Writer) (*MediaMeta, error) {
+	query := url.Values{}
+	query.Set("media_id", mediaID)
+
+	return oa.GetStream(ctx, "/cgi-bin/media/get", query, w)
+}
+
+// DownloadHDVoice 下载JSSDK上传的高清语音素材(speex格式)并写入w
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_temporary_materials.html)
+func (oa *OfficialAccount) DownloadHDVoice(ctx context.Context, mediaID string, w io.Writer) (*MediaMeta, error) {
+	query := url.Values{}
+	query.Set("media_id", mediaID)
+
+	return oa.GetStream(ctx, "/cgi-bin/media/get/jssdk", query, w)
+}
+
+// UploadNewsImage 上传图文消息内的图片，返回图片URL
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html)
+func (oa *OfficialAccount) UploadNewsImage(ctx context.Context, filePath string) (string, error) {
+	ret, err := oa.Upload(ctx, "/cgi-bin/media/uploadimg", "media", filePath, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("url").String(), nil
+}
+
+// UploadNewsImageWithReader 上传图文消息内的图片，返回图片URL
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html)
+func (oa *OfficialAccount) UploadNewsImageWithReader(ctx context.Context, fileName string, reader io.Reader) (string, error) {
+	ret, err := oa.UploadWithReader(ctx, "/cgi-bin/media/uploadimg", "media", fileName, reader, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("url").String(), nil
+}
+
+// AddMaterial 新增其他类型永久素材(图片、语音、缩略图)，视频请使用 AddVideoMaterial
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html)
+func (oa *OfficialAccount) AddMaterial(ctx context.Context, mediaType MediaType, filePath string) (*MaterialUpload, error) {
+	query := url.Values{}
+	query.Set("type", string(mediaType))
+
+	ret, err := oa.Upload(ctx, "/cgi-bin/material/add_material", "media", filePath, nil, query)
+	if err != nil {
+		return nil, err
+	}
+	return parseMaterialUpload(ret)
+}
+
+// AddMaterialWithReader 新增其他类型永久素材(图片、语音、缩略图)，视频请使用 AddVideoMaterialWithReader
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html)
+func (oa *OfficialAccount) AddMaterialWithReader(ctx context.Context, mediaType MediaType, fileName string, reader io.Reader) (*MaterialUpload, error) {
+	query := url.Values{}
+	query.Set("type", string(mediaType))
+
+	ret, err := oa.UploadWithReader(ctx, "/cgi-bin/material/add_material", "media", fileName, reader, nil, query)
+	if err != nil {
+		return nil, err
+	}
+	return parseMaterialUpload(ret)
+}
+
+// AddVideoMaterial 新增永久视频素材(需额外提交 description 表单字段)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html)
+func (oa *OfficialAccount) AddVideoMaterial(ctx context.Context, filePath, title, introduction string) (*MaterialUpload, error) {
+	form, err := videoDescription(title, introduction)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("type", string(MediaVideo))
+
+	ret, err := oa.Upload(ctx, "/cgi-bin/material/add_material", "media", filePath, form, query)
+	if err != nil {
+		return nil, err
+	}
+	return parseMaterialUpload(ret)
+}
+
+// AddVideoMaterialWithReader 新增永久视频素材(需额外提交 description 表单字段)
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Adding_Permanent_Assets.html)
+func (oa *OfficialAccount) AddVideoMaterialWithReader(ctx context.Context, fileName string, reader io.Reader, title, introduction string) (*MaterialUpload, error) {
+	form, err := videoDescription(title, introduction)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("type", string(MediaVideo))
+
+	ret, err := oa.UploadWithReader(ctx, "/cgi-bin/material/add_material", "media", fileName, reader, form, query)
+	if err != nil {
+		return nil, err
+	}
+	return parseMaterialUpload(ret)
+}
+
+// DownloadMaterial 下载永久素材(图片、语音、缩略图)并写入w；
+// 视频素材和图文素材返回的是JSON(MediaMeta.IsJSON)，视频素材请使用 GetVideoMaterial
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Getting_Permanent_Assets.html)
+func (oa *OfficialAccount) DownloadMaterial(ctx context.Context, mediaID string, w io.Writer) (*MediaMeta, error) {
+	return oa.PostStream(ctx, "/cgi-bin/material/get_material", lib.X{"media_id": mediaID}, w)
+}
+
+// GetVideoMaterial 获取永久视频素材
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Getting_Permanent_Assets.html)
+func (oa *OfficialAccount) GetVideoMaterial(ctx context.Context, mediaID string) (*VideoMaterial, error) {
+	ret, err := oa.PostJSON(ctx, "/cgi-bin/material/get_material", lib.X{"media_id": mediaID})
+	if err != nil {
+		return nil, err
+	}
+
+	video := new(VideoMaterial)
+	if err = unmarshalResult(ret, video); err != nil {
+		return nil, err
+	}
+	return video, nil
+}
+
+// DeleteMaterial 删除永久素材
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Deleting_Permanent_Assets.html)
+func (oa *OfficialAccount) DeleteMaterial(ctx context.Context, mediaID string) error {
+	_, err := oa.PostJSON(ctx, "/cgi-bin/material/del_material", lib.X{"media_id": mediaID})
+	return err
+}
+
+// GetMaterialCount 获取永久素材总数
+// [参考](https://developers.weixin.qq.com/doc/offiaccount/Asset_Management/Get_the_total_of_all_materials.html)
+func (oa *OfficialAccount) GetMaterialCount(ctx context.Context) (*MaterialCount, error) {
+	ret, err := oa.GetJSON(ctx, "/cgi-bin/material/get_materialcount", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	count := new(MaterialCount)
+	if err = unmarshalResult(ret, count); err != nil {
+		return nil, err
+	}
+	return count, nil
+}
@@ -0,0 +1,121 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+func newTestMediaOA(t *testing.T, handler http.HandlerFunc, options ...OAOption) *OfficialAccount {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ACCESS_TOKEN", r.URL.Query().Get(AccessToken))
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	oa := NewOfficialAccount("APPID", "SECRET", options...)
+	oa.host = srv.URL
+	oa.token.Store("ACCESS_TOKEN")
+
+	return oa
+}
+
+func TestOAUploadMedia(t *testing.T) {
+	oa := newTestMediaOA(t, func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("media")
+		assert.Nil(t, err)
+		defer file.Close()
+
+		b, _ := io.ReadAll(file)
+		assert.Equal(t, "jpeg-bytes", string(b))
+		assert.Equal(t, "test.jpg", header.Filename)
+
+		switch r.URL.Path {
+		case "/cgi-bin/media/upload":
+			assert.Equal(t, "image", r.URL.Query().Get("type"))
+			_, _ = w.Write([]byte(`{"type":"image","media_id":"MEDIA_ID","created_at":1700000000}`))
+		case "/cgi-bin/material/add_material":
+			assert.Equal(t, "video", r.URL.Query().Get("type"))
+			desc := gjson.Parse(r.FormValue("description"))
+			assert.Equal(t, "标题", desc.Get("title").String())
+			assert.Equal(t, "简介", desc.Get("introduction").String())
+			_, _ = w.Write([]byte(`{"media_id":"MATERIAL_ID"}`))
+		default:
+			_, _ = w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+		}
+	})
+
+	ctx := context.Background()
+
+	media, err := oa.UploadMediaWithReader(ctx, MediaImage, "test.jpg", strings.NewReader("jpeg-bytes"))
+	assert.Nil(t, err)
+	assert.Equal(t, &MediaUpload{Type: "image", MediaID: "MEDIA_ID", CreatedAt: 1700000000}, media)
+
+	material, err := oa.AddVideoMaterialWithReader(ctx, "test.jpg", strings.NewReader("jpeg-bytes"), "标题", "简介")
+	assert.Nil(t, err)
+	assert.Equal(t, "MATERIAL_ID", material.MediaID)
+}
+
+func TestOADownloadMaterial(t *testing.T) {
+	oa := newTestMediaOA(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cgi-bin/material/get_material", r.URL.Path)
+
+		b, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "MATERIAL_ID", gjson.GetBytes(b, "media_id").String())
+
+		w.Header().Set(lib.HeaderContentType, "image/png")
+		w.Header().Set("Content-Disposition", `attachment; filename="MATERIAL_ID.png"`)
+		_, _ = w.Write([]byte("png-bytes"))
+	})
+
+	var buf bytes.Buffer
+
+	meta, err := oa.DownloadMaterial(context.Background(), "MATERIAL_ID", &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "MATERIAL_ID.png", meta.FileName)
+	assert.Equal(t, "image/png", meta.ContentType)
+	assert.Equal(t, int64(9), meta.Size)
+	assert.Equal(t, "png-bytes", buf.String())
+}
+
+func TestOADownloadMediaError(t *testing.T) {
+	var logged []error
+
+	oa := newTestMediaOA(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cgi-bin/media/get", r.URL.Path)
+
+		if r.URL.Query().Get("media_id") == "BROKEN" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set(lib.HeaderContentType, "application/json; encoding=utf-8")
+		_, _ = w.Write([]byte(`{"errcode":40007,"errmsg":"invalid media_id"}`))
+	}, WithOALogger(func(ctx context.Context, err error, data map[string]string) {
+		logged = append(logged, err)
+	}))
+
+	var buf bytes.Buffer
+
+	// 返回错误JSON
+	_, err := oa.DownloadMedia(context.Background(), "INVALID", &buf)
+	assert.EqualError(t, err, "40007 | invalid media_id")
+	assert.Zero(t, buf.Len())
+
+	// 非2xx响应
+	_, err = oa.DownloadMedia(context.Background(), "BROKEN", &buf)
+	assert.EqualError(t, err, "HTTP Request Error, StatusCode = 502")
+	assert.Zero(t, buf.Len())
+
+	assert.Equal(t, 2, len(logged))
+	assert.EqualError(t, logged[0], "40007 | invalid media_id")
+	assert.EqualError(t, logged[1], "HTTP Request Error, StatusCode = 502")
+}
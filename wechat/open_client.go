@@ -0,0 +1,105 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// openClient 第三方平台、第三方应用及服务商共用的接口请求
+type openClient struct {
+	host   string
+	client *resty.Client
+	logger func(ctx context.Context, err error, data map[string]string)
+}
+
+func (o *openClient) url(path string, query url.Values) string {
+	var builder strings.Builder
+
+	builder.WriteString(o.host)
+	if len(path) != 0 && path[0] != '/' {
+		builder.WriteString("/")
+	}
+	builder.WriteString(path)
+	if len(query) != 0 {
+		builder.WriteString("?")
+		builder.WriteString(query.Encode())
+	}
+
+	return builder.String()
+}
+
+func (o *openClient) do(ctx context.Context, method, path string, header http.Header, query url.Values, params lib.X) ([]byte, error) {
+	reqURL := o.url(path, query)
+
+	log := lib.NewReqLog(method, reqURL)
+	defer log.Do(ctx, o.logger)
+
+	var (
+		body []byte
+		err  error
+	)
+
+	if params != nil {
+		body, err = json.Marshal(params)
+		if err != nil {
+			log.SetError(err)
+			return nil, err
+		}
+		log.SetReqBody(string(body))
+	}
+
+	resp, err := o.client.R().
+		SetContext(ctx).
+		SetHeaderMultiValues(header).
+		SetBody(body).
+		Execute(method, reqURL)
+	if err != nil {
+		log.SetError(err)
+		return nil, err
+	}
+	log.SetRespHeader(resp.Header())
+	log.SetStatusCode(resp.StatusCode())
+	log.SetRespBody(string(resp.Body()))
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("HTTP Request Error, StatusCode = %d", resp.StatusCode())
+	}
+	return resp.Body(), nil
+}
+
+// getJSON GET请求JSON数据，并校验errcode
+func (o *openClient) getJSON(ctx context.Context, path string, query url.Values) (gjson.Result, error) {
+	b, err := o.do(ctx, http.MethodGet, path, nil, query, nil)
+	if err != nil {
+		return lib.Fail(err)
+	}
+	return parseOpenResult(b)
+}
+
+// postJSON POST请求JSON数据，并校验errcode
+func (o *openClient) postJSON(ctx context.Context, path string, query url.Values, params lib.X) (gjson.Result, error) {
+	header := http.Header{}
+	header.Set(lib.HeaderContentType, lib.ContentJSON)
+
+	b, err := o.do(ctx, http.MethodPost, path, header, query, params)
+	if err != nil {
+		return lib.Fail(err)
+	}
+	return parseOpenResult(b)
+}
+
+func parseOpenResult(b []byte) (gjson.Result, error) {
+	ret := gjson.ParseBytes(b)
+	if code := ret.Get("errcode").Int(); code != 0 {
+		return lib.Fail(fmt.Errorf("%d | %s", code, ret.Get("errmsg").String()))
+	}
+	return ret, nil
+}
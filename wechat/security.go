@@ -0,0 +1,369 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/shenghui0779/sdk-go/lib"
+)
+
+// EventMediaCheck 异步校验图片/音频结果推送事件
+const EventMediaCheck = "wxa_media_check"
+
+// SecScene 内容安全检测场景
+type SecScene int
+
+const (
+	SecSceneProfile SecScene = 1 // 资料
+	SecSceneComment SecScene = 2 // 评论
+	SecSceneForum   SecScene = 3 // 论坛
+	SecSceneSocial  SecScene = 4 // 社交日志
+)
+
+// SecMediaType 内容安全检测的多媒体类型
+type SecMediaType int
+
+const (
+	SecMediaAudio SecMediaType = 1 // 音频
+	SecMediaImage SecMediaType = 2 // 图片
+)
+
+// 内容安全检测建议
+const (
+	SecSuggestPass   = "pass"
+	SecSuggestReview = "review"
+	SecSuggestRisky  = "risky"
+)
+
+// MsgSecCheck 文本内容安全检测参数
+type MsgSecCheck struct {
+	Content   string   `json:"content"`
+	Scene     SecScene `json:"scene"`
+	OpenID    string   `json:"openid"` // 需在近两小时访问过小程序
+	Title     string   `json:"title,omitempty"`
+	Nickname  string   `json:"nickname,omitempty"`
+	Signature string   `json:"signature,omitempty"` // 个性签名，仅在资料类场景有效
+}
+
+// SecCheckResult 内容安全综合结果
+type SecCheckResult struct {
+	Suggest string `json:"suggest" xml:"suggest"`
+	Label   int    `json:"label" xml:"label"` // 100-正常 10001-广告 20001-时政 20002-色情 20003-辱骂 20006-违法犯罪 20008-欺诈 20012-低俗 20013-版权 21000-其他
+}
+
+// SecCheckDetail 内容安全详细检测结果
+type SecCheckDetail struct {
+	Strategy string `json:"strategy" xml:"strategy"`
+	ErrCode  int    `json:"errcode" xml:"errcode"`
+	Suggest  string `json:"suggest" xml:"suggest"`
+	Label    int    `json:"label" xml:"label"`
+	Level    int    `json:"level" xml:"level"`
+	Prob     int    `json:"prob" xml:"prob"`
+	Keyword  string `json:"keyword" xml:"keyword"`
+}
+
+// MsgSecCheckResult 文本内容安全检测结果
+type MsgSecCheckResult struct {
+	TraceID string            `json:"trace_id"`
+	Result  *SecCheckResult   `json:"result"`
+	Detail  []*SecCheckDetail `json:"detail"`
+}
+
+// IsPass 是否检测通过
+func (r *MsgSecCheckResult) IsPass() bool {
+	return r.Result != nil && r.Result.Suggest == SecSuggestPass
+}
+
+// MediaCheckEvent 异步校验图片/音频结果推送事件
+type MediaCheckEvent struct {
+	ToUserName   string            `json:"ToUserName" xml:"ToUserName"`
+	FromUserName string            `json:"FromUserName" xml:"FromUserName"`
+	CreateTime   int64             `json:"CreateTime" xml:"CreateTime"`
+	MsgType      string            `json:"MsgType" xml:"MsgType"`
+	Event        string            `json:"Event" xml:"Event"`
+	AppID        string            `json:"appid" xml:"appid"`
+	TraceID      string            `json:"trace_id" xml:"trace_id"`
+	Version      int               `json:"version" xml:"version"`
+	Result       *SecCheckResult   `json:"result" xml:"result"`
+	Detail       []*SecCheckDetail `json:"detail" xml:"detail"`
+	ErrCode      int               `json:"errcode" xml:"errcode"`
+	ErrMsg       string            `json:"errmsg" xml:"errmsg"`
+}
+
+// IsPass 是否检测通过
+func (e *MediaCheckEvent) IsPass() bool {
+	return e.ErrCode == 0 && e.Result != nil && e.Result.Suggest == SecSuggestPass
+}
+
+// ParseMediaCheckEvent 解析 wxa_media_check 事件(明文，支持XML和JSON)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/sec-center/sec-check/mediaCheckAsync.html)
+func ParseMediaCheckEvent(b []byte) (*MediaCheckEvent, error) {
+	b = bytes.TrimSpace(b)
+
+	event := new(MediaCheckEvent)
+	if len(b) != 0 && b[0] == '<' {
+		if err := xml.Unmarshal(b, event); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := json.Unmarshal(b, event); err != nil {
+			return nil, err
+		}
+	}
+
+	if event.Event != EventMediaCheck {
+		return nil, fmt.Errorf("unexpected event: %s", event.Event)
+	}
+	return event, nil
+}
+
+func msgSecCheck(ctx context.Context, post func(ctx context.Context, path string, params lib.X) (gjson.Result, error), check *MsgSecCheck) (*MsgSecCheckResult, error) {
+	params, err := lib.ToX(check)
+	if err != nil {
+		return nil, err
+	}
+	params["version"] = 2
+
+	ret, err := post(ctx, "/wxa/msg_sec_check", params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(MsgSecCheckResult)
+	if err = unmarshalResult(ret, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func mediaCheckAsync(ctx context.Context, post func(ctx context.Context, path string, params lib.X) (gjson.Result, error),
+	mediaURL string, mediaType SecMediaType, scene SecScene, openid string) (string, error) {
+	params := lib.X{
+		"media_url":  mediaURL,
+		"media_type": mediaType,
+		"version":    2,
+		"scene":      scene,
+		"openid":     openid,
+	}
+
+	ret, err := post(ctx, "/wxa/media_check_async", params)
+	if err != nil {
+		return "", err
+	}
+	return ret.Get("trace_id").String(), nil
+}
+
+// MsgSecCheck 文本内容安全识别(2.0)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/sec-center/sec-check/msgSecCheck.html)
+func (mp *MiniProgram) MsgSecCheck(ctx context.Context, check *MsgSecCheck) (*MsgSecCheckResult, error) {
+	return msgSecCheck(ctx, mp.PostJSON, check)
+}
+
+// MediaCheckAsync 异步校验图片/音频(2.0)，返回 trace_id，结果通过 wxa_media_check 事件推送
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/sec-center/sec-check/mediaCheckAsync.html)
+func (mp *MiniProgram) MediaCheckAsync(ctx context.Context, mediaURL string, mediaType SecMediaType, scene SecScene, openid string) (string, error) {
+	return mediaCheckAsync(ctx, mp.PostJSON, mediaURL, mediaType, scene, openid)
+}
+
+// MsgSecCheck 文本内容安全识别(2.0)
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/sec-center/sec-check/msgSecCheck.html)
+func (oa *OfficialAccount) MsgSecCheck(ctx context.Context, check *MsgSecCheck) (*MsgSecCheckResult, error) {
+	return msgSecCheck(ctx, oa.PostJSON, check)
+}
+
+// MediaCheckAsync 异步校验图片/音频(2.0)，返回 trace_id，结果通过 wxa_media_check 事件推送
+// [参考](https://developers.weixin.qq.com/miniprogram/dev/OpenApiDoc/sec-center/sec-check/mediaCheckAsync.html)
+func (oa *OfficialAccount) MediaCheckAsync(ctx context.Context, mediaURL string, mediaType SecMediaType, scene SecScene, openid string) (string, error) {
+	return mediaCheckAsync(ctx, oa.PostJSON, mediaURL, mediaType, scene, openid)
+}
+
+type mediaCheckWaiter struct {
+	ch       chan *MediaCheckEvent
+	callback func(e *MediaCheckEvent)
+	expireAt time.Time
+}
+
+type mediaCheckEarly struct {
+	event    *MediaCheckEvent
+	expireAt time.Time
+}
+
+// MediaCheckTracker 关联 media_check_async 返回的 trace_id 与 wxa_media_check 推送结果；
+// 注意：仅适用于单实例，多实例部署时推送可能到达其它实例
+type MediaCheckTracker struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	waiters map[string][]*mediaCheckWaiter // 同一 trace_id 可被多次跟踪，结果推送给全部等待方
+	early   map[string]*mediaCheckEarly    // 先于 Track 到达的结果
+}
+
+// Track 跟踪 trace_id，结果推送后写入返回的 channel (缓冲为1)
+func (t *MediaCheckTracker) Track(traceID string) <-chan *MediaCheckEvent {
+	ch := make(chan *MediaCheckEvent, 1)
+	t.track(traceID, &mediaCheckWaiter{ch: ch})
+	return ch
+}
+
+// OnResult 跟踪 trace_id，结果推送后异步执行回调
+func (t *MediaCheckTracker) OnResult(traceID string, fn func(e *MediaCheckEvent)) {
+	t.track(traceID, &mediaCheckWaiter{callback: fn})
+}
+
+// Wait 等待 trace_id 的检测结果，直到ctx结束
+func (t *MediaCheckTracker) Wait(ctx context.Context, traceID string) (*MediaCheckEvent, error) {
+	w := &mediaCheckWaiter{ch: make(chan *MediaCheckEvent, 1)}
+	t.track(traceID, w)
+
+	select {
+	case <-ctx.Done():
+		t.untrack(traceID, w)
+		return nil, ctx.Err()
+	case e := <-w.ch:
+		return e, nil
+	}
+}
+
+// Cancel 取消跟踪 trace_id(该 trace_id 的全部等待方)
+func (t *MediaCheckTracker) Cancel(traceID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.waiters, traceID)
+}
+
+// Dispatch 分发 wxa_media_check 推送结果，返回是否有对应的跟踪；
+// 未被跟踪的结果会暂存(有效期同ttl)，以应对推送先于 Track 到达的情况
+func (t *MediaCheckTracker) Dispatch(e *MediaCheckEvent) bool {
+	if e == nil || len(e.TraceID) == 0 {
+		return false
+	}
+
+	t.mutex.Lock()
+
+	t.purge()
+
+	waiters, ok := t.waiters[e.TraceID]
+	if !ok {
+		t.early[e.TraceID] = &mediaCheckEarly{
+			event:    e,
+			expireAt: time.Now().Add(t.ttl),
+		}
+		t.mutex.Unlock()
+		return false
+	}
+	delete(t.waiters, e.TraceID)
+
+	t.mutex.Unlock()
+
+	for _, w := range waiters {
+		w.notify(e)
+	}
+	return true
+}
+
+// DispatchRaw 解析并分发 wxa_media_check 推送(明文)
+func (t *MediaCheckTracker) DispatchRaw(b []byte) (*MediaCheckEvent, error) {
+	e, err := ParseMediaCheckEvent(b)
+	if err != nil {
+		return nil, err
+	}
+	t.Dispatch(e)
+	return e, nil
+}
+
+// Pending 返回当前正在等待结果的 trace_id 数量
+func (t *MediaCheckTracker) Pending() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return len(t.waiters)
+}
+
+func (t *MediaCheckTracker) track(traceID string, w *mediaCheckWaiter) {
+	t.mutex.Lock()
+
+	t.purge()
+
+	// 暂存的结果保留至过期，同一 trace_id 的其它等待方也能获取
+	if v, ok := t.early[traceID]; ok {
+		t.mutex.Unlock()
+
+		w.notify(v.event)
+		return
+	}
+
+	w.expireAt = time.Now().Add(t.ttl)
+	t.waiters[traceID] = append(t.waiters[traceID], w)
+
+	t.mutex.Unlock()
+}
+
+// untrack 仅移除指定的等待方
+func (t *MediaCheckTracker) untrack(traceID string, w *mediaCheckWaiter) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	waiters := t.waiters[traceID]
+	for i, v := range waiters {
+		if v == w {
+			waiters = append(waiters[:i:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(t.waiters, traceID)
+		return
+	}
+	t.waiters[traceID] = waiters
+}
+
+// purge 清理过期数据(需持有锁)
+func (t *MediaCheckTracker) purge() {
+	now := time.Now()
+	for k, waiters := range t.waiters {
+		alive := waiters[:0]
+		for _, v := range waiters {
+			if !now.After(v.expireAt) {
+				alive = append(alive, v)
+			}
+		}
+		if len(alive) == 0 {
+			delete(t.waiters, k)
+			continue
+		}
+		t.waiters[k] = alive
+	}
+	for k, v := range t.early {
+		if now.After(v.expireAt) {
+			delete(t.early, k)
+		}
+	}
+}
+
+func (w *mediaCheckWaiter) notify(e *MediaCheckEvent) {
+	if w.ch != nil {
+		w.ch <- e
+	}
+	if w.callback != nil {
+		go w.callback(e)
+	}
+}
+
+// NewMediaCheckTracker 生成 trace_id 跟踪器，ttl 为跟踪的有效期(<=0 时默认：30分钟)
+func NewMediaCheckTracker(ttl time.Duration) *MediaCheckTracker {
+	if ttl <= 0 {
+		ttl = 30 * time.Minute
+	}
+	return &MediaCheckTracker{
+		ttl:     ttl,
+		waiters: make(map[string][]*mediaCheckWaiter),
+		early:   make(map[string]*mediaCheckEarly),
+	}
+}
@@ -0,0 +1,107 @@
+package wechat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaCheckTracker(t *testing.T) {
+	tracker := NewMediaCheckTracker(time.Minute)
+
+	e, err := ParseMediaCheckEvent([]byte(`{
+		"ToUserName": "gh_38cc49f9733b",
+		"FromUserName": "oH1fu0FdHqpToe2T6gBj0WyB8iS1",
+		"CreateTime": 1626959646,
+		"MsgType": "event",
+		"Event": "wxa_media_check",
+		"appid": "wx8f16a5e5e6fbbd21",
+		"trace_id": "60f96f1d-3845297a-1976a3ae",
+		"version": 2,
+		"detail": [{"strategy": "content_model", "errcode": 0, "suggest": "pass", "label": 100, "prob": 90}],
+		"errcode": 0,
+		"errmsg": "ok",
+		"result": {"suggest": "pass", "label": 100}
+	}`))
+	assert.Nil(t, err)
+	assert.True(t, e.IsPass())
+	assert.Equal(t, 90, e.Detail[0].Prob)
+
+	// 先跟踪后推送
+	ch := tracker.Track(e.TraceID)
+	assert.Equal(t, 1, tracker.Pending())
+	assert.True(t, tracker.Dispatch(e))
+	assert.Equal(t, e, <-ch)
+	assert.Equal(t, 0, tracker.Pending())
+
+	// 推送先于跟踪到达
+	xe, err := tracker.DispatchRaw([]byte(`<xml>
+<ToUserName><![CDATA[gh_38cc49f9733b]]></ToUserName>
+<Event><![CDATA[wxa_media_check]]></Event>
+<trace_id><![CDATA[60f96f1d-3845297a-1976a3af]]></trace_id>
+<result><suggest><![CDATA[risky]]></suggest><label>20002</label></result>
+</xml>`))
+	assert.Nil(t, err)
+	assert.False(t, xe.IsPass())
+
+	ret, err := tracker.Wait(context.Background(), "60f96f1d-3845297a-1976a3af")
+	assert.Nil(t, err)
+	assert.Equal(t, 20002, ret.Result.Label)
+
+	// 回调
+	done := make(chan string, 1)
+	tracker.OnResult("trace_cb", func(e *MediaCheckEvent) { done <- e.TraceID })
+	tracker.Dispatch(&MediaCheckEvent{Event: EventMediaCheck, TraceID: "trace_cb"})
+	assert.Equal(t, "trace_cb", <-done)
+
+	// 超时
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = tracker.Wait(ctx, "trace_timeout")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, tracker.Pending())
+}
+
+func TestMediaCheckTrackerDuplicate(t *testing.T) {
+	tracker := NewMediaCheckTracker(time.Minute)
+
+	e := &MediaCheckEvent{Event: EventMediaCheck, TraceID: "trace_dup"}
+
+	// 同一 trace_id 多次跟踪，结果推送给全部等待方
+	ch1 := tracker.Track(e.TraceID)
+	ch2 := tracker.Track(e.TraceID)
+
+	done := make(chan string, 1)
+	tracker.OnResult(e.TraceID, func(e *MediaCheckEvent) { done <- e.TraceID })
+
+	// 超时的等待方不影响其它等待方
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := tracker.Wait(ctx, e.TraceID)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, tracker.Pending())
+
+	assert.True(t, tracker.Dispatch(e))
+	assert.Equal(t, e, <-ch1)
+	assert.Equal(t, e, <-ch2)
+	assert.Equal(t, "trace_dup", <-done)
+	assert.Equal(t, 0, tracker.Pending())
+
+	// 推送先于跟踪到达，多次跟踪均可获取结果
+	early := &MediaCheckEvent{Event: EventMediaCheck, TraceID: "trace_early"}
+	assert.False(t, tracker.Dispatch(early))
+
+	for i := 0; i < 2; i++ {
+		ret, err := tracker.Wait(context.Background(), early.TraceID)
+		assert.Nil(t, err)
+		assert.Equal(t, early, ret)
+	}
+
+	// 取消跟踪
+	tracker.Track("trace_cancel")
+	tracker.Track("trace_cancel")
+	tracker.Cancel("trace_cancel")
+	assert.Equal(t, 0, tracker.Pending())
+}
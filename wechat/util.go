@@ -1,6 +1,8 @@
 package wechat
 
 import (
+	"encoding/json"
+
 	"github.com/tidwall/gjson"
 )
 
@@ -36,3 +38,11 @@ func DecodeBytesToUint32(b []byte) uint32 {
 	}
 	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
 }
+
+// unmarshalResult 将API返回结果解析到 v (结果不存在时忽略)
+func unmarshalResult(ret gjson.Result, v any) error {
+	if !ret.Exists() {
+		return nil
+	}
+	return json.Unmarshal([]byte(ret.Raw), v)
+}